@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"time"
@@ -15,31 +17,59 @@ import (
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
+// TokenRevoker 访问令牌吊销查询接口
+type TokenRevoker interface {
+	IsRevoked(jti string) (bool, error)
+}
+
+// AccountChecker 账户状态查询接口，返回账户是否可用和当前角色；停用或已删除的账户返回false
+type AccountChecker interface {
+	AccountStatus(userID uint) (active bool, role string, err error)
+}
+
 // JWTManager JWT管理器
 type JWTManager struct {
-	secret      []byte
-	expireHours int
+	secret       []byte
+	accessExpire time.Duration
+	revoker      TokenRevoker
+	accounts     AccountChecker
 }
 
 // NewJWTManager 创建JWT管理器
-func NewJWTManager(cfg *config.Config) *JWTManager {
+func NewJWTManager(cfg *config.Config, revoker TokenRevoker, accounts AccountChecker) *JWTManager {
 	return &JWTManager{
-		secret:      cfg.JWT.Secret,
-		expireHours: cfg.JWT.ExpireHours,
+		secret:       cfg.JWT.Secret,
+		accessExpire: cfg.GetAccessTokenExpireTime(),
+		revoker:      revoker,
+		accounts:     accounts,
 	}
 }
 
-// GenerateToken 生成JWT令牌
-func (j *JWTManager) GenerateToken(userID uint, username string) (string, error) {
+// AccessExpire 获取访问令牌有效期
+func (j *JWTManager) AccessExpire() time.Duration {
+	return j.accessExpire
+}
+
+// GenerateToken 生成短期访问令牌，每个令牌带有唯一的jti用于吊销
+func (j *JWTManager) GenerateToken(userID uint, username, role string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", errors.New("令牌生成失败")
+	}
+
+	now := time.Now()
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(j.expireHours) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessExpire)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -56,7 +86,7 @@ func (j *JWTManager) GenerateToken(userID uint, username string) (string, error)
 func (j *JWTManager) ParseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return j.secret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || !token.Valid {
 		return nil, errors.New("无效的认证令牌")
@@ -70,6 +100,51 @@ func (j *JWTManager) ParseToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// Validate 校验已解析的访问令牌当前是否仍然有效：未过期、未被吊销且账户未被停用，返回账户当前的角色。
+// 停用前签发的访问令牌同样失效；修改角色后已签发的访问令牌立即按新角色鉴权。
+// 实时推送等长连接在连接期间定期调用，令牌失效后断开连接
+func (j *JWTManager) Validate(claims *Claims) (string, error) {
+	if claims.ExpiresAt == nil || !time.Now().Before(claims.ExpiresAt.Time) {
+		return "", errors.New("认证令牌已过期")
+	}
+
+	if j.revoker != nil {
+		revoked, err := j.revoker.IsRevoked(claims.ID)
+		if err != nil {
+			return "", errors.New("认证令牌校验失败")
+		}
+		if revoked {
+			return "", errors.New("认证令牌已失效")
+		}
+	}
+
+	role := claims.Role
+	if j.accounts != nil {
+		active, current, err := j.accounts.AccountStatus(claims.UserID)
+		if err != nil {
+			return "", errors.New("认证令牌校验失败")
+		}
+		if !active {
+			return "", errors.New("账户已被停用")
+		}
+		role = current
+	}
+	return role, nil
+}
+
+// QueryTokenMiddleware 请求头中没有访问令牌时从access_token查询参数读取，需放在AuthMiddleware之前。
+// 浏览器的EventSource和WebSocket无法设置请求头，仅用于实时推送接口
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
+
 // AuthMiddleware JWT认证中间件
 func (j *JWTManager) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -88,9 +163,9 @@ func (j *JWTManager) AuthMiddleware() gin.HandlerFunc {
 			tokenString = tokenString[7:]
 		}
 
-		// 解析JWT
+		// 解析JWT，没有jti的令牌无法吊销，一律拒绝
 		claims, err := j.ParseToken(tokenString)
-		if err != nil {
+		if err != nil || claims.ID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "无效的认证令牌",
@@ -99,9 +174,30 @@ func (j *JWTManager) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查令牌是否已被吊销、账户是否已被停用，角色以数据库为准
+		role, err := j.Validate(claims)
+		if err != nil {
+			statusCode := http.StatusInternalServerError
+			switch err.Error() {
+			case "认证令牌已过期", "认证令牌已失效":
+				statusCode = http.StatusUnauthorized
+			case "账户已被停用":
+				statusCode = http.StatusForbidden
+			}
+			c.JSON(statusCode, gin.H{
+				"code":    statusCode,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		claims.Role = role
+
 		// 将用户信息存储到上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
@@ -135,3 +231,27 @@ func GetUsername(c *gin.Context) (string, error) {
 
 	return name, nil
 }
+
+// GetClaims 从上下文中获取完整的令牌声明
+func GetClaims(c *gin.Context) (*Claims, error) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, errors.New("令牌声明不存在")
+	}
+
+	claims, ok := value.(*Claims)
+	if !ok {
+		return nil, errors.New("令牌声明类型错误")
+	}
+
+	return claims, nil
+}
+
+// newTokenID 生成随机的令牌ID(jti)
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission 权限标识
+type Permission string
+
+const (
+	PermPostCreate      Permission = "post:create"      // 发布文章
+	PermPostModerate    Permission = "post:moderate"    // 编辑/删除任意文章
+	PermCommentCreate   Permission = "comment:create"   // 发表评论
+	PermCommentModerate Permission = "comment:moderate" // 编辑/删除任意评论
+	PermUserManage      Permission = "user:manage"      // 管理用户
+	PermTaxonomyManage  Permission = "taxonomy:manage"  // 管理标签和分类
+	PermWebhookManage   Permission = "webhook:manage"   // 管理Webhook
+)
+
+// rolePermissions 角色与权限的对应关系
+var rolePermissions = map[string][]Permission{
+	models.RoleReader:    {PermCommentCreate},
+	models.RoleAuthor:    {PermCommentCreate, PermPostCreate},
+	models.RoleModerator: {PermCommentCreate, PermPostCreate, PermCommentModerate, PermTaxonomyManage},
+	models.RoleAdmin:     {PermCommentCreate, PermPostCreate, PermCommentModerate, PermTaxonomyManage, PermPostModerate, PermUserManage, PermWebhookManage},
+}
+
+// RoleHasPermission 判断角色是否拥有指定权限
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can 判断当前请求的用户是否拥有指定权限
+func Can(c *gin.Context, perm Permission) bool {
+	role, err := GetRole(c)
+	if err != nil {
+		return false
+	}
+	return RoleHasPermission(role, perm)
+}
+
+// RequireRole 角色校验中间件，需在AuthMiddleware之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := GetRole(c)
+		if err == nil {
+			for _, r := range roles {
+				if r == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "权限不足",
+		})
+		c.Abort()
+	}
+}
+
+// RequirePermission 权限校验中间件，需在AuthMiddleware之后使用
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Can(c, perm) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "权限不足",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetRole 从上下文中获取用户角色
+func GetRole(c *gin.Context) (string, error) {
+	role, exists := c.Get("role")
+	if !exists {
+		return "", errors.New("用户角色不存在")
+	}
+
+	name, ok := role.(string)
+	if !ok {
+		return "", errors.New("用户角色类型错误")
+	}
+
+	return name, nil
+}
+
+// EmailVerifier 邮箱验证状态查询接口
+type EmailVerifier interface {
+	IsEmailVerified(userID uint) (bool, error)
+}
+
+// RequireVerifiedEmail 要求邮箱已验证的中间件，需在AuthMiddleware之后使用；verifier为nil时不做限制
+func RequireVerifiedEmail(verifier EmailVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "获取用户信息失败",
+			})
+			c.Abort()
+			return
+		}
+
+		verified, err := verifier.IsEmailVerified(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "邮箱验证状态查询失败",
+			})
+			c.Abort()
+			return
+		}
+		if !verified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "请先验证邮箱",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
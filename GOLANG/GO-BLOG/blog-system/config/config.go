@@ -1,9 +1,10 @@
 package config
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -25,6 +26,45 @@ type Config struct {
 
 	// 应用配置
 	App AppConfig
+
+	// 搜索配置
+	Search SearchConfig
+
+	// 后台任务配置
+	Scheduler SchedulerConfig
+
+	// 初始管理员配置
+	Admin AdminConfig
+
+	// 垃圾评论检测配置
+	Spam SpamConfig
+
+	// 媒体文件配置
+	Media MediaConfig
+
+	// 邮件配置
+	Mail MailConfig
+
+	// 请求频率限制配置
+	RateLimit RateLimitConfig
+
+	// 订阅源配置
+	Feed FeedConfig
+
+	// 互动配置
+	Interaction InteractionConfig
+
+	// 站内通知配置
+	Notification NotificationConfig
+
+	// 实时推送配置
+	Stream StreamConfig
+
+	// Webhook配置
+	Webhook WebhookConfig
+
+	// 监控指标配置
+	Metrics MetricsConfig
 }
 
 // DatabaseConfig 数据库配置
@@ -41,20 +81,24 @@ type DatabaseConfig struct {
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port string
-	Host string
+	Port           string
+	Host           string
+	TrustedProxies []string // 可信的反向代理地址或网段，只有来自这些地址的X-Forwarded-For才用于确定客户端IP
 }
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret      []byte
-	ExpireHours int
+	Secret              []byte
+	AccessExpireMinutes int
+	RefreshExpireHours  int
 }
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level  string
-	Format string
+	Level       string
+	Format      string // json或text
+	BodyLimit   int    // debug日志记录请求体的最大字节数，超出部分截断
+	SlowQueryMs int    // 执行时间超过该值的SQL记录为慢查询
 }
 
 // AppConfig 应用配置
@@ -64,11 +108,140 @@ type AppConfig struct {
 	Env     string
 }
 
+// SearchConfig 搜索配置
+type SearchConfig struct {
+	Driver string
+}
+
+// SchedulerConfig 后台任务配置
+type SchedulerConfig struct {
+	PublishIntervalSeconds int
+	TrashRetentionDays     int // 回收站内容的保留天数，超过后永久删除
+}
+
+// AdminConfig 初始管理员配置，系统中没有管理员时用于创建第一个管理员
+type AdminConfig struct {
+	Username string
+	Password string
+	Email    string
+}
+
+// SpamConfig 垃圾评论检测配置
+type SpamConfig struct {
+	HoldThreshold         float64  // 评分达到该值时评论进入审核队列
+	SpamThreshold         float64  // 评分达到该值时评论直接判定为垃圾评论
+	MaxLinks              int      // 允许的最大链接数
+	Blocklist             []string // 屏蔽词列表
+	RepeatWindowMinutes   int      // 重复内容检测时间窗口
+	VelocityLimit         int      // 同一IP在时间窗口内允许的评论数
+	VelocityWindowMinutes int      // 频率检测时间窗口
+}
+
+// MediaConfig 媒体文件配置
+type MediaConfig struct {
+	Storage       string   // 存储后端: local, s3
+	LocalDir      string   // 本地存储目录
+	BaseURL       string   // 本地存储文件的访问路径前缀
+	MaxUploadMB   int      // 单个文件的最大大小(MB)
+	UserQuotaMB   int      // 每个用户的存储配额(MB)，0表示不限制
+	ThumbnailSize int      // 缩略图长边的最大像素
+	AllowedTypes  []string // 允许上传的MIME类型
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PublicURL   string // 文件的公开访问地址前缀，为空时使用S3Endpoint/S3Bucket
+}
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver               string // 邮件驱动: outbox(开发测试用，保存到目录), smtp
+	OutboxDir            string
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	From                 string
+	VerifyURL            string // 邮箱验证链接前缀，令牌追加在末尾
+	ResetURL             string // 密码重置链接前缀，令牌追加在末尾
+	RequireVerification  bool   // 未验证邮箱的用户是否禁止发布文章和评论
+	VerifyTokenTTLHours  int
+	ResetTokenTTLMinutes int
+}
+
+// RateLimitPolicy 频率限制策略：每个Period内最多Requests次请求，Requests为0表示不限制
+type RateLimitPolicy struct {
+	Requests int
+	Period   time.Duration
+}
+
+// RateLimitConfig 请求频率限制配置
+type RateLimitConfig struct {
+	Enabled              bool
+	Anonymous            RateLimitPolicy // 未认证的公开接口，按IP限制
+	User                 RateLimitPolicy // 需要认证的接口，按用户限制
+	Auth                 RateLimitPolicy // 登录、注册、刷新令牌、邮箱验证和密码找回接口，按IP限制
+	Write                RateLimitPolicy // 发布文章、评论和上传文件，按用户限制
+	LockoutThreshold     int             // 同一用户名连续登录失败达到该次数后锁定，0表示不锁定
+	LockoutWindowMinutes int             // 登录失败次数的统计窗口
+	LockoutBaseSeconds   int             // 第一次锁定的时长，之后每次锁定时长翻倍
+	LockoutMaxMinutes    int             // 锁定时长的上限
+}
+
+// FeedConfig RSS、Atom和JSON Feed订阅源配置
+type FeedConfig struct {
+	SiteURL     string // 站点地址，用于生成订阅源中的绝对链接
+	PostURL     string // 文章页面链接前缀，文章别名追加在末尾
+	Title       string // 为空时使用应用名称
+	Description string
+	Language    string
+	ItemCount   int // 订阅源包含的文章数量
+}
+
+// InteractionConfig 点赞、表情回应和浏览量配置
+type InteractionConfig struct {
+	ViewWindowMinutes int // 同一用户(未登录时为同一IP)在该时间内重复浏览同一篇文章只计一次
+}
+
+// NotificationConfig 站内通知配置
+type NotificationConfig struct {
+	QueueSize     int // 待处理事件队列长度，队列已满时丢弃新事件
+	RetentionDays int // 已读通知的保留天数
+}
+
+// StreamConfig 评论和通知实时推送配置
+type StreamConfig struct {
+	HeartbeatSeconds int      // 心跳间隔，WebSocket连接超过两个心跳间隔没有响应时断开
+	ClientBuffer     int      // 每个连接的待推送队列长度，队列已满时断开连接，客户端重连后补发
+	ReplaySize       int      // 每个主题保留用于断线补发的消息数量
+	ReplayMinutes    int      // 用于断线补发的消息保留时长
+	AllowedOrigins   []string // 允许发起WebSocket连接的页面域名，支持*通配符，为空时只允许与服务同域的页面
+}
+
+// WebhookConfig Webhook投递配置
+type WebhookConfig struct {
+	TimeoutSeconds        int // 单次投递请求的超时时间
+	Workers               int // 同时进行的投递请求数
+	MaxAttempts           int // 每条投递最多请求的次数
+	RetryBaseSeconds      int // 第一次重试前的等待时间，之后每次翻倍
+	RetryMaxMinutes       int // 重试等待时间的上限
+	DisableAfterFailures  int // 连续多少条投递最终失败后自动停用，0表示不自动停用
+	DeliveryRetentionDays int // 已完成投递记录的保留天数
+}
+
+// MetricsConfig Prometheus监控指标配置
+type MetricsConfig struct {
+	Enabled bool
+	Path    string
+	Token   string // 访问监控指标需要的Bearer令牌，为空时不校验
+}
+
 // Load 加载配置
 func Load() *Config {
 	// 尝试加载.env文件
 	if err := godotenv.Load(); err != nil {
-		log.Println("未找到.env文件，使用系统环境变量")
+		slog.Info("未找到.env文件，使用系统环境变量")
 	}
 
 	return &Config{
@@ -83,22 +256,123 @@ func Load() *Config {
 			Loc:       getEnv("DB_LOC", "Local"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Host:           getEnv("SERVER_HOST", "0.0.0.0"),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		},
 		JWT: JWTConfig{
-			Secret:      []byte(getEnv("JWT_SECRET", "your_secret_key_change_in_production")),
-			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 24),
+			Secret:              []byte(getEnv("JWT_SECRET", "your_secret_key_change_in_production")),
+			AccessExpireMinutes: getEnvAsInt("JWT_ACCESS_EXPIRE_MINUTES", 15),
+			RefreshExpireHours:  getEnvAsInt("JWT_REFRESH_EXPIRE_HOURS", 168),
 		},
 		Log: LogConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:       getEnv("LOG_LEVEL", "info"),
+			Format:      getEnv("LOG_FORMAT", "json"),
+			BodyLimit:   getEnvAsInt("LOG_BODY_LIMIT", 4096),
+			SlowQueryMs: getEnvAsInt("LOG_SLOW_QUERY_MS", 200),
 		},
 		App: AppConfig{
 			Name:    getEnv("APP_NAME", "Blog System"),
 			Version: getEnv("APP_VERSION", "1.0.0"),
 			Env:     getEnv("APP_ENV", "development"),
 		},
+		Search: SearchConfig{
+			Driver: getEnv("SEARCH_DRIVER", "mysql"),
+		},
+		Scheduler: SchedulerConfig{
+			PublishIntervalSeconds: getEnvAsInt("SCHEDULER_PUBLISH_INTERVAL_SECONDS", 30),
+			TrashRetentionDays:     getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		},
+		Admin: AdminConfig{
+			Username: getEnv("ADMIN_USERNAME", ""),
+			Password: getEnv("ADMIN_PASSWORD", ""),
+			Email:    getEnv("ADMIN_EMAIL", ""),
+		},
+		Spam: SpamConfig{
+			HoldThreshold:         getEnvAsFloat("SPAM_HOLD_THRESHOLD", 1),
+			SpamThreshold:         getEnvAsFloat("SPAM_THRESHOLD", 3),
+			MaxLinks:              getEnvAsInt("SPAM_MAX_LINKS", 2),
+			Blocklist:             getEnvAsList("SPAM_BLOCKLIST"),
+			RepeatWindowMinutes:   getEnvAsInt("SPAM_REPEAT_WINDOW_MINUTES", 1440),
+			VelocityLimit:         getEnvAsInt("SPAM_VELOCITY_LIMIT", 5),
+			VelocityWindowMinutes: getEnvAsInt("SPAM_VELOCITY_WINDOW_MINUTES", 10),
+		},
+		Media: MediaConfig{
+			Storage:       getEnv("MEDIA_STORAGE", "local"),
+			LocalDir:      getEnv("MEDIA_LOCAL_DIR", "uploads"),
+			BaseURL:       getEnv("MEDIA_BASE_URL", "/uploads"),
+			MaxUploadMB:   getEnvAsInt("MEDIA_MAX_UPLOAD_MB", 10),
+			UserQuotaMB:   getEnvAsInt("MEDIA_USER_QUOTA_MB", 200),
+			ThumbnailSize: getEnvAsInt("MEDIA_THUMBNAIL_SIZE", 320),
+			AllowedTypes:  getEnvAsList("MEDIA_ALLOWED_TYPES"),
+			S3Endpoint:    getEnv("S3_ENDPOINT", ""),
+			S3Region:      getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:      getEnv("S3_BUCKET", ""),
+			S3AccessKey:   getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey:   getEnv("S3_SECRET_KEY", ""),
+			S3PublicURL:   getEnv("S3_PUBLIC_URL", ""),
+		},
+		Mail: MailConfig{
+			Driver:               getEnv("MAIL_DRIVER", "outbox"),
+			OutboxDir:            getEnv("MAIL_OUTBOX_DIR", "outbox"),
+			SMTPHost:             getEnv("SMTP_HOST", ""),
+			SMTPPort:             getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+			From:                 getEnv("MAIL_FROM", "Blog System <noreply@example.com>"),
+			VerifyURL:            getEnv("MAIL_VERIFY_URL", "http://localhost:8080/api/email/verify?token="),
+			ResetURL:             getEnv("MAIL_RESET_URL", "http://localhost:8080/reset-password?token="),
+			RequireVerification:  getEnvAsBool("MAIL_REQUIRE_VERIFICATION", true),
+			VerifyTokenTTLHours:  getEnvAsInt("MAIL_VERIFY_TOKEN_TTL_HOURS", 24),
+			ResetTokenTTLMinutes: getEnvAsInt("MAIL_RESET_TOKEN_TTL_MINUTES", 30),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:              getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Anonymous:            getEnvAsRateLimit("RATE_LIMIT_ANONYMOUS", RateLimitPolicy{Requests: 60, Period: time.Minute}),
+			User:                 getEnvAsRateLimit("RATE_LIMIT_USER", RateLimitPolicy{Requests: 300, Period: time.Minute}),
+			Auth:                 getEnvAsRateLimit("RATE_LIMIT_AUTH", RateLimitPolicy{Requests: 10, Period: time.Minute}),
+			Write:                getEnvAsRateLimit("RATE_LIMIT_WRITE", RateLimitPolicy{Requests: 30, Period: time.Minute}),
+			LockoutThreshold:     getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutWindowMinutes: getEnvAsInt("LOGIN_LOCKOUT_WINDOW_MINUTES", 15),
+			LockoutBaseSeconds:   getEnvAsInt("LOGIN_LOCKOUT_BASE_SECONDS", 60),
+			LockoutMaxMinutes:    getEnvAsInt("LOGIN_LOCKOUT_MAX_MINUTES", 60),
+		},
+		Feed: FeedConfig{
+			SiteURL:     strings.TrimSuffix(getEnv("FEED_SITE_URL", "http://localhost:8080"), "/"),
+			PostURL:     getEnv("FEED_POST_URL", "http://localhost:8080/posts/"),
+			Title:       getEnv("FEED_TITLE", ""),
+			Description: getEnv("FEED_DESCRIPTION", "个人博客系统"),
+			Language:    getEnv("FEED_LANGUAGE", "zh-CN"),
+			ItemCount:   getEnvAsInt("FEED_ITEM_COUNT", 20),
+		},
+		Interaction: InteractionConfig{
+			ViewWindowMinutes: getEnvAsInt("VIEW_DEDUP_WINDOW_MINUTES", 30),
+		},
+		Notification: NotificationConfig{
+			QueueSize:     getEnvAsInt("NOTIFICATION_QUEUE_SIZE", 1024),
+			RetentionDays: getEnvAsInt("NOTIFICATION_RETENTION_DAYS", 90),
+		},
+		Stream: StreamConfig{
+			HeartbeatSeconds: getEnvAsInt("STREAM_HEARTBEAT_SECONDS", 25),
+			ClientBuffer:     getEnvAsInt("STREAM_CLIENT_BUFFER", 64),
+			ReplaySize:       getEnvAsInt("STREAM_REPLAY_SIZE", 100),
+			ReplayMinutes:    getEnvAsInt("STREAM_REPLAY_MINUTES", 10),
+			AllowedOrigins:   getEnvAsList("STREAM_ALLOWED_ORIGINS"),
+		},
+		Webhook: WebhookConfig{
+			TimeoutSeconds:        getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+			Workers:               getEnvAsInt("WEBHOOK_WORKERS", 4),
+			MaxAttempts:           getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+			RetryBaseSeconds:      getEnvAsInt("WEBHOOK_RETRY_BASE_SECONDS", 30),
+			RetryMaxMinutes:       getEnvAsInt("WEBHOOK_RETRY_MAX_MINUTES", 60),
+			DisableAfterFailures:  getEnvAsInt("WEBHOOK_DISABLE_AFTER_FAILURES", 5),
+			DeliveryRetentionDays: getEnvAsInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 30),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", true),
+			Path:    getEnv("METRICS_PATH", "/metrics"),
+			Token:   getEnv("METRICS_TOKEN", ""),
+		},
 	}
 }
 
@@ -116,9 +390,89 @@ func (c *Config) GetServerAddr() string {
 	return c.Server.Host + ":" + c.Server.Port
 }
 
-// GetJWTExpireTime 获取JWT过期时间
-func (c *Config) GetJWTExpireTime() time.Duration {
-	return time.Duration(c.JWT.ExpireHours) * time.Hour
+// GetAccessTokenExpireTime 获取访问令牌过期时间
+func (c *Config) GetAccessTokenExpireTime() time.Duration {
+	return time.Duration(c.JWT.AccessExpireMinutes) * time.Minute
+}
+
+// GetTrashRetention 获取回收站内容的保留时长
+func (c *Config) GetTrashRetention() time.Duration {
+	return time.Duration(c.Scheduler.TrashRetentionDays) * 24 * time.Hour
+}
+
+// GetMaxUploadSize 获取单个上传文件的最大字节数
+func (c *Config) GetMaxUploadSize() int64 {
+	return int64(c.Media.MaxUploadMB) << 20
+}
+
+// GetUserQuota 获取每个用户的存储配额字节数，0表示不限制
+func (c *Config) GetUserQuota() int64 {
+	return int64(c.Media.UserQuotaMB) << 20
+}
+
+// GetVerifyTokenExpireTime 获取邮箱验证令牌有效期
+func (c *Config) GetVerifyTokenExpireTime() time.Duration {
+	return time.Duration(c.Mail.VerifyTokenTTLHours) * time.Hour
+}
+
+// GetResetTokenExpireTime 获取密码重置令牌有效期
+func (c *Config) GetResetTokenExpireTime() time.Duration {
+	return time.Duration(c.Mail.ResetTokenTTLMinutes) * time.Minute
+}
+
+// GetLockoutWindow 获取登录失败次数的统计窗口
+func (c *Config) GetLockoutWindow() time.Duration {
+	return time.Duration(c.RateLimit.LockoutWindowMinutes) * time.Minute
+}
+
+// GetLockoutBase 获取第一次锁定的时长
+func (c *Config) GetLockoutBase() time.Duration {
+	return time.Duration(c.RateLimit.LockoutBaseSeconds) * time.Second
+}
+
+// GetLockoutMax 获取锁定时长的上限
+func (c *Config) GetLockoutMax() time.Duration {
+	return time.Duration(c.RateLimit.LockoutMaxMinutes) * time.Minute
+}
+
+// GetViewWindow 获取浏览量去重的时间窗口
+func (c *Config) GetViewWindow() time.Duration {
+	return time.Duration(c.Interaction.ViewWindowMinutes) * time.Minute
+}
+
+// GetStreamHeartbeat 获取实时推送的心跳间隔
+func (c *Config) GetStreamHeartbeat() time.Duration {
+	return time.Duration(c.Stream.HeartbeatSeconds) * time.Second
+}
+
+// GetStreamReplayTTL 获取用于断线补发的消息保留时长
+func (c *Config) GetStreamReplayTTL() time.Duration {
+	return time.Duration(c.Stream.ReplayMinutes) * time.Minute
+}
+
+// GetNotificationRetention 获取已读通知的保留时长
+func (c *Config) GetNotificationRetention() time.Duration {
+	return time.Duration(c.Notification.RetentionDays) * 24 * time.Hour
+}
+
+// GetWebhookTimeout 获取单次Webhook投递请求的超时时间
+func (c *Config) GetWebhookTimeout() time.Duration {
+	return time.Duration(c.Webhook.TimeoutSeconds) * time.Second
+}
+
+// GetWebhookDeliveryRetention 获取已完成Webhook投递记录的保留时长
+func (c *Config) GetWebhookDeliveryRetention() time.Duration {
+	return time.Duration(c.Webhook.DeliveryRetentionDays) * 24 * time.Hour
+}
+
+// GetSlowQueryThreshold 获取慢查询阈值
+func (c *Config) GetSlowQueryThreshold() time.Duration {
+	return time.Duration(c.Log.SlowQueryMs) * time.Millisecond
+}
+
+// GetRefreshTokenExpireTime 获取刷新令牌过期时间
+func (c *Config) GetRefreshTokenExpireTime() time.Duration {
+	return time.Duration(c.JWT.RefreshExpireHours) * time.Hour
 }
 
 // 辅助函数
@@ -146,3 +500,47 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList 读取逗号分隔的列表，忽略空项
+func getEnvAsList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvAsRateLimit 读取"次数/时长"格式的频率限制，如"10/1m"表示每分钟10次；"0"表示不限制
+func getEnvAsRateLimit(key string, defaultValue RateLimitPolicy) RateLimitPolicy {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if value == "0" {
+		return RateLimitPolicy{}
+	}
+
+	count, period, ok := strings.Cut(value, "/")
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if !ok || err != nil || requests < 0 {
+		slog.Warn("频率限制配置格式错误，使用默认值", "key", key)
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || duration <= 0 {
+		slog.Warn("频率限制配置格式错误，使用默认值", "key", key)
+		return defaultValue
+	}
+	return RateLimitPolicy{Requests: requests, Period: duration}
+}
@@ -1,13 +1,14 @@
 package database
 
 import (
-	"log"
+	"log/slog"
+	"os"
 
 	"blog-system/config"
+	"blog-system/logging"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // DB 全局数据库连接
@@ -17,31 +18,23 @@ var DB *gorm.DB
 func InitDB(cfg *config.Config) {
 	var err error
 
-	// 配置GORM日志
-	logLevel := logger.Silent
-	switch cfg.Log.Level {
-	case "info":
-		logLevel = logger.Info
-	case "warn":
-		logLevel = logger.Warn
-	case "error":
-		logLevel = logger.Error
-	}
-
+	// 配置GORM日志，输出到slog：LOG_LEVEL为debug时记录所有SQL，否则只记录执行失败的SQL和慢查询
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger: logging.NewGormLogger(slog.Default(), cfg.GetSlowQueryThreshold()),
 	}
 
 	// 连接MySQL数据库
 	DB, err = gorm.Open(mysql.Open(cfg.GetDSN()), gormConfig)
 	if err != nil {
-		log.Fatal("数据库连接失败:", err)
+		slog.Error("数据库连接失败", "error", err)
+		os.Exit(1)
 	}
 
 	// 获取底层的sql.DB对象进行连接池配置
 	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("获取数据库连接失败:", err)
+		slog.Error("获取数据库连接失败", "error", err)
+		os.Exit(1)
 	}
 
 	// 设置连接池参数
@@ -49,7 +42,7 @@ func InitDB(cfg *config.Config) {
 	sqlDB.SetMaxOpenConns(100)  // 设置打开数据库连接的最大数量
 	sqlDB.SetConnMaxLifetime(0) // 设置连接可复用的最大时间
 
-	log.Println("MySQL数据库连接成功")
+	slog.Info("MySQL数据库连接成功")
 }
 
 // GetDB 获取数据库连接实例
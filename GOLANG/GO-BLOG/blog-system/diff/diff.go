@@ -0,0 +1,131 @@
+package diff
+
+import "strings"
+
+// 差异类型
+const (
+	OpEqual  = "equal"  // 两个版本中都存在的行
+	OpInsert = "insert" // 新版本中新增的行
+	OpDelete = "delete" // 旧版本中删除的行
+)
+
+// Line 差异结果中的一行，OldLine和NewLine为从1开始的行号，不存在时为0
+type Line struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Stats 差异统计
+type Stats struct {
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+}
+
+// Lines 按行比较两段文本，使用Myers算法得到最短编辑脚本
+func Lines(a, b string) []Line {
+	return compare(splitLines(a), splitLines(b))
+}
+
+// Count 统计差异结果中新增和删除的行数
+func Count(lines []Line) Stats {
+	var stats Stats
+	for _, line := range lines {
+		switch line.Op {
+		case OpInsert:
+			stats.Insertions++
+		case OpDelete:
+			stats.Deletions++
+		}
+	}
+	return stats
+}
+
+// splitLines 按换行符拆分文本，统一处理\r\n，空文本返回空切片
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// compare Myers差分算法：记录每一步的V数组，再从终点回溯得到编辑脚本
+func compare(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return []Line{}
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // 向下移动：插入
+			} else {
+				x = v[offset+k-1] + 1 // 向右移动：删除
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return backtrack(trace, a, b, offset)
+}
+
+// backtrack 从终点沿trace回溯，生成按顺序排列的差异行
+func backtrack(trace [][]int, a, b []string, offset int) []Line {
+	x, y := len(a), len(b)
+	var reversed []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: OpEqual, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, Line{Op: OpInsert, Text: b[y-1], NewLine: y})
+		} else {
+			reversed = append(reversed, Line{Op: OpDelete, Text: a[x-1], OldLine: x})
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
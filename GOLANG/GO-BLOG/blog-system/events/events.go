@@ -0,0 +1,127 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	PostPublished       = "post.published"
+	PostUpdated         = "post.updated"
+	PostUnpublished     = "post.unpublished"
+	PostDeleted         = "post.deleted"
+	CommentCreated      = "comment.created"
+	CommentUpdated      = "comment.updated"
+	CommentDeleted      = "comment.deleted"
+	PostLiked           = "post.liked"
+	CommentLiked        = "comment.liked"
+	UserFollowed        = "user.followed"
+	NotificationCreated = "notification.created"
+)
+
+// Event 业务事件
+type Event struct {
+	Type     string    `json:"type"`
+	ActorID  uint      `json:"actor_id"`
+	TargetID uint      `json:"target_id"`
+	Data     any       `json:"data,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// subscriber 事件订阅者，每个订阅者拥有独立的队列和goroutine，处理慢的订阅者不会影响其他订阅者
+type subscriber struct {
+	name   string
+	handle func(Event)
+	queue  chan Event
+}
+
+// Bus 进程内的异步事件总线。Publish不会阻塞调用方，订阅者的队列已满时丢弃事件并记录日志
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []*subscriber
+	queueSize   int
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+// NewBus 创建事件总线，queueSize为每个订阅者的队列长度
+func NewBus(queueSize int) *Bus {
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	return &Bus{queueSize: queueSize}
+}
+
+// Subscribe 注册订阅者，handle在订阅者自己的goroutine中按发布顺序依次调用
+func (b *Bus) Subscribe(name string, handle func(Event)) {
+	s := &subscriber{name: name, handle: handle, queue: make(chan Event, b.queueSize)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subscribers = append(b.subscribers, s)
+	b.wg.Add(1)
+	go b.loop(s)
+}
+
+// Publish 发布事件，b为nil时不做任何操作
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, s := range b.subscribers {
+		select {
+		case s.queue <- e:
+		default:
+			slog.Warn("📣 事件订阅者队列已满，丢弃事件", "subscriber", s.name, "event", e.Type)
+		}
+	}
+}
+
+// Close 停止接收新事件，并等待订阅者处理完队列中的事件
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, s := range b.subscribers {
+		close(s.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (b *Bus) loop(s *subscriber) {
+	defer b.wg.Done()
+	for e := range s.queue {
+		b.dispatch(s, e)
+	}
+}
+
+// dispatch 调用订阅者处理事件，处理函数panic不会影响事件总线
+func (b *Bus) dispatch(s *subscriber, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("📣 事件订阅者处理异常", "subscriber", s.name, "event", e.Type, "error", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+	s.handle(e)
+}
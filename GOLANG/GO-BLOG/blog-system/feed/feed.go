@@ -0,0 +1,34 @@
+package feed
+
+import "time"
+
+// Feed 与格式无关的订阅源，由RSS、Atom和JSON Feed编码器输出
+type Feed struct {
+	Title       string
+	Link        string // 站点地址
+	FeedURL     string // 订阅源自身的地址
+	Description string
+	Language    string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item 订阅源中的一篇文章
+type Item struct {
+	ID          string // 全局唯一且不随标题变化的标识
+	Title       string
+	Link        string
+	Summary     string
+	ContentHTML string
+	Author      string
+	Categories  []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// 订阅源的MIME类型
+const (
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+)
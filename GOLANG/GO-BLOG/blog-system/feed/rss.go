@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type rssDocument struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	AtomNS       string     `xml:"xmlns:atom,attr"`
+	ContentNS    string     `xml:"xmlns:content,attr"`
+	DublinCoreNS string     `xml:"xmlns:dc,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Generator     string    `xml:"generator"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description,omitempty"`
+	Content     cdata    `xml:"content:encoded,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// cdata 以CDATA输出HTML内容，便于阅读器和人工查看
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// RSS 编码为RSS 2.0文档
+func RSS(f *Feed) ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.Link,
+		Description: f.Description,
+		Language:    f.Language,
+		Generator:   generator,
+		AtomLink:    atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+	}
+	if !f.Updated.IsZero() {
+		channel.LastBuildDate = f.Updated.Format(time.RFC1123Z)
+	}
+
+	for _, item := range f.Items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID},
+			Description: item.Summary,
+			Content:     cdata{Value: item.ContentHTML},
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		})
+	}
+
+	return marshalXML(rssDocument{
+		Version:      "2.0",
+		AtomNS:       "http://www.w3.org/2005/Atom",
+		ContentNS:    "http://purl.org/rss/1.0/modules/content/",
+		DublinCoreNS: "http://purl.org/dc/elements/1.1/",
+		Channel:      channel,
+	})
+}
+
+// marshalXML 输出带XML声明的缩进文档
+func marshalXML(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog-system/auth"
+	"blog-system/config"
+	"blog-system/mail"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mailTimeout 发送单封邮件的超时时间
+const mailTimeout = 30 * time.Second
+
+// AccountMailer 签发一次性令牌并发送邮箱验证和密码重置邮件
+type AccountMailer struct {
+	mailer    mail.Mailer
+	tokens    *models.UserTokenCRUD
+	appName   string
+	verifyURL string
+	resetURL  string
+	verifyTTL time.Duration
+	resetTTL  time.Duration
+}
+
+// NewAccountMailer 创建账户邮件发送器
+func NewAccountMailer(mailer mail.Mailer, tokens *models.UserTokenCRUD, cfg *config.Config) *AccountMailer {
+	return &AccountMailer{
+		mailer:    mailer,
+		tokens:    tokens,
+		appName:   cfg.App.Name,
+		verifyURL: cfg.Mail.VerifyURL,
+		resetURL:  cfg.Mail.ResetURL,
+		verifyTTL: cfg.GetVerifyTokenExpireTime(),
+		resetTTL:  cfg.GetResetTokenExpireTime(),
+	}
+}
+
+// SendVerification 发送邮箱验证邮件
+func (m *AccountMailer) SendVerification(ctx context.Context, user *models.User, lang string) error {
+	return m.send(ctx, user, lang, models.TokenPurposeVerifyEmail, mail.TemplateVerifyEmail, m.verifyURL, m.verifyTTL)
+}
+
+// SendPasswordReset 发送密码重置邮件
+func (m *AccountMailer) SendPasswordReset(ctx context.Context, user *models.User, lang string) error {
+	return m.send(ctx, user, lang, models.TokenPurposeResetPassword, mail.TemplateResetPassword, m.resetURL, m.resetTTL)
+}
+
+// sendAsync 在后台发送邮件，失败时只记录日志
+func (m *AccountMailer) sendAsync(send func(context.Context, *models.User, string) error, user *models.User, lang string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := send(ctx, user, lang); err != nil {
+			slog.Error("发送邮件失败", "user_id", user.ID, "error", err)
+		}
+	}()
+}
+
+func (m *AccountMailer) send(ctx context.Context, user *models.User, lang, purpose, template, baseURL string, ttl time.Duration) error {
+	token, err := m.tokens.Issue(user, purpose, ttl)
+	if err != nil {
+		return err
+	}
+
+	msg, err := mail.Render(template, lang, user.Email, mail.TemplateData{
+		AppName:   m.appName,
+		Username:  user.Username,
+		Link:      baseURL + token,
+		ExpiresIn: mail.FormatDuration(ttl, lang),
+	})
+	if err != nil {
+		return err
+	}
+	return m.mailer.Send(ctx, msg)
+}
+
+// AccountHandler 邮箱验证和密码找回处理器
+type AccountHandler struct {
+	userCRUD  *models.UserCRUD
+	tokenCRUD *models.TokenCRUD
+	tokens    *models.UserTokenCRUD
+	mails     *AccountMailer
+	audit     *Auditor
+}
+
+// NewAccountHandler 创建邮箱验证和密码找回处理器
+func NewAccountHandler(userCRUD *models.UserCRUD, tokenCRUD *models.TokenCRUD, tokens *models.UserTokenCRUD, mails *AccountMailer, audit *Auditor) *AccountHandler {
+	return &AccountHandler{
+		userCRUD:  userCRUD,
+		tokenCRUD: tokenCRUD,
+		tokens:    tokens,
+		mails:     mails,
+		audit:     audit,
+	}
+}
+
+// RequestVerification 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 向当前用户的邮箱发送验证邮件，之前发送的验证链接随之失效；邮件语言根据Accept-Language选择
+// @Tags 账户安全
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "发送成功"
+// @Failure 400 {object} models.Response "邮箱已验证"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "邮件发送失败"
+// @Router /email/verification [post]
+func (h *AccountHandler) RequestVerification(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	user, err := h.userCRUD.WithContext(c).GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "邮箱已验证",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), mailTimeout)
+	defer cancel()
+	if err := h.mails.SendVerification(ctx, user, mail.Lang(c.GetHeader("Accept-Language"))); err != nil {
+		slog.ErrorContext(c.Request.Context(), "发送验证邮件失败", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: "邮件发送失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "验证邮件已发送",
+	})
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用验证邮件中的令牌完成邮箱验证，令牌只能使用一次；支持通过查询参数(邮件链接)或请求体提交
+// @Tags 账户安全
+// @Accept json
+// @Produce json
+// @Param token query string false "验证令牌"
+// @Param request body models.VerifyEmailRequest false "验证令牌"
+// @Success 200 {object} models.Response "验证成功"
+// @Failure 400 {object} models.Response "令牌无效、已过期或已使用"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /email/verify [get]
+// @Router /email/verify [post]
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := h.tokens.WithContext(c).Consume(req.Token, models.TokenPurposeVerifyEmail)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, token.UserID)
+	user, err := h.userCRUD.WithContext(c).MarkEmailVerified(token.UserID, token.Email)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "邮箱已变更，请重新验证" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.RecordAs(c, token.UserID, models.AuditUpdate, models.AuditEntityUser, user.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "邮箱验证成功",
+		Data: gin.H{
+			"user_id":           user.ID,
+			"email":             user.Email,
+			"email_verified_at": user.EmailVerifiedAt,
+		},
+	})
+}
+
+// ForgotPassword 申请重置密码
+// @Summary 申请重置密码
+// @Description 向邮箱发送密码重置链接。无论邮箱是否注册都返回相同的结果，避免泄露账户信息；停用的账户不会收到邮件
+// @Tags 账户安全
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "注册邮箱"
+// @Success 200 {object} models.Response "请求已受理"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Router /password/forgot [post]
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 后台发送，响应时间不随邮箱是否存在而变化
+	if user, err := h.userCRUD.WithContext(c).GetByEmail(req.Email); err == nil && user.IsActive {
+		h.mails.sendAsync(h.mails.SendPasswordReset, user, mail.Lang(c.GetHeader("Accept-Language")))
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "如果该邮箱已注册，您将收到一封密码重置邮件",
+	})
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用密码重置邮件中的令牌设置新密码，令牌只能使用一次；重置后吊销该用户的所有刷新令牌
+// @Tags 账户安全
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "重置令牌和新密码"
+// @Success 200 {object} models.Response "重置成功"
+// @Failure 400 {object} models.Response "请求参数错误，令牌无效、已过期、已使用，或申请后邮箱已变更"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /password/reset [post]
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := h.tokens.WithContext(c).Consume(req.Token, models.TokenPurposeResetPassword)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	if err := h.userCRUD.WithContext(c).ResetPassword(token.UserID, token.Email, req.NewPassword); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "用户不存在", "邮箱已变更，请重新申请重置密码":
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.RecordAs(c, token.UserID, models.AuditPassword, models.AuditEntityUser, token.UserID, nil)
+
+	// 吊销所有会话，可能泄露的旧密码登录的设备需要重新登录
+	h.tokenCRUD.WithContext(c).RevokeAllForUser(token.UserID)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "密码重置成功，请使用新密码登录",
+	})
+}
+
+// respondTokenError 返回一次性令牌校验失败的响应
+func respondTokenError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch err.Error() {
+	case "无效的令牌", "令牌已过期", "令牌已使用":
+		statusCode = http.StatusBadRequest
+	}
+	c.JSON(statusCode, models.Response{
+		Code:    statusCode,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"blog-system/config"
+	"blog-system/internal/testdb"
+	"blog-system/mail"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenPattern 从邮件链接中提取令牌
+var tokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_.-]+)`)
+
+// accountTestEnv 使用Outbox收集邮件的账户处理器测试环境
+type accountTestEnv struct {
+	router  *gin.Engine
+	outbox  *mail.Outbox
+	users   *models.UserCRUD
+	tokens  *models.TokenCRUD
+	handler *AccountHandler
+	user    *models.User
+}
+
+func newAccountTestEnv(t *testing.T) *accountTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	outbox, err := mail.NewOutbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &accountTestEnv{
+		outbox: outbox,
+		users:  models.NewUserCRUD(db),
+		tokens: models.NewTokenCRUD(db, time.Hour),
+	}
+	env.user, err = env.users.Create(&models.RegisterRequest{Username: "alice", Password: "old-password", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	userTokens := models.NewUserTokenCRUD(db, []byte("test-secret"))
+	env.handler = NewAccountHandler(env.users, env.tokens, userTokens, newTestAccountMailer(outbox, userTokens, 24, 30), NewAuditor(models.NewAuditCRUD(db)))
+
+	env.router = gin.New()
+	env.router.POST("/email/verification", func(c *gin.Context) { c.Set("user_id", env.user.ID) }, env.handler.RequestVerification)
+	env.router.GET("/email/verify", env.handler.VerifyEmail)
+	env.router.POST("/password/forgot", env.handler.ForgotPassword)
+	env.router.POST("/password/reset", env.handler.ResetPassword)
+	return env
+}
+
+func newTestAccountMailer(outbox *mail.Outbox, tokens *models.UserTokenCRUD, verifyHours, resetMinutes int) *AccountMailer {
+	return NewAccountMailer(outbox, tokens, &config.Config{
+		App: config.AppConfig{Name: "测试博客"},
+		Mail: config.MailConfig{
+			VerifyURL:            "https://blog.example.com/verify?token=",
+			ResetURL:             "https://blog.example.com/reset?token=",
+			VerifyTokenTTLHours:  verifyHours,
+			ResetTokenTTLMinutes: resetMinutes,
+		},
+	})
+}
+
+func (e *accountTestEnv) do(t *testing.T, method, path string, body any) (int, models.Response) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s 响应不是JSON: %s", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// waitToken 等待发送给to的新邮件(ForgotPassword在后台发送)，返回邮件链接中的令牌
+func (e *accountTestEnv) waitToken(t *testing.T, to string, sent int) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.outbox.Messages()) <= sent {
+		if time.Now().After(deadline) {
+			t.Fatal("等待邮件超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	msg, ok := e.outbox.Last(to)
+	if !ok {
+		t.Fatalf("没有发送给%s的邮件", to)
+	}
+	match := tokenPattern.FindStringSubmatch(msg.Text)
+	if match == nil {
+		t.Fatalf("邮件中没有令牌链接: %s", msg.Text)
+	}
+	return match[1]
+}
+
+func TestVerifyEmailWithOutboxToken(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	if code, resp := env.do(t, http.MethodPost, "/email/verification", nil); code != http.StatusOK {
+		t.Fatalf("RequestVerification = %d, %s", code, resp.Message)
+	}
+	msg, ok := env.outbox.Last("alice@example.com")
+	if !ok || msg.Subject == "" || msg.HTML == "" {
+		t.Fatalf("验证邮件 = %+v", msg)
+	}
+	token := env.waitToken(t, "alice@example.com", 0)
+
+	if code, resp := env.do(t, http.MethodGet, "/email/verify?token="+token, nil); code != http.StatusOK {
+		t.Fatalf("VerifyEmail = %d, %s", code, resp.Message)
+	}
+	user, _ := env.users.GetByID(env.user.ID)
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("验证成功后应记录邮箱验证时间")
+	}
+
+	code, resp := env.do(t, http.MethodGet, "/email/verify?token="+token, nil)
+	if code != http.StatusBadRequest || resp.Message != "令牌已使用" {
+		t.Fatalf("重复使用令牌 = %d, %s", code, resp.Message)
+	}
+	if code, resp := env.do(t, http.MethodPost, "/email/verification", nil); code != http.StatusBadRequest || resp.Message != "邮箱已验证" {
+		t.Fatalf("已验证后再次申请 = %d, %s", code, resp.Message)
+	}
+}
+
+func TestVerifyEmailRejectsReplacedToken(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	env.do(t, http.MethodPost, "/email/verification", nil)
+	first := env.waitToken(t, "alice@example.com", 0)
+	env.do(t, http.MethodPost, "/email/verification", nil)
+	second := env.waitToken(t, "alice@example.com", 1)
+
+	// 重新发送后旧链接失效
+	if code, resp := env.do(t, http.MethodGet, "/email/verify?token="+first, nil); code != http.StatusBadRequest || resp.Message != "令牌已使用" {
+		t.Fatalf("旧令牌 = %d, %s", code, resp.Message)
+	}
+	if code, resp := env.do(t, http.MethodGet, "/email/verify?token="+second, nil); code != http.StatusOK {
+		t.Fatalf("新令牌 = %d, %s", code, resp.Message)
+	}
+}
+
+func TestResetPasswordWithOutboxToken(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	refresh, _, err := env.tokens.IssueRefreshToken(env.user.ID, "")
+	if err != nil {
+		t.Fatalf("签发刷新令牌失败: %v", err)
+	}
+
+	if code, _ := env.do(t, http.MethodPost, "/password/forgot", gin.H{"email": "alice@example.com"}); code != http.StatusOK {
+		t.Fatalf("ForgotPassword = %d", code)
+	}
+	token := env.waitToken(t, "alice@example.com", 0)
+
+	reset := gin.H{"token": token, "new_password": "new-password"}
+	if code, resp := env.do(t, http.MethodPost, "/password/reset", reset); code != http.StatusOK {
+		t.Fatalf("ResetPassword = %d, %s", code, resp.Message)
+	}
+	user, _ := env.users.GetByUsername("alice")
+	if env.users.VerifyPassword(user, "new-password") != nil || env.users.VerifyPassword(user, "old-password") == nil {
+		t.Fatal("重置后应只能使用新密码")
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("通过重置邮件设置密码后邮箱应视为已验证")
+	}
+
+	// 重置前签发的刷新令牌全部吊销
+	if _, _, err := env.tokens.Rotate(refresh); err == nil || err.Error() != "刷新令牌已被重复使用" {
+		t.Fatalf("重置密码后旧的刷新令牌应已吊销: %v", err)
+	}
+
+	reset["new_password"] = "another-password"
+	code, resp := env.do(t, http.MethodPost, "/password/reset", reset)
+	if code != http.StatusBadRequest || resp.Message != "令牌已使用" {
+		t.Fatalf("重复使用令牌 = %d, %s", code, resp.Message)
+	}
+	user, _ = env.users.GetByUsername("alice")
+	if env.users.VerifyPassword(user, "new-password") != nil {
+		t.Fatal("重复使用令牌不应修改密码")
+	}
+}
+
+func TestResetPasswordRejectsInvalidTokens(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	// 有效期为负数的令牌签发后即过期
+	expired := newTestAccountMailer(env.outbox, env.handler.tokens, 24, -1)
+	if err := expired.SendPasswordReset(t.Context(), env.user, "zh"); err != nil {
+		t.Fatalf("发送重置邮件失败: %v", err)
+	}
+	token := env.waitToken(t, "alice@example.com", 0)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantMsg string
+	}{
+		{"令牌已过期", token, "令牌已过期"},
+		{"签名被篡改", token + "x", "无效的令牌"},
+		{"格式错误", "not-a-token", "无效的令牌"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := env.do(t, http.MethodPost, "/password/reset", gin.H{"token": tt.token, "new_password": "new-password"})
+			if code != http.StatusBadRequest || resp.Message != tt.wantMsg {
+				t.Fatalf("ResetPassword = %d, %s, want 400 %s", code, resp.Message, tt.wantMsg)
+			}
+		})
+	}
+
+	// 验证邮箱的令牌不能用于重置密码
+	verifyMailer := newTestAccountMailer(env.outbox, env.handler.tokens, 24, 30)
+	if err := verifyMailer.SendVerification(t.Context(), env.user, "zh"); err != nil {
+		t.Fatal(err)
+	}
+	verifyToken := env.waitToken(t, "alice@example.com", 1)
+	if code, resp := env.do(t, http.MethodPost, "/password/reset", gin.H{"token": verifyToken, "new_password": "new-password"}); code != http.StatusBadRequest || resp.Message != "无效的令牌" {
+		t.Fatalf("用途不符的令牌 = %d, %s", code, resp.Message)
+	}
+
+	user, _ := env.users.GetByUsername("alice")
+	if env.users.VerifyPassword(user, "old-password") != nil {
+		t.Fatal("令牌无效时不应修改密码")
+	}
+}
+
+func TestResetPasswordRejectsTokenAfterEmailChange(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	env.do(t, http.MethodPost, "/password/forgot", gin.H{"email": "alice@example.com"})
+	token := env.waitToken(t, "alice@example.com", 0)
+
+	// 发出重置邮件后用户修改了邮箱，发往旧邮箱的链接不能再重置密码
+	if _, err := env.users.UpdateProfile(env.user.ID, &models.ProfileRequest{Email: "alice@new.example.com"}); err != nil {
+		t.Fatalf("修改邮箱失败: %v", err)
+	}
+
+	code, resp := env.do(t, http.MethodPost, "/password/reset", gin.H{"token": token, "new_password": "new-password"})
+	if code != http.StatusBadRequest || resp.Message != "邮箱已变更，请重新申请重置密码" {
+		t.Fatalf("ResetPassword = %d, %s", code, resp.Message)
+	}
+	user, _ := env.users.GetByUsername("alice")
+	if env.users.VerifyPassword(user, "old-password") != nil {
+		t.Fatal("邮箱变更后旧链接不应修改密码")
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Fatal("旧邮箱的重置链接不应将新邮箱标记为已验证")
+	}
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-system/auth"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 管理员处理器
+type AdminHandler struct {
+	userCRUD  *models.UserCRUD
+	tokenCRUD *models.TokenCRUD
+	audit     *Auditor
+}
+
+// NewAdminHandler 创建管理员处理器
+func NewAdminHandler(userCRUD *models.UserCRUD, tokenCRUD *models.TokenCRUD, audit *Auditor) *AdminHandler {
+	return &AdminHandler{
+		userCRUD:  userCRUD,
+		tokenCRUD: tokenCRUD,
+		audit:     audit,
+	}
+}
+
+// ListUsers 获取用户列表
+// @Summary 获取用户列表
+// @Description 获取所有用户及其角色，仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	users, err := h.userCRUD.WithContext(c).List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取用户列表成功",
+		Data:    users,
+	})
+}
+
+// UpdateUserRole 修改用户角色
+// @Summary 修改用户角色
+// @Description 修改指定用户的角色，仅管理员可用；修改后立即生效，该用户已签发的访问令牌按新角色鉴权
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body models.RoleRequest true "角色信息"
+// @Success 200 {object} models.Response "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /admin/users/{id}/role [put]
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	adminID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 防止管理员误操作导致系统失去管理员
+	if adminID == uint(id) {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "不能修改自己的角色",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, uint(id))
+	user, err := h.userCRUD.WithContext(c).UpdateRole(uint(id), req.Role)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityUser, user.ID, before)
+
+	// 吊销该用户的刷新令牌，使新角色在下次登录后生效
+	h.tokenCRUD.WithContext(c).RevokeAllForUser(user.ID)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "用户角色修改成功",
+		Data:    user,
+	})
+}
+
+// DeactivateUser 停用用户
+// @Summary 停用用户
+// @Description 停用用户账户并吊销其刷新令牌，停用后该用户无法登录，已签发的访问令牌也会被拒绝；仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} models.Response "停用成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /admin/users/{id}/deactivate [post]
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+// ActivateUser 启用用户
+// @Summary 启用用户
+// @Description 重新启用已停用的用户账户；仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} models.Response "启用成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /admin/users/{id}/activate [post]
+func (h *AdminHandler) ActivateUser(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *AdminHandler) setActive(c *gin.Context, active bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	adminID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	if adminID == uint(id) {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "不能修改自己的账户状态",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, uint(id))
+	user, err := h.userCRUD.WithContext(c).SetActive(uint(id), active)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityUser, user.ID, before)
+
+	message := "用户启用成功"
+	if !active {
+		// 吊销刷新令牌，使停用立即对所有设备生效
+		h.tokenCRUD.WithContext(c).RevokeAllForUser(user.ID)
+		message = "用户停用成功"
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: message,
+		Data:    user,
+	})
+}
+
+// DeleteUser 删除用户
+// @Summary 删除用户
+// @Description 将用户移入回收站并吊销其刷新令牌，用户的文章和评论保留，超过保留期后永久删除；仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	adminID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	if adminID == uint(id) {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "不能删除自己",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, uint(id))
+	if err := h.userCRUD.WithContext(c).Delete(uint(id)); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityUser, uint(id), before)
+
+	// 吊销该用户的刷新令牌，使其无法继续续期
+	h.tokenCRUD.WithContext(c).RevokeAllForUser(uint(id))
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "用户删除成功",
+	})
+}
+
+// ListDeletedUsers 获取已删除的用户
+// @Summary 获取已删除的用户
+// @Description 获取回收站中的用户，按删除时间倒序排列；仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /admin/users/trash [get]
+func (h *AdminHandler) ListDeletedUsers(c *gin.Context) {
+	users, err := h.userCRUD.WithContext(c).ListDeleted()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取已删除用户成功",
+		Data:    users,
+	})
+}
+
+// RestoreUser 恢复用户
+// @Summary 恢复用户
+// @Description 从回收站恢复用户，恢复后用户需要重新登录；仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} models.Response "恢复成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "回收站中不存在该用户"
+// @Router /admin/users/{id}/restore [post]
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, uint(id))
+	user, err := h.userCRUD.WithContext(c).Restore(uint(id))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "回收站中不存在该用户" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditRestore, models.AuditEntityUser, user.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "用户恢复成功",
+		Data:    user,
+	})
+}
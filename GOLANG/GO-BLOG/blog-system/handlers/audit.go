@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"blog-system/auth"
+	"blog-system/middleware"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auditor 记录文章、评论、用户、Webhook、标签、分类和媒体文件修改操作的审计日志。
+// 修改前调用Snapshot获取对象的原始状态，修改成功后调用Record记录
+type Auditor struct {
+	auditCRUD *models.AuditCRUD
+}
+
+// NewAuditor 创建审计记录器
+func NewAuditor(auditCRUD *models.AuditCRUD) *Auditor {
+	return &Auditor{auditCRUD: auditCRUD}
+}
+
+// Snapshot 获取对象修改前的状态，创建操作不需要调用
+func (a *Auditor) Snapshot(entityType string, id uint) map[string]any {
+	return a.auditCRUD.Snapshot(entityType, id)
+}
+
+// Record 以当前登录用户为操作人记录一次修改
+func (a *Auditor) Record(c *gin.Context, action, entityType string, id uint, before map[string]any) {
+	actorID, _ := auth.GetUserID(c)
+	a.RecordAs(c, actorID, action, entityType, id, before)
+}
+
+// RecordAs 以指定用户为操作人记录一次修改，用于注册、重置密码等未登录的操作。
+// 删除操作只记录删除前的状态，其他操作读取对象当前的状态作为修改后的状态。
+// 修改已经生效，记录失败只写入日志，不影响请求的响应
+func (a *Auditor) RecordAs(c *gin.Context, actorID uint, action, entityType string, id uint, before map[string]any) {
+	// 修改已经生效，客户端断开连接时也要完成记录
+	auditCRUD := a.auditCRUD.WithContext(context.WithoutCancel(c.Request.Context()))
+	var after map[string]any
+	if action != models.AuditDelete {
+		after = auditCRUD.Snapshot(entityType, id)
+	}
+
+	_, err := auditCRUD.Record(&models.AuditEntry{
+		ActorID:    actorID,
+		IP:         c.ClientIP(),
+		RequestID:  middleware.GetRequestID(c),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "📝 记录审计日志失败", "action", action, "entity_type", entityType, "entity_id", id, "error", err)
+	}
+}
+
+// AuditHandler 审计日志查询处理器
+type AuditHandler struct {
+	auditCRUD *models.AuditCRUD
+}
+
+// NewAuditHandler 创建审计日志查询处理器
+func NewAuditHandler(auditCRUD *models.AuditCRUD) *AuditHandler {
+	return &AuditHandler{auditCRUD: auditCRUD}
+}
+
+// ListAuditLogs 查询审计日志
+// @Summary 查询审计日志
+// @Description 分页查询文章、评论、用户和Webhook的修改记录，按时间倒序排列，仅管理员可用。
+// @Description before和after为修改前后发生变化的字段，创建时before为null，删除时after为null；密码等不对外输出的字段不会记录
+// @Tags 审计日志
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "操作人ID"
+// @Param action query string false "操作" Enums(create, update, delete, restore, publish, unpublish, password)
+// @Param entity_type query string false "对象类型" Enums(post, comment, user, webhook, tag, category, media)
+// @Param entity_id query int false "对象ID"
+// @Param request_id query string false "请求ID"
+// @Param from query string false "开始时间，RFC3339格式或YYYY-MM-DD"
+// @Param to query string false "结束时间，RFC3339格式或YYYY-MM-DD(包含当天)"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.AuditLog} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /admin/audit [get]
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	var query models.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	logs, pagination, err := h.auditCRUD.WithContext(c).List(&query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() != "获取审计日志失败" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取审计日志成功",
+		Data:       logs,
+		Pagination: pagination,
+	})
+}
+
+// VerifyAuditLogs 校验审计日志
+// @Summary 校验审计日志
+// @Description 按序号逐条校验审计日志的哈希链，检测记录是否被修改、删除或插入，返回第一处不一致的序号和原因
+// @Tags 审计日志
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=models.AuditVerification} "校验完成"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /admin/audit/verify [get]
+func (h *AuditHandler) VerifyAuditLogs(c *gin.Context) {
+	result, err := h.auditCRUD.WithContext(c).Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	message := "审计日志完整"
+	if !result.Valid {
+		message = "审计日志已被篡改"
+	}
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: message,
+		Data:    result,
+	})
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-system/auth"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookmarkHandler 收藏处理器
+type BookmarkHandler struct {
+	bookmarkCRUD *models.BookmarkCRUD
+}
+
+// NewBookmarkHandler 创建收藏处理器
+func NewBookmarkHandler(bookmarkCRUD *models.BookmarkCRUD) *BookmarkHandler {
+	return &BookmarkHandler{bookmarkCRUD: bookmarkCRUD}
+}
+
+// bookmarkErrorStatus 根据错误信息确定响应状态码
+func bookmarkErrorStatus(err error) int {
+	switch err.Error() {
+	case "文章不存在", "收藏不存在", "收藏夹不存在":
+		return http.StatusNotFound
+	case "收藏夹已存在":
+		return http.StatusConflict
+	case "收藏夹名称不能为空", "无效的分页游标":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListBookmarks 获取我的收藏
+// @Summary 获取我的收藏
+// @Description 分页获取当前用户收藏的文章，按收藏时间倒序排列，可按收藏夹筛选
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param collection_id query int false "收藏夹ID"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.Bookmark} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "收藏夹不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks [get]
+func (h *BookmarkHandler) ListBookmarks(c *gin.Context) {
+	var query models.BookmarkQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	bookmarks, pagination, err := h.bookmarkCRUD.WithContext(c).List(userID, &query)
+	if err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取收藏列表成功",
+		Data:       bookmarks,
+		Pagination: pagination,
+	})
+}
+
+// AddBookmark 收藏文章
+// @Summary 收藏文章
+// @Description 将文章收藏到指定收藏夹，收藏夹不存在时自动创建，未指定时使用默认收藏夹；重复收藏不产生影响
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BookmarkRequest true "收藏信息"
+// @Success 201 {object} models.Response{data=models.Bookmark} "收藏成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks [post]
+func (h *BookmarkHandler) AddBookmark(c *gin.Context) {
+	var req models.BookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	bookmark, err := h.bookmarkCRUD.WithContext(c).Add(userID, &req)
+	if err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "收藏成功",
+		Data:    bookmark,
+	})
+}
+
+// RemoveBookmark 取消收藏
+// @Summary 取消收藏
+// @Description 从收藏夹中移除收藏
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "收藏ID"
+// @Success 200 {object} models.Response "取消收藏成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "收藏不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks/{id} [delete]
+func (h *BookmarkHandler) RemoveBookmark(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的收藏ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	if err := h.bookmarkCRUD.WithContext(c).Remove(uint(id), userID); err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "取消收藏成功",
+	})
+}
+
+// ListCollections 获取我的收藏夹
+// @Summary 获取我的收藏夹
+// @Description 获取当前用户的全部收藏夹及每个收藏夹的收藏数量
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=[]models.BookmarkCollection} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks/collections [get]
+func (h *BookmarkHandler) ListCollections(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	collections, err := h.bookmarkCRUD.WithContext(c).ListCollections(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取收藏夹成功",
+		Data:    collections,
+	})
+}
+
+// CreateCollection 创建收藏夹
+// @Summary 创建收藏夹
+// @Description 创建收藏夹，公开收藏夹中新增的收藏会出现在粉丝的首页动态中
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CollectionRequest true "收藏夹信息"
+// @Success 201 {object} models.Response{data=models.BookmarkCollection} "创建成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 409 {object} models.Response "收藏夹已存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks/collections [post]
+func (h *BookmarkHandler) CreateCollection(c *gin.Context) {
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	collection, err := h.bookmarkCRUD.WithContext(c).CreateCollection(userID, &req)
+	if err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "创建收藏夹成功",
+		Data:    collection,
+	})
+}
+
+// UpdateCollection 修改收藏夹
+// @Summary 修改收藏夹
+// @Description 修改收藏夹的名称和公开状态
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "收藏夹ID"
+// @Param request body models.CollectionRequest true "收藏夹信息"
+// @Success 200 {object} models.Response{data=models.BookmarkCollection} "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "收藏夹不存在"
+// @Failure 409 {object} models.Response "收藏夹已存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks/collections/{id} [put]
+func (h *BookmarkHandler) UpdateCollection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的收藏夹ID",
+		})
+		return
+	}
+
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	collection, err := h.bookmarkCRUD.WithContext(c).UpdateCollection(uint(id), userID, &req)
+	if err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "修改收藏夹成功",
+		Data:    collection,
+	})
+}
+
+// DeleteCollection 删除收藏夹
+// @Summary 删除收藏夹
+// @Description 删除收藏夹及其中的全部收藏
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "收藏夹ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "收藏夹不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /bookmarks/collections/{id} [delete]
+func (h *BookmarkHandler) DeleteCollection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的收藏夹ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	if err := h.bookmarkCRUD.WithContext(c).DeleteCollection(uint(id), userID); err != nil {
+		statusCode := bookmarkErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "删除收藏夹成功",
+	})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-system/config"
+	"blog-system/feed"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedMaxAge 订阅源的缓存时间(秒)，阅读器之后通过ETag和Last-Modified重新验证
+const feedMaxAge = 300
+
+// FeedHandler RSS、Atom和JSON Feed订阅源处理器。订阅源是公开的，不经过AuthMiddleware
+type FeedHandler struct {
+	postCRUD *models.PostCRUD
+	userCRUD *models.UserCRUD
+	cfg      config.FeedConfig
+}
+
+// NewFeedHandler 创建订阅源处理器
+func NewFeedHandler(postCRUD *models.PostCRUD, userCRUD *models.UserCRUD, cfg *config.Config) *FeedHandler {
+	feedCfg := cfg.Feed
+	if feedCfg.Title == "" {
+		feedCfg.Title = cfg.App.Name
+	}
+	return &FeedHandler{postCRUD: postCRUD, userCRUD: userCRUD, cfg: feedCfg}
+}
+
+// RSS 输出RSS 2.0订阅源：GET /feed.xml 和 GET /users/:username/feed.xml
+func (h *FeedHandler) RSS(c *gin.Context) {
+	h.serve(c, feed.ContentTypeRSS, feed.RSS)
+}
+
+// Atom 输出Atom 1.0订阅源：GET /atom.xml 和 GET /users/:username/atom.xml
+func (h *FeedHandler) Atom(c *gin.Context) {
+	h.serve(c, feed.ContentTypeAtom, feed.Atom)
+}
+
+// JSON 输出JSON Feed 1.1订阅源：GET /feed.json 和 GET /users/:username/feed.json
+func (h *FeedHandler) JSON(c *gin.Context) {
+	h.serve(c, feed.ContentTypeJSON, feed.JSON)
+}
+
+// serve 生成订阅源并处理条件请求；路径中带username时只包含该作者的文章
+func (h *FeedHandler) serve(c *gin.Context, contentType string, encode func(*feed.Feed) ([]byte, error)) {
+	f := &feed.Feed{
+		Title:       h.cfg.Title,
+		Link:        h.cfg.SiteURL + "/",
+		FeedURL:     h.cfg.SiteURL + c.Request.URL.Path,
+		Description: h.cfg.Description,
+		Language:    h.cfg.Language,
+	}
+
+	var authorID uint
+	if username := c.Param("username"); username != "" {
+		profile, err := h.userCRUD.WithContext(c).GetPublicProfile(username)
+		if err != nil {
+			statusCode := http.StatusInternalServerError
+			if err.Error() == "用户不存在" {
+				statusCode = http.StatusNotFound
+			}
+			c.JSON(statusCode, models.Response{
+				Code:    statusCode,
+				Message: err.Error(),
+			})
+			return
+		}
+		authorID = profile.ID
+		f.Title += " - " + displayName(profile.Nickname, profile.Username)
+		if profile.Bio != "" {
+			f.Description = profile.Bio
+		}
+	}
+
+	updated, err := h.postCRUD.WithContext(c).FeedUpdatedAt(authorID)
+	if err == nil {
+		var posts []models.Post
+		if posts, err = h.postCRUD.WithContext(c).ListFeed(authorID, h.cfg.ItemCount); err == nil {
+			h.fill(f, posts, updated)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	body, err := encode(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: "订阅源生成失败",
+		})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", f.Updated.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(feedMaxAge))
+
+	if notModified(c.Request, etag, f.Updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// fill 将文章转换为订阅源条目
+func (h *FeedHandler) fill(f *feed.Feed, posts []models.Post, updated time.Time) {
+	// 没有任何文章时使用固定的时间，保证响应内容稳定
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	f.Updated = updated.UTC().Truncate(time.Second)
+
+	for _, post := range posts {
+		item := feed.Item{
+			ID:          h.cfg.PostURL + strconv.FormatUint(uint64(post.ID), 10),
+			Title:       post.Title,
+			Link:        h.cfg.PostURL + post.Slug,
+			Summary:     post.Summary,
+			ContentHTML: post.ContentHTML,
+			Author:      displayName(post.User.Nickname, post.User.Username),
+			Published:   post.CreatedAt,
+			Updated:     post.UpdatedAt,
+		}
+		if post.PublishedAt != nil {
+			item.Published = *post.PublishedAt
+		}
+		if post.Category != nil {
+			item.Categories = append(item.Categories, post.Category.Name)
+		}
+		for _, tag := range post.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		f.Items = append(f.Items, item)
+	}
+}
+
+// notModified 判断条件请求是否命中缓存，If-None-Match优先于If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		return !lastModified.After(since)
+	}
+	return false
+}
+
+// displayName 优先使用昵称
+func displayName(nickname, username string) string {
+	if nickname != "" {
+		return nickname
+	}
+	return username
+}
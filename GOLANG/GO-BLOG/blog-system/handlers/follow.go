@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowHandler 关注和首页动态处理器
+type FollowHandler struct {
+	followCRUD *models.FollowCRUD
+	userCRUD   *models.UserCRUD
+	bus        *events.Bus
+}
+
+// NewFollowHandler 创建关注处理器
+func NewFollowHandler(followCRUD *models.FollowCRUD, userCRUD *models.UserCRUD, bus *events.Bus) *FollowHandler {
+	return &FollowHandler{followCRUD: followCRUD, userCRUD: userCRUD, bus: bus}
+}
+
+// Follow 关注用户
+// @Summary 关注用户
+// @Description 关注指定用户，重复关注不产生影响；关注后该用户发布的文章和公开收藏会出现在首页动态中
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param username path string true "用户名"
+// @Success 200 {object} models.Response "关注成功"
+// @Failure 400 {object} models.Response "不能关注自己"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/{username}/follow [put]
+func (h *FollowHandler) Follow(c *gin.Context) {
+	h.setFollow(c, true)
+}
+
+// Unfollow 取消关注
+// @Summary 取消关注
+// @Description 取消关注指定用户，未关注时不产生影响
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param username path string true "用户名"
+// @Success 200 {object} models.Response "取消关注成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/{username}/follow [delete]
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	h.setFollow(c, false)
+}
+
+func (h *FollowHandler) setFollow(c *gin.Context, follow bool) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	profile, err := h.userCRUD.WithContext(c).GetPublicProfile(c.Param("username"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	message := "关注成功"
+	if follow {
+		err = h.followCRUD.WithContext(c).Follow(userID, profile.ID)
+		if err == nil {
+			h.bus.Publish(events.Event{Type: events.UserFollowed, ActorID: userID, TargetID: profile.ID})
+		}
+	} else {
+		err = h.followCRUD.WithContext(c).Unfollow(userID, profile.ID)
+		message = "取消关注成功"
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "不能关注自己" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 返回最新的关注数
+	profile, err = h.userCRUD.WithContext(c).GetPublicProfile(profile.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: message,
+		Data: gin.H{
+			"following":      follow,
+			"follower_count": profile.FollowerCount,
+		},
+	})
+}
+
+// ListFollowers 获取用户的粉丝
+// @Summary 获取用户的粉丝
+// @Description 分页获取关注该用户的用户，按关注时间倒序排列，无需认证
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Param username path string true "用户名"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.UserSummary} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/{username}/followers [get]
+func (h *FollowHandler) ListFollowers(c *gin.Context) {
+	h.list(c, h.followCRUD.ListFollowers, "获取粉丝列表成功")
+}
+
+// ListFollowing 获取用户关注的人
+// @Summary 获取用户关注的人
+// @Description 分页获取该用户关注的用户，按关注时间倒序排列，无需认证
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Param username path string true "用户名"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.UserSummary} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/{username}/following [get]
+func (h *FollowHandler) ListFollowing(c *gin.Context) {
+	h.list(c, h.followCRUD.ListFollowing, "获取关注列表成功")
+}
+
+func (h *FollowHandler) list(c *gin.Context, list func(uint, *models.PageQuery) ([]models.UserSummary, *models.Pagination, error), message string) {
+	var query models.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	profile, err := h.userCRUD.WithContext(c).GetPublicProfile(c.Param("username"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	users, pagination, err := list(profile.ID, &query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    message,
+		Data:       users,
+		Pagination: pagination,
+	})
+}
+
+// HomeFeed 获取首页动态
+// @Summary 获取首页动态
+// @Description 分页获取当前用户的首页动态：关注的作者发布的文章(type=post)和关注的用户收藏到公开收藏夹的文章(type=bookmark)，按时间倒序合并
+// @Tags 关注和收藏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.FeedItem} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /feed [get]
+func (h *FollowHandler) HomeFeed(c *gin.Context) {
+	var query models.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	items, pagination, err := h.followCRUD.WithContext(c).HomeFeed(userID, &query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取动态成功",
+		Data:       items,
+		Pagination: pagination,
+	})
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/mail"
+	"blog-system/metrics"
+	"blog-system/middleware"
 	"blog-system/models"
+	"blog-system/ratelimit"
+	"blog-system/spam"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,20 +21,30 @@ import (
 // UserHandler 用户处理器
 type UserHandler struct {
 	userCRUD   *models.UserCRUD
+	postCRUD   *models.PostCRUD
+	tokenCRUD  *models.TokenCRUD
 	jwtManager *auth.JWTManager
+	mails      *AccountMailer
+	guard      *ratelimit.LoginGuard
+	audit      *Auditor
 }
 
 // NewUserHandler 创建用户处理器
-func NewUserHandler(userCRUD *models.UserCRUD, jwtManager *auth.JWTManager) *UserHandler {
+func NewUserHandler(userCRUD *models.UserCRUD, postCRUD *models.PostCRUD, tokenCRUD *models.TokenCRUD, jwtManager *auth.JWTManager, mails *AccountMailer, guard *ratelimit.LoginGuard, audit *Auditor) *UserHandler {
 	return &UserHandler{
 		userCRUD:   userCRUD,
+		postCRUD:   postCRUD,
+		tokenCRUD:  tokenCRUD,
 		jwtManager: jwtManager,
+		mails:      mails,
+		guard:      guard,
+		audit:      audit,
 	}
 }
 
 // Register 用户注册
 // @Summary 用户注册
-// @Description 创建新用户账户
+// @Description 创建新用户账户，并向注册邮箱发送验证邮件；邮件语言根据Accept-Language选择
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -47,7 +64,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userCRUD.Create(&req)
+	user, err := h.userCRUD.WithContext(c).Create(&req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "用户名已存在" || err.Error() == "邮箱已存在" {
@@ -59,10 +76,14 @@ func (h *UserHandler) Register(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.RecordAs(c, user.ID, models.AuditCreate, models.AuditEntityUser, user.ID, nil)
+	metrics.UserRegistrations.Inc()
+
+	h.mails.sendAsync(h.mails.SendVerification, user, mail.Lang(c.GetHeader("Accept-Language")))
 
 	c.JSON(http.StatusCreated, models.Response{
 		Code:    201,
-		Message: "用户注册成功",
+		Message: "用户注册成功，请查收验证邮件",
 		Data: gin.H{
 			"user_id":  user.ID,
 			"username": user.Username,
@@ -73,7 +94,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录获取JWT令牌
+// @Description 用户登录获取短期访问令牌和刷新令牌；同一用户名连续登录失败多次后暂时锁定，锁定时长逐次递增
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -81,6 +102,8 @@ func (h *UserHandler) Register(c *gin.Context) {
 // @Success 200 {object} models.Response "登录成功"
 // @Failure 400 {object} models.Response "请求参数错误"
 // @Failure 401 {object} models.Response "用户名或密码错误"
+// @Failure 403 {object} models.Response "账户已被停用"
+// @Failure 429 {object} models.Response "请求过于频繁或登录失败次数过多"
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Router /login [post]
 func (h *UserHandler) Login(c *gin.Context) {
@@ -93,8 +116,15 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userCRUD.GetByUsername(req.Username)
+	if wait := h.guard.Check(req.Username, time.Now()); wait > 0 {
+		middleware.TooManyRequests(c, wait, "登录失败次数过多，请稍后再试")
+		return
+	}
+
+	// 用户不存在时同样计入失败次数，避免通过锁定行为探测用户名
+	user, err := h.userCRUD.WithContext(c).GetByUsername(req.Username)
 	if err != nil {
+		h.guard.Fail(req.Username, time.Now())
 		c.JSON(http.StatusUnauthorized, models.Response{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -102,7 +132,8 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if err := h.userCRUD.VerifyPassword(user, req.Password); err != nil {
+	if err := h.userCRUD.WithContext(c).VerifyPassword(user, req.Password); err != nil {
+		h.guard.Fail(req.Username, time.Now())
 		c.JSON(http.StatusUnauthorized, models.Response{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -110,7 +141,18 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.jwtManager.GenerateToken(user.ID, user.Username)
+	h.guard.Succeed(req.Username)
+
+	// 密码正确后再提示账户状态，避免泄露账户是否存在
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, models.Response{
+			Code:    403,
+			Message: "账户已被停用",
+		})
+		return
+	}
+
+	token, err := h.jwtManager.GenerateToken(user.ID, user.Username, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Response{
 			Code:    500,
@@ -119,77 +161,284 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	refreshToken, _, err := h.tokenCRUD.WithContext(c).IssueRefreshToken(user.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
 		Message: "登录成功",
 		Data: gin.H{
-			"token":    token,
-			"user_id":  user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"token":         token,
+			"refresh_token": refreshToken,
+			"token_type":    "Bearer",
+			"expires_in":    int(h.jwtManager.AccessExpire().Seconds()),
+			"user_id":       user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"role":          user.Role,
 		},
 	})
 }
 
+// Refresh 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌立即失效；重复使用已轮换的刷新令牌会吊销整个令牌族
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "刷新令牌"
+// @Success 200 {object} models.Response "刷新成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "刷新令牌无效、过期或已被重复使用"
+// @Failure 403 {object} models.Response "账户已被停用"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /refresh [post]
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	refreshToken, next, err := h.tokenCRUD.WithContext(c).Rotate(req.RefreshToken)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "无效的刷新令牌", "刷新令牌已过期", "刷新令牌已被重复使用":
+			statusCode = http.StatusUnauthorized
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userCRUD.WithContext(c).GetByID(next.UserID)
+	if err != nil {
+		h.tokenCRUD.WithContext(c).RevokeFamily(next.FamilyID)
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !user.IsActive {
+		h.tokenCRUD.WithContext(c).RevokeFamily(next.FamilyID)
+		c.JSON(http.StatusForbidden, models.Response{
+			Code:    403,
+			Message: "账户已被停用",
+		})
+		return
+	}
+
+	token, err := h.jwtManager.GenerateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: "令牌生成失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "令牌刷新成功",
+		Data: gin.H{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"token_type":    "Bearer",
+			"expires_in":    int(h.jwtManager.AccessExpire().Seconds()),
+		},
+	})
+}
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 吊销当前访问令牌；提供refresh_token时同时吊销其所在的令牌族
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.LogoutRequest false "刷新令牌"
+// @Success 200 {object} models.Response "退出成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, err := auth.GetClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 请求体可选，忽略解析错误
+	var req models.LogoutRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.tokenCRUD.WithContext(c).RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.RefreshToken != "" {
+		// 令牌不属于当前用户或已不存在时无需处理
+		h.tokenCRUD.WithContext(c).RevokeRefreshToken(req.RefreshToken, claims.UserID)
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "退出登录成功",
+	})
+}
+
 // PostHandler 文章处理器
 type PostHandler struct {
 	postCRUD *models.PostCRUD
+	views    *ViewCounter
+	bus      *events.Bus
+	audit    *Auditor
 }
 
 // NewPostHandler 创建文章处理器
-func NewPostHandler(postCRUD *models.PostCRUD) *PostHandler {
-	return &PostHandler{postCRUD: postCRUD}
+func NewPostHandler(postCRUD *models.PostCRUD, views *ViewCounter, bus *events.Bus, audit *Auditor) *PostHandler {
+	return &PostHandler{postCRUD: postCRUD, views: views, bus: bus, audit: audit}
+}
+
+// publishChange 根据文章变更前后的状态发布文章发布、修改或撤回事件，未发布的文章不产生事件
+func (h *PostHandler) publishChange(post *models.Post, userID uint) {
+	wasPublished := post.PreviousStatus == models.PostStatusPublished
+	eventType := ""
+	switch {
+	case post.Status == models.PostStatusPublished && !wasPublished:
+		eventType = events.PostPublished
+		metrics.PostsPublished.Inc()
+	case post.Status == models.PostStatusPublished:
+		eventType = events.PostUpdated
+	case wasPublished:
+		eventType = events.PostUnpublished
+	default:
+		return
+	}
+	h.bus.Publish(events.Event{Type: eventType, ActorID: userID, TargetID: post.ID, Data: post})
 }
 
-// GetAllPosts 获取所有文章
-// @Summary 获取所有文章
-// @Description 获取文章列表，按创建时间倒序排列
+// GetAllPosts 获取文章列表
+// @Summary 获取文章列表
+// @Description 分页获取文章列表，支持页码或游标分页、按作者/状态/标签/分类/时间范围过滤，默认按创建时间倒序排列；未发布的文章只对作者本人可见
 // @Tags 文章管理
 // @Accept json
 // @Produce json
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Param author_id query int false "作者ID"
+// @Param author query string false "作者用户名"
+// @Param status query string false "文章状态"
+// @Param tag query string false "标签名"
+// @Param category query int false "分类ID(包含子分类)"
+// @Param from query string false "开始时间(RFC3339或YYYY-MM-DD)"
+// @Param to query string false "结束时间(RFC3339或YYYY-MM-DD)"
+// @Param sort query string false "排序字段: created_at, updated_at"
+// @Param order query string false "排序方向: asc, desc"
 // @Success 200 {object} models.Response "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Router /posts [get]
 func (h *PostHandler) GetAllPosts(c *gin.Context) {
-	posts, err := h.postCRUD.GetAll()
+	var query models.PostListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Code:    500,
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	posts, pagination, err := h.postCRUD.WithContext(c).List(&query, userID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() != "获取文章列表失败" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
 			Message: err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.Response{
-		Code:    200,
-		Message: "获取文章列表成功",
-		Data:    posts,
+		Code:       200,
+		Message:    "获取文章列表成功",
+		Data:       posts,
+		Pagination: pagination,
 	})
 }
 
-// GetPostByID 根据ID获取文章
+// GetPostByID 根据ID或别名获取文章
 // @Summary 获取单个文章
-// @Description 根据文章ID获取文章详情，包含评论信息
+// @Description 根据文章ID或URL别名获取文章详情，包含评论信息、浏览量、点赞和表情回应统计；草稿和定时发布的文章只对作者本人可见；使用标题修改前的旧别名访问时返回301重定向到当前别名；同一用户在一段时间内重复浏览只计一次浏览量
 // @Tags 文章管理
 // @Accept json
 // @Produce json
-// @Param id path int true "文章ID"
+// @Param id path string true "文章ID或URL别名"
 // @Success 200 {object} models.Response "获取成功"
-// @Failure 400 {object} models.Response "无效的文章ID"
+// @Success 301 {object} models.Response "别名已变更，重定向到当前别名"
 // @Failure 404 {object} models.Response "文章不存在"
 // @Router /posts/{id} [get]
 func (h *PostHandler) GetPostByID(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	userID, err := auth.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Response{
-			Code:    400,
-			Message: "无效的文章ID",
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
 		})
 		return
 	}
 
-	post, err := h.postCRUD.GetByID(uint(id))
+	// 路径参数不是数字时按别名查找
+	var post *models.Post
+	idStr := c.Param("id")
+	if id, parseErr := strconv.ParseUint(idStr, 10, 32); parseErr == nil {
+		post, err = h.postCRUD.WithContext(c).GetByID(uint(id), userID)
+	} else {
+		var current string
+		post, current, err = h.postCRUD.WithContext(c).GetBySlug(idStr, userID)
+		if err == nil && current != "" {
+			c.Header("Location", "/api/posts/"+current)
+			c.JSON(http.StatusMovedPermanently, models.Response{
+				Code:    301,
+				Message: "文章别名已变更",
+				Data:    gin.H{"slug": current},
+			})
+			return
+		}
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.Response{
 			Code:    404,
@@ -198,6 +447,8 @@ func (h *PostHandler) GetPostByID(c *gin.Context) {
 		return
 	}
 
+	h.views.Record(c, post, userID)
+
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
 		Message: "获取文章成功",
@@ -207,7 +458,7 @@ func (h *PostHandler) GetPostByID(c *gin.Context) {
 
 // CreatePost 创建文章
 // @Summary 创建文章
-// @Description 创建新文章，需要JWT认证
+// @Description 创建新文章，需要JWT认证和作者及以上角色；status可选draft/published/scheduled，默认立即发布，定时发布需提供publish_at
 // @Tags 文章管理
 // @Accept json
 // @Produce json
@@ -216,6 +467,7 @@ func (h *PostHandler) GetPostByID(c *gin.Context) {
 // @Success 201 {object} models.Response "创建成功"
 // @Failure 400 {object} models.Response "请求参数错误"
 // @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Router /posts [post]
 func (h *PostHandler) CreatePost(c *gin.Context) {
@@ -237,14 +489,22 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postCRUD.Create(&req, userID)
+	post, err := h.postCRUD.WithContext(c).Create(&req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Code:    500,
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "定时发布时间必须晚于当前时间", "分类不存在", "标签名不能超过50个字符":
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
 			Message: err.Error(),
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityPost, post.ID, nil)
+	metrics.PostsCreated.Inc()
+	h.publishChange(post, userID)
 
 	c.JSON(http.StatusCreated, models.Response{
 		Code:    201,
@@ -255,7 +515,7 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 // UpdatePost 更新文章
 // @Summary 更新文章
-// @Description 更新文章内容，只有作者可以修改
+// @Description 更新文章内容，作者和管理员可以修改
 // @Tags 文章管理
 // @Accept json
 // @Produce json
@@ -297,13 +557,16 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postCRUD.Update(uint(id), &req, userID)
+	before := h.audit.Snapshot(models.AuditEntityPost, uint(id))
+	post, err := h.postCRUD.WithContext(c).Update(uint(id), &req, userID, auth.Can(c, auth.PermPostModerate))
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "文章不存在" {
 			statusCode = http.StatusNotFound
 		} else if err.Error() == "无权限修改此文章" {
 			statusCode = http.StatusForbidden
+		} else if err.Error() == "定时发布时间必须晚于当前时间" || err.Error() == "分类不存在" || err.Error() == "标签名不能超过50个字符" {
+			statusCode = http.StatusBadRequest
 		}
 		c.JSON(statusCode, models.Response{
 			Code:    statusCode,
@@ -311,6 +574,8 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityPost, post.ID, before)
+	h.publishChange(post, userID)
 
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
@@ -321,7 +586,7 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 
 // DeletePost 删除文章
 // @Summary 删除文章
-// @Description 删除文章，只有作者可以删除
+// @Description 将文章移入回收站，作者和管理员可以删除；可通过POST /posts/{id}/restore恢复
 // @Tags 文章管理
 // @Accept json
 // @Produce json
@@ -353,7 +618,8 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	err = h.postCRUD.Delete(uint(id), userID)
+	before := h.audit.Snapshot(models.AuditEntityPost, uint(id))
+	post, err := h.postCRUD.WithContext(c).Delete(uint(id), userID, auth.Can(c, auth.PermPostModerate))
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "文章不存在" {
@@ -367,6 +633,10 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityPost, post.ID, before)
+	if post.Status == models.PostStatusPublished {
+		h.bus.Publish(events.Event{Type: events.PostDeleted, ActorID: userID, TargetID: post.ID, Data: post})
+	}
 
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
@@ -374,6 +644,140 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 	})
 }
 
+// PublishPost 发布文章
+// @Summary 发布文章
+// @Description 发布草稿文章；提供未来的publish_at时改为定时发布，由后台任务在到期后发布
+// @Tags 文章管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param request body models.PublishRequest false "定时发布时间"
+// @Success 200 {object} models.Response{data=models.Post} "发布成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限修改此文章"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Router /posts/{id}/publish [post]
+func (h *PostHandler) PublishPost(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文章ID",
+		})
+		return
+	}
+
+	// 请求体可选，为空时立即发布
+	var req models.PublishRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityPost, uint(id))
+	post, err := h.postCRUD.WithContext(c).Publish(uint(id), userID, auth.Can(c, auth.PermPostModerate), req.PublishAt)
+	if err != nil {
+		h.respondStatusError(c, err)
+		return
+	}
+	h.audit.Record(c, models.AuditPublish, models.AuditEntityPost, post.ID, before)
+	h.publishChange(post, userID)
+
+	message := "文章发布成功"
+	if post.Status == models.PostStatusScheduled {
+		message = "文章已设置定时发布"
+	}
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: message,
+		Data:    post,
+	})
+}
+
+// UnpublishPost 撤回文章
+// @Summary 撤回文章
+// @Description 将已发布或定时发布的文章撤回为草稿
+// @Tags 文章管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Success 200 {object} models.Response{data=models.Post} "撤回成功"
+// @Failure 400 {object} models.Response "无效的文章ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限修改此文章"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Router /posts/{id}/unpublish [post]
+func (h *PostHandler) UnpublishPost(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文章ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityPost, uint(id))
+	post, err := h.postCRUD.WithContext(c).Unpublish(uint(id), userID, auth.Can(c, auth.PermPostModerate))
+	if err != nil {
+		h.respondStatusError(c, err)
+		return
+	}
+	h.audit.Record(c, models.AuditUnpublish, models.AuditEntityPost, post.ID, before)
+	h.publishChange(post, userID)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "文章已撤回为草稿",
+		Data:    post,
+	})
+}
+
+// respondStatusError 返回文章状态变更的错误响应
+func (h *PostHandler) respondStatusError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch err.Error() {
+	case "文章不存在":
+		statusCode = http.StatusNotFound
+	case "无权限修改此文章":
+		statusCode = http.StatusForbidden
+	case "定时发布时间必须晚于当前时间", "无效的文章状态":
+		statusCode = http.StatusBadRequest
+	}
+	c.JSON(statusCode, models.Response{
+		Code:    statusCode,
+		Message: err.Error(),
+	})
+}
+
 // GetLastPost 获取最后一篇文章
 // @Summary 获取最后一篇文章
 // @Description 获取数据库中最后一篇文章的详细信息
@@ -385,7 +789,16 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Router /api/posts/last [get]
 func (h *PostHandler) GetLastPost(c *gin.Context) {
-	post, err := h.postCRUD.GetLastPost()
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	post, err := h.postCRUD.WithContext(c).GetLastPost(userID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "没有找到文章" {
@@ -409,23 +822,68 @@ func (h *PostHandler) GetLastPost(c *gin.Context) {
 type CommentHandler struct {
 	commentCRUD *models.CommentCRUD
 	postCRUD    *models.PostCRUD
+	scorer      *spam.Scorer
+	bus         *events.Bus
+	audit       *Auditor
 }
 
 // NewCommentHandler 创建评论处理器
-func NewCommentHandler(commentCRUD *models.CommentCRUD, postCRUD *models.PostCRUD) *CommentHandler {
+func NewCommentHandler(commentCRUD *models.CommentCRUD, postCRUD *models.PostCRUD, scorer *spam.Scorer, bus *events.Bus, audit *Auditor) *CommentHandler {
 	return &CommentHandler{
 		commentCRUD: commentCRUD,
 		postCRUD:    postCRUD,
+		scorer:      scorer,
+		bus:         bus,
+		audit:       audit,
 	}
 }
 
+// commentMeta 记录评论来源并进行垃圾评论评分，版主和管理员的评论不参与评分
+func (h *CommentHandler) commentMeta(c *gin.Context, content string, userID, postID uint) *models.CommentMeta {
+	meta := &models.CommentMeta{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Status:    models.CommentStatusApproved,
+	}
+	if len(meta.UserAgent) > 255 {
+		meta.UserAgent = meta.UserAgent[:255]
+	}
+	if auth.Can(c, auth.PermCommentModerate) {
+		return meta
+	}
+
+	verdict := h.scorer.Evaluate(&spam.Input{
+		Content:   content,
+		UserID:    userID,
+		PostID:    postID,
+		IP:        meta.IPAddress,
+		UserAgent: meta.UserAgent,
+	})
+	meta.SpamScore = verdict.Score
+	meta.SpamReason = verdict.Reason()
+	if len(meta.SpamReason) > 255 {
+		meta.SpamReason = meta.SpamReason[:255]
+	}
+	switch verdict.Action {
+	case spam.ActionHold:
+		meta.Status = models.CommentStatusPending
+	case spam.ActionSpam:
+		meta.Status = models.CommentStatusSpam
+	}
+	return meta
+}
+
 // GetPostComments 获取文章评论
 // @Summary 获取文章评论
-// @Description 获取指定文章的所有评论
+// @Description 分页获取指定文章的评论，按创建时间正序排列；format=flat(默认)返回带depth和parent_id的平铺列表，format=tree按顶级评论分页并在replies中返回回复树；未通过审核的评论只对评论者本人可见
 // @Tags 评论管理
 // @Accept json
 // @Produce json
 // @Param id path int true "文章ID"
+// @Param format query string false "返回格式: flat, tree"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
 // @Success 200 {object} models.Response "获取成功"
 // @Failure 400 {object} models.Response "无效的文章ID"
 // @Failure 500 {object} models.Response "服务器内部错误"
@@ -441,9 +899,26 @@ func (h *CommentHandler) GetPostComments(c *gin.Context) {
 		return
 	}
 
-	// 首先检查文章是否存在
-	_, err = h.postCRUD.GetByID(uint(id))
+	var query models.CommentListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
 	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 首先检查文章是否存在且可见
+	if !h.postCRUD.WithContext(c).IsVisible(uint(id), userID) {
 		c.JSON(http.StatusNotFound, models.Response{
 			Code:    http.StatusNotFound,
 			Message: "文章不存在",
@@ -451,30 +926,42 @@ func (h *CommentHandler) GetPostComments(c *gin.Context) {
 		return
 	}
 
-	// 获取该文章的评论
-	comments, err := h.commentCRUD.GetByPostID(uint(id))
+	// 获取该文章的评论，tree格式按顶级评论分页并附带回复树
+	var comments []models.Comment
+	var pagination *models.Pagination
+	if query.Format == "tree" {
+		comments, pagination, err = h.commentCRUD.WithContext(c).GetThreadsByPostID(uint(id), &query.PageQuery, userID)
+	} else {
+		comments, pagination, err = h.commentCRUD.WithContext(c).GetByPostID(uint(id), &query.PageQuery, userID)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Code:    http.StatusInternalServerError,
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
 			Message: err.Error(),
 		})
 		return
 	}
 
 	// 根据评论数量返回不同的响应
-	if len(comments) == 0 {
+	if pagination.Total == 0 {
 		// 文章存在但没有评论
 		c.JSON(http.StatusOK, models.Response{
-			Code:    http.StatusOK,
-			Message: "文章存在但暂无评论",
-			Data:    []interface{}{}, // 返回空数组
+			Code:       http.StatusOK,
+			Message:    "文章存在但暂无评论",
+			Data:       []interface{}{}, // 返回空数组
+			Pagination: pagination,
 		})
 	} else {
 		// 文章存在且有评论
 		c.JSON(http.StatusOK, models.Response{
-			Code:    http.StatusOK,
-			Message: "获取评论列表成功",
-			Data:    comments,
+			Code:       http.StatusOK,
+			Message:    "获取评论列表成功",
+			Data:       comments,
+			Pagination: pagination,
 		})
 	}
 }
@@ -502,10 +989,25 @@ func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("DEBUG: 查询评论ID %d\n", id)
-	comment, err := h.commentCRUD.GetByID(uint(id))
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	comment, err := h.commentCRUD.WithContext(c).GetByID(uint(id), userID)
+	if err == nil && !comment.Post.VisibleTo(userID) {
+		err = errors.New("评论不存在")
+	}
+	// 未通过审核的评论只对评论者本人和版主可见
+	if err == nil && comment.Status != models.CommentStatusApproved &&
+		comment.UserID != userID && !auth.Can(c, auth.PermCommentModerate) {
+		err = errors.New("评论不存在")
+	}
 	if err != nil {
-		fmt.Printf("DEBUG: 评论不存在: %v\n", err)
 		c.JSON(http.StatusNotFound, models.Response{
 			Code:    http.StatusNotFound,
 			Message: "评论不存在",
@@ -513,7 +1015,6 @@ func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("DEBUG: 找到评论，内容: %s\n", comment.Content)
 	c.JSON(http.StatusOK, models.Response{
 		Code:    http.StatusOK,
 		Message: "获取评论成功",
@@ -523,7 +1024,7 @@ func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 
 // CreateComment 创建评论
 // @Summary 创建评论
-// @Description 为指定文章创建评论，需要JWT认证
+// @Description 为指定文章创建评论，需要JWT认证；提供parent_id时回复该评论，回复层级最多为5层；被判定为可疑的评论进入审核队列，审核通过后才对其他用户可见
 // @Tags 评论管理
 // @Accept json
 // @Produce json
@@ -581,9 +1082,8 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
-	// 首先检查文章是否存在
-	_, err = h.postCRUD.GetByID(uint(id))
-	if err != nil {
+	// 首先检查文章是否存在且可见
+	if !h.postCRUD.WithContext(c).IsVisible(uint(id), userID) {
 		c.JSON(http.StatusNotFound, models.Response{
 			Code:    404,
 			Message: "文章不存在",
@@ -591,11 +1091,15 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
-	comment, err := h.commentCRUD.Create(&req, userID, uint(id))
+	meta := h.commentMeta(c, req.Content, userID, uint(id))
+	comment, err := h.commentCRUD.WithContext(c).Create(&req, userID, uint(id), meta)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "文章不存在" {
+		switch err.Error() {
+		case "文章不存在", "父评论不存在":
 			statusCode = http.StatusNotFound
+		case "回复层级超过限制":
+			statusCode = http.StatusBadRequest
 		}
 		c.JSON(statusCode, models.Response{
 			Code:    statusCode,
@@ -603,17 +1107,25 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityComment, comment.ID, nil)
+
+	metrics.CommentsCreated.WithLabelValues(comment.Status).Inc()
+	h.bus.Publish(events.Event{Type: events.CommentCreated, ActorID: userID, TargetID: comment.ID, Data: comment})
 
+	message := "评论创建成功"
+	if comment.Status != models.CommentStatusApproved {
+		message = "评论已提交，等待审核"
+	}
 	c.JSON(http.StatusCreated, models.Response{
 		Code:    201,
-		Message: "评论创建成功",
+		Message: message,
 		Data:    comment,
 	})
 }
 
 // UpdateComment 更新评论
 // @Summary 更新评论
-// @Description 更新指定ID的评论内容
+// @Description 更新指定ID的评论内容，评论作者、版主和管理员可以修改；修改后的内容被判定为可疑时评论重新进入审核队列
 // @Tags 评论管理
 // @Accept json
 // @Produce json
@@ -668,7 +1180,23 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	comment, err := h.commentCRUD.Update(uint(id), &req, userID.(uint))
+	existing, err := h.commentCRUD.WithContext(c).GetByID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 修改后的内容按所在文章重新评分，只在判定为可疑时变更审核状态
+	var meta *models.CommentMeta
+	if m := h.commentMeta(c, req.Content, userID.(uint), existing.PostID); m.Status != models.CommentStatusApproved {
+		meta = m
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityComment, uint(id))
+	comment, err := h.commentCRUD.WithContext(c).Update(uint(id), &req, userID.(uint), auth.Can(c, auth.PermCommentModerate), meta)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "评论不存在" {
@@ -682,6 +1210,9 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityComment, comment.ID, before)
+
+	h.bus.Publish(events.Event{Type: events.CommentUpdated, ActorID: userID.(uint), TargetID: comment.ID, Data: comment})
 
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
@@ -692,7 +1223,7 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 
 // DeleteComment 删除评论
 // @Summary 删除评论
-// @Description 删除指定ID的评论
+// @Description 将指定ID的评论及其下的所有回复移入回收站，评论作者、版主和管理员可以删除；可通过POST /comments/{id}/restore恢复
 // @Tags 评论管理
 // @Accept json
 // @Produce json
@@ -727,7 +1258,8 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	err = h.commentCRUD.Delete(uint(id), userID.(uint))
+	before := h.audit.Snapshot(models.AuditEntityComment, uint(id))
+	comment, err := h.commentCRUD.WithContext(c).Delete(uint(id), userID.(uint), auth.Can(c, auth.PermCommentModerate))
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "评论不存在" {
@@ -741,6 +1273,9 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		})
 		return
 	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityComment, comment.ID, before)
+
+	h.bus.Publish(events.Event{Type: events.CommentDeleted, ActorID: userID.(uint), TargetID: comment.ID, Data: comment})
 
 	c.JSON(http.StatusOK, models.Response{
 		Code:    200,
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"blog-system/auth"
+	"blog-system/config"
+	"blog-system/media"
+	"blog-system/models"
+	"blog-system/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAllowedTypes 未配置MEDIA_ALLOWED_TYPES时允许上传的文件类型
+var defaultAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+
+// MediaHandler 媒体文件处理器
+type MediaHandler struct {
+	mediaCRUD     *models.MediaCRUD
+	userCRUD      *models.UserCRUD
+	store         storage.Storage
+	maxSize       int64
+	quota         int64
+	thumbnailSize int
+	allowedTypes  map[string]bool
+	audit         *Auditor
+}
+
+// NewMediaHandler 创建媒体文件处理器
+func NewMediaHandler(mediaCRUD *models.MediaCRUD, userCRUD *models.UserCRUD, store storage.Storage, cfg *config.Config, audit *Auditor) *MediaHandler {
+	types := cfg.Media.AllowedTypes
+	if len(types) == 0 {
+		types = defaultAllowedTypes
+	}
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	return &MediaHandler{
+		mediaCRUD:     mediaCRUD,
+		userCRUD:      userCRUD,
+		store:         store,
+		maxSize:       cfg.GetMaxUploadSize(),
+		quota:         cfg.GetUserQuota(),
+		thumbnailSize: cfg.Media.ThumbnailSize,
+		allowedTypes:  allowed,
+		audit:         audit,
+	}
+}
+
+// uploadError 上传失败的错误及对应的HTTP状态码
+type uploadError struct {
+	status  int
+	message string
+}
+
+func (e *uploadError) Error() string {
+	return e.message
+}
+
+// UploadMedia 上传媒体文件
+// @Summary 上传媒体文件
+// @Description 上传图片或文档，文件类型根据内容识别；图片会生成缩略图。文件大小计入用户的存储配额，返回的url可直接在文章Markdown中引用
+// @Tags 媒体文件
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "上传的文件"
+// @Success 201 {object} models.Response "上传成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 413 {object} models.Response "文件大小超过限制或存储空间不足"
+// @Failure 415 {object} models.Response "不支持的文件类型"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /media [post]
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	item, err := h.upload(c, userID, false)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityMedia, item.ID, nil)
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "文件上传成功",
+		Data:    item,
+	})
+}
+
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 上传图片作为当前用户的头像，头像使用生成的缩略图；上传的图片同时保存在用户的媒体文件中
+// @Tags 媒体文件
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "头像图片"
+// @Success 200 {object} models.Response "上传成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 413 {object} models.Response "文件大小超过限制或存储空间不足"
+// @Failure 415 {object} models.Response "不支持的文件类型"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /media/avatar [post]
+func (h *MediaHandler) UploadAvatar(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	item, err := h.upload(c, userID, true)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityMedia, item.ID, nil)
+
+	before := h.audit.Snapshot(models.AuditEntityUser, userID)
+	user, err := h.userCRUD.WithContext(c).UpdateAvatar(userID, item.ThumbnailURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityUser, user.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "头像上传成功",
+		Data:    user,
+	})
+}
+
+// ListMedia 获取媒体文件列表
+// @Summary 获取媒体文件列表
+// @Description 分页获取当前用户上传的媒体文件，按上传时间倒序排列
+// @Tags 媒体文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /media [get]
+func (h *MediaHandler) ListMedia(c *gin.Context) {
+	var query models.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	items, pagination, err := h.mediaCRUD.WithContext(c).List(userID, &query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for i := range items {
+		h.fillURLs(&items[i])
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取文件列表成功",
+		Data:       items,
+		Pagination: pagination,
+	})
+}
+
+// GetUsage 获取存储空间使用情况
+// @Summary 获取存储空间使用情况
+// @Description 获取当前用户已使用的存储空间和配额(字节)，quota为0表示不限制
+// @Tags 媒体文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=models.StorageUsage} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /media/usage [get]
+func (h *MediaHandler) GetUsage(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	used, err := h.mediaCRUD.WithContext(c).Usage(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取存储空间使用情况成功",
+		Data:    models.StorageUsage{Used: used, Quota: h.quota},
+	})
+}
+
+// DeleteMedia 删除媒体文件
+// @Summary 删除媒体文件
+// @Description 删除媒体文件及其缩略图并释放存储空间，只有上传者或管理员可以删除；引用该文件的文章将无法显示它
+// @Tags 媒体文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "文件不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /media/{id} [delete]
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文件ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityMedia, uint(id))
+	item, err := h.mediaCRUD.WithContext(c).Delete(uint(id), userID, auth.Can(c, auth.PermPostModerate))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "文件不存在":
+			statusCode = http.StatusNotFound
+		case "无权限删除此文件":
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityMedia, item.ID, before)
+
+	// 记录已删除，存储中的文件删除失败只记录日志
+	h.removeFiles(c.Request.Context(), item.StorageKey, item.ThumbnailKey)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "文件删除成功",
+	})
+}
+
+// upload 读取并校验上传的文件，生成缩略图，占用存储配额后写入存储后端。
+// imagesOnly为true时只接受图片。
+func (h *MediaHandler) upload(c *gin.Context, userID uint, imagesOnly bool) (*models.Media, error) {
+	// 限制请求体大小，为multipart的边界和其他字段预留空间
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, h.tooLarge()
+		}
+		return nil, &uploadError{http.StatusBadRequest, "请选择要上传的文件"}
+	}
+	if header.Size > h.maxSize {
+		return nil, h.tooLarge()
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, &uploadError{http.StatusBadRequest, "读取上传文件失败"}
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.maxSize+1))
+	if err != nil {
+		return nil, &uploadError{http.StatusBadRequest, "读取上传文件失败"}
+	}
+	if int64(len(data)) > h.maxSize {
+		return nil, h.tooLarge()
+	}
+	if len(data) == 0 {
+		return nil, &uploadError{http.StatusBadRequest, "上传的文件为空"}
+	}
+
+	// 根据文件内容识别类型，不信任客户端声明的Content-Type和扩展名
+	contentType := media.Detect(data)
+	if !h.allowedTypes[contentType] || media.Extension(contentType) == "" || (imagesOnly && !media.IsImage(contentType)) {
+		return nil, &uploadError{http.StatusUnsupportedMediaType, "不支持的文件类型: " + contentType}
+	}
+
+	base, err := newMediaKey(userID)
+	if err != nil {
+		return nil, &uploadError{http.StatusInternalServerError, "文件上传失败"}
+	}
+	item := &models.Media{
+		UserID:      userID,
+		Filename:    cleanFilename(header.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  base + media.Extension(contentType),
+	}
+
+	var img *media.Image
+	if media.IsImage(contentType) {
+		if img, err = media.Process(data, h.thumbnailSize); err != nil {
+			return nil, &uploadError{http.StatusBadRequest, err.Error()}
+		}
+		item.Width, item.Height = img.Width, img.Height
+		item.ThumbnailKey = base + "-thumb" + media.Extension(img.ThumbnailType)
+		item.Size += int64(len(img.Thumbnail))
+	}
+
+	if err := h.mediaCRUD.WithContext(c).Create(item, h.quota); err != nil {
+		if err.Error() == "存储空间不足" {
+			return nil, &uploadError{http.StatusRequestEntityTooLarge, err.Error()}
+		}
+		return nil, &uploadError{http.StatusInternalServerError, err.Error()}
+	}
+
+	ctx := c.Request.Context()
+	err = h.store.Put(ctx, item.StorageKey, bytes.NewReader(data), int64(len(data)), contentType)
+	if err == nil && img != nil {
+		err = h.store.Put(ctx, item.ThumbnailKey, bytes.NewReader(img.Thumbnail), int64(len(img.Thumbnail)), img.ThumbnailType)
+	}
+	if err != nil {
+		// 写入存储失败时撤销记录并释放配额
+		slog.ErrorContext(ctx, "媒体文件写入存储失败", "key", item.StorageKey, "error", err)
+		h.mediaCRUD.WithContext(context.WithoutCancel(ctx)).Delete(item.ID, userID, true)
+		h.removeFiles(ctx, item.StorageKey, item.ThumbnailKey)
+		return nil, &uploadError{http.StatusInternalServerError, "文件上传失败"}
+	}
+
+	h.fillURLs(item)
+	return item, nil
+}
+
+func (h *MediaHandler) tooLarge() error {
+	return &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("文件大小超过限制(%dMB)", h.maxSize>>20)}
+}
+
+func (h *MediaHandler) respondUploadError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	var uploadErr *uploadError
+	if errors.As(err, &uploadErr) {
+		statusCode = uploadErr.status
+	}
+	c.JSON(statusCode, models.Response{
+		Code:    statusCode,
+		Message: err.Error(),
+	})
+}
+
+// fillURLs 根据存储后端生成文件和缩略图的访问地址
+func (h *MediaHandler) fillURLs(item *models.Media) {
+	item.URL = h.store.URL(item.StorageKey)
+	if item.ThumbnailKey != "" {
+		item.ThumbnailURL = h.store.URL(item.ThumbnailKey)
+	}
+}
+
+func (h *MediaHandler) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := h.store.Delete(ctx, key); err != nil {
+			slog.ErrorContext(ctx, "删除媒体文件失败", "key", key, "error", err)
+		}
+	}
+}
+
+// newMediaKey 生成不含扩展名的存储路径：media/{用户ID}/{年}/{月}/{随机名}
+func newMediaKey(userID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("media/%d/%s/%s", userID, time.Now().Format("2006/01"), hex.EncodeToString(b)), nil
+}
+
+// cleanFilename 去除客户端文件名中的路径，并截断到255字节以内
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
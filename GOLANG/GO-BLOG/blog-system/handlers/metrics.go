@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler Prometheus监控指标处理器
+type MetricsHandler struct {
+	handler http.Handler
+	token   string
+}
+
+// NewMetricsHandler 创建监控指标处理器，token不为空时要求请求携带Authorization: Bearer {token}
+func NewMetricsHandler(registry *prometheus.Registry, token string) *MetricsHandler {
+	return &MetricsHandler{
+		handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+			ErrorHandling: promhttp.ContinueOnError,
+			Registry:      registry,
+		}),
+		token: token,
+	}
+}
+
+// Serve 以Prometheus文本格式输出所有监控指标
+func (h *MetricsHandler) Serve(c *gin.Context) {
+	if h.token != "" {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.JSON(http.StatusUnauthorized, models.Response{
+				Code:    401,
+				Message: "无效的监控令牌",
+			})
+			return
+		}
+	}
+
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
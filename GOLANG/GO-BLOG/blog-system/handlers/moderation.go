@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationHandler 评论审核处理器
+type ModerationHandler struct {
+	commentCRUD *models.CommentCRUD
+	bus         *events.Bus
+	audit       *Auditor
+}
+
+// NewModerationHandler 创建评论审核处理器
+func NewModerationHandler(commentCRUD *models.CommentCRUD, bus *events.Bus, audit *Auditor) *ModerationHandler {
+	return &ModerationHandler{commentCRUD: commentCRUD, bus: bus, audit: audit}
+}
+
+// ListComments 获取审核队列
+// @Summary 获取评论审核队列
+// @Description 分页获取指定审核状态的评论，默认返回待审核评论，附带IP、User-Agent和垃圾评论评分；仅版主和管理员可用
+// @Tags 评论审核
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "审核状态: pending(默认), approved, rejected, spam"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.ModerationItem} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /moderation/comments [get]
+func (h *ModerationHandler) ListComments(c *gin.Context) {
+	var query models.ModerationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	items, pagination, err := h.commentCRUD.WithContext(c).ListForModeration(&query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取审核队列成功",
+		Data:       items,
+		Pagination: pagination,
+	})
+}
+
+// ModerateComments 批量审核评论
+// @Summary 批量审核评论
+// @Description 批量通过、拒绝评论或将评论标记为垃圾评论，单次最多100条；通过审核的评论会通知文章作者和被回复者、实时推送并投递Webhook。仅版主和管理员可用
+// @Tags 评论审核
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ModerateRequest true "审核信息，action可选approve, reject, spam"
+// @Success 200 {object} models.Response "审核成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "评论不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /moderation/comments [post]
+func (h *ModerationHandler) ModerateComments(c *gin.Context) {
+	var req models.ModerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	moderatorID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 每条评论单独记录审计日志
+	before := make(map[uint]map[string]any, len(req.IDs))
+	for _, id := range req.IDs {
+		before[id] = h.audit.Snapshot(models.AuditEntityComment, id)
+	}
+	comments, err := h.commentCRUD.WithContext(c).Moderate(req.IDs, req.Action, moderatorID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "评论不存在":
+			statusCode = http.StatusNotFound
+		case "无效的审核动作":
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for i := range comments {
+		h.audit.Record(c, models.AuditUpdate, models.AuditEntityComment, comments[i].ID, before[comments[i].ID])
+		h.publishModeration(&comments[i], moderatorID)
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "评论审核成功",
+		Data:    gin.H{"updated": len(comments)},
+	})
+}
+
+// publishModeration 根据审核前后的状态发布业务事件：通过审核的评论此时才对其他人可见，
+// 按新增评论通知作者、推送和投递Webhook；撤销通过的评论按修改发布，订阅方将其视为删除
+func (h *ModerationHandler) publishModeration(comment *models.Comment, moderatorID uint) {
+	wasApproved := comment.PreviousStatus == models.CommentStatusApproved
+	switch {
+	case comment.Status == models.CommentStatusApproved && !wasApproved:
+		h.bus.Publish(events.Event{Type: events.CommentCreated, ActorID: comment.UserID, TargetID: comment.ID, Data: comment})
+	case comment.Status != models.CommentStatusApproved && wasApproved:
+		h.bus.Publish(events.Event{Type: events.CommentUpdated, ActorID: moderatorID, TargetID: comment.ID, Data: comment})
+	}
+}
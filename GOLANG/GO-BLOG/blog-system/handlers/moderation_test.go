@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-system/events"
+	"blog-system/internal/testdb"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModerateCommentsNotifiesOnApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	userCRUD := models.NewUserCRUD(db)
+	commentCRUD := models.NewCommentCRUD(db)
+	notificationCRUD := models.NewNotificationCRUD(db)
+
+	author, _ := userCRUD.Create(&models.RegisterRequest{Username: "author", Password: "password123", Email: "author@example.com"})
+	reader, _ := userCRUD.Create(&models.RegisterRequest{Username: "reader", Password: "password123", Email: "reader@example.com"})
+	moderator, _ := userCRUD.Create(&models.RegisterRequest{Username: "moderator", Password: "password123", Email: "moderator@example.com"})
+	post, err := models.NewPostCRUD(db).Create(&models.PostRequest{Title: "文章", Content: "内容"}, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, err := commentCRUD.Create(&models.CommentRequest{Content: "待审核的评论"}, reader.ID, post.ID, &models.CommentMeta{Status: models.CommentStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus(8)
+	SubscribeNotifications(bus, notificationCRUD)
+	var published []events.Event
+	bus.Subscribe("测试", func(e events.Event) { published = append(published, e) })
+
+	auditCRUD := models.NewAuditCRUD(db)
+	handler := NewModerationHandler(commentCRUD, bus, NewAuditor(auditCRUD))
+	r := gin.New()
+	r.POST("/moderation/comments", func(c *gin.Context) { c.Set("user_id", moderator.ID) }, handler.ModerateComments)
+	moderate := func(action string) {
+		t.Helper()
+		body, _ := json.Marshal(models.ModerateRequest{IDs: []uint{held.ID}, Action: action})
+		req := httptest.NewRequest(http.MethodPost, "/moderation/comments", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("ModerateComments(%s) = %d, %s", action, w.Code, w.Body.String())
+		}
+	}
+
+	// 通过审核按新增评论发布，再次通过不重复发布；撤销通过按修改发布
+	moderate("approve")
+	moderate("approve")
+	moderate("spam")
+	bus.Close()
+
+	if len(published) != 2 {
+		t.Fatalf("发布了%d个事件, want 2: %+v", len(published), published)
+	}
+	if e := published[0]; e.Type != events.CommentCreated || e.TargetID != held.ID || e.ActorID != reader.ID {
+		t.Fatalf("通过审核的事件 = %+v", e)
+	}
+	if comment := published[0].Data.(*models.Comment); comment.Status != models.CommentStatusApproved || comment.User.Username != "reader" {
+		t.Fatalf("事件中的评论 = %+v", comment)
+	}
+	if e := published[1]; e.Type != events.CommentUpdated || e.ActorID != moderator.ID || e.Data.(*models.Comment).Status != models.CommentStatusSpam {
+		t.Fatalf("撤销通过的事件 = %+v", e)
+	}
+
+	notifications, _, err := notificationCRUD.List(author.ID, &models.NotificationQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("文章作者收到%d条通知, want 1", len(notifications))
+	}
+	if n := notifications[0]; n.Type != models.NotificationComment || n.CommentID != held.ID || n.ActorID != reader.ID {
+		t.Fatalf("通知 = %+v", n)
+	}
+
+	// 每次审核为每条评论记录一条审计日志
+	logs, _, err := auditCRUD.List(&models.AuditQuery{EntityType: models.AuditEntityComment, EntityID: held.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("记录了%d条审计日志, want 3", len(logs))
+	}
+	if l := logs[0]; l.Action != models.AuditUpdate || l.ActorID != moderator.ID || !bytes.Contains(l.After, []byte(models.CommentStatusSpam)) {
+		t.Fatalf("最近的审计日志 = %+v", l)
+	}
+}
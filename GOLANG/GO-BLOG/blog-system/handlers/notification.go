@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubscribeNotifications 订阅评论、点赞和关注事件并生成站内通知，每条生成的通知再作为事件发布以便实时推送。
+// 通知在事件总线的后台goroutine中生成，不影响原请求的响应时间
+func SubscribeNotifications(bus *events.Bus, notificationCRUD *models.NotificationCRUD) {
+	bus.Subscribe("站内通知", func(e events.Event) {
+		var created []models.Notification
+		var err error
+		switch e.Type {
+		case events.CommentCreated:
+			if comment, ok := e.Data.(*models.Comment); ok {
+				created, err = notificationCRUD.NotifyComment(comment)
+			}
+		case events.PostLiked:
+			created, err = notificationCRUD.NotifyLike(models.TargetPost, e.TargetID, e.ActorID)
+		case events.CommentLiked:
+			created, err = notificationCRUD.NotifyLike(models.TargetComment, e.TargetID, e.ActorID)
+		case events.UserFollowed:
+			created, err = notificationCRUD.NotifyFollow(e.ActorID, e.TargetID)
+		}
+		if err != nil {
+			slog.Error("🔔 生成通知失败", "event", e.Type, "error", err)
+			return
+		}
+
+		for i := range created {
+			bus.Publish(events.Event{Type: events.NotificationCreated, ActorID: e.ActorID, TargetID: created[i].UserID, Data: &created[i]})
+		}
+	})
+}
+
+// NotificationHandler 站内通知处理器
+type NotificationHandler struct {
+	notificationCRUD *models.NotificationCRUD
+}
+
+// NewNotificationHandler 创建站内通知处理器
+func NewNotificationHandler(notificationCRUD *models.NotificationCRUD) *NotificationHandler {
+	return &NotificationHandler{notificationCRUD: notificationCRUD}
+}
+
+// ListNotifications 获取我的通知
+// @Summary 获取我的通知
+// @Description 分页获取当前用户的站内通知，按时间倒序排列。type为comment表示文章收到评论，reply表示评论收到回复，like表示文章或评论被点赞，follow表示被关注
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "只返回未读通知"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.Notification} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	var query models.NotificationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	notifications, pagination, err := h.notificationCRUD.WithContext(c).List(userID, &query)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取通知成功",
+		Data:       notifications,
+		Pagination: pagination,
+	})
+}
+
+// GetUnreadCount 获取未读通知数量
+// @Summary 获取未读通知数量
+// @Description 获取当前用户的未读通知数量
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications/unread-count [get]
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	count, err := h.notificationCRUD.WithContext(c).UnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取未读通知数量成功",
+		Data:    gin.H{"unread": count},
+	})
+}
+
+// MarkRead 标记通知为已读
+// @Summary 标记通知为已读
+// @Description 将当前用户的一条通知标记为已读
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} models.Response{data=models.Notification} "标记成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "通知不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的通知ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	notification, err := h.notificationCRUD.WithContext(c).MarkRead(uint(id), userID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "通知不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "已标记为已读",
+		Data:    notification,
+	})
+}
+
+// MarkAllRead 标记全部通知为已读
+// @Summary 标记全部通知为已读
+// @Description 将当前用户的全部未读通知标记为已读，返回标记的数量
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "标记成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications/read-all [post]
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	count, err := h.notificationCRUD.WithContext(c).MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "已全部标记为已读",
+		Data:    gin.H{"count": count},
+	})
+}
+
+// GetPreferences 获取通知偏好
+// @Summary 获取通知偏好
+// @Description 获取当前用户接收的通知类型，键为通知类型(comment、reply、like、follow)，值为是否接收
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications/preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	prefs, err := h.notificationCRUD.WithContext(c).Preferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取通知偏好成功",
+		Data:    prefs,
+	})
+}
+
+// UpdatePreferences 修改通知偏好
+// @Summary 修改通知偏好
+// @Description 开启或关闭指定类型的通知，如{"like":false}，未提供的类型保持不变；关闭后不再生成该类型的通知
+// @Tags 站内通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]bool true "通知偏好"
+// @Success 200 {object} models.Response "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req map[string]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	prefs, err := h.notificationCRUD.WithContext(c).UpdatePreferences(userID, req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "无效的通知类型") {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "修改通知偏好成功",
+		Data:    prefs,
+	})
+}
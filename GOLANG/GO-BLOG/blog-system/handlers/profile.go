@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog-system/auth"
+	"blog-system/mail"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile 获取当前用户资料
+// @Summary 获取当前用户资料
+// @Description 获取当前登录用户的完整资料
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /users/me [get]
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	user, err := h.userCRUD.WithContext(c).GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取用户资料成功",
+		Data:    user,
+	})
+}
+
+// UpdateProfile 修改当前用户资料
+// @Summary 修改当前用户资料
+// @Description 修改当前用户的昵称、邮箱、头像和个人简介；email为空时保持不变，修改邮箱后需要重新验证；头像也可通过POST /media/avatar上传
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ProfileRequest true "个人资料"
+// @Success 200 {object} models.Response "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误或邮箱已存在"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/me [put]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req models.ProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	current, err := h.userCRUD.WithContext(c).GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityUser, userID)
+	user, err := h.userCRUD.WithContext(c).UpdateProfile(userID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "用户不存在":
+			statusCode = http.StatusNotFound
+		case "邮箱已存在":
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityUser, user.ID, before)
+
+	// 邮箱变更后向新邮箱发送验证邮件
+	if user.Email != current.Email {
+		h.mails.sendAsync(h.mails.SendVerification, user, mail.Lang(c.GetHeader("Accept-Language")))
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "个人资料修改成功",
+		Data:    user,
+	})
+}
+
+// ChangePassword 修改密码
+// @Summary 修改密码
+// @Description 校验当前密码后修改密码；修改成功后吊销当前访问令牌和所有刷新令牌，需要重新登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ChangePasswordRequest true "当前密码和新密码"
+// @Success 200 {object} models.Response "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误或当前密码错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/me/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	claims, err := auth.GetClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	if err := h.userCRUD.WithContext(c).ChangePassword(claims.UserID, req.CurrentPassword, req.NewPassword); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "当前密码错误" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.RecordAs(c, claims.UserID, models.AuditPassword, models.AuditEntityUser, claims.UserID, nil)
+
+	// 吊销所有会话，使用旧密码登录的设备需要重新登录
+	h.tokenCRUD.WithContext(c).RevokeAllForUser(claims.UserID)
+	h.tokenCRUD.WithContext(c).RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "密码修改成功，请重新登录",
+	})
+}
+
+// GetPublicProfile 获取用户公开资料
+// @Summary 获取用户公开资料
+// @Description 根据用户名获取用户的公开资料、粉丝数、关注数和已发布的文章，无需认证；停用的账户返回404
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param username path string true "用户名"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /users/{username} [get]
+func (h *UserHandler) GetPublicProfile(c *gin.Context) {
+	var query models.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	profile, err := h.userCRUD.WithContext(c).GetPublicProfile(c.Param("username"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "用户不存在" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 以匿名身份查询，只返回已发布的文章
+	posts, pagination, err := h.postCRUD.WithContext(c).List(&models.PostListQuery{PageQuery: query, AuthorID: profile.ID}, 0)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取用户资料成功",
+		Data: gin.H{
+			"profile": profile,
+			"posts":   models.NewPublicPosts(posts),
+		},
+		Pagination: pagination,
+	})
+}
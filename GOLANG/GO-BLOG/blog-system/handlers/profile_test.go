@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-system/internal/testdb"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findKey 递归查找JSON中是否存在指定的键
+func findKey(v any, key string) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if k == key || findKey(child, key) {
+				return true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if findKey(child, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestGetPublicProfileHidesPrivateFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	userCRUD := models.NewUserCRUD(db)
+	postCRUD := models.NewPostCRUD(db)
+
+	user, err := userCRUD.Create(&models.RegisterRequest{Username: "alice", Password: "password123", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postCRUD.Create(&models.PostRequest{Title: "公开文章", Content: "内容"}, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewUserHandler(userCRUD, postCRUD, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.GET("/users/:username", handler.GetPublicProfile)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/alice", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetPublicProfile = %d, %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			Posts []map[string]any `json:"posts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Posts) != 1 {
+		t.Fatalf("文章数 = %d, want 1", len(resp.Data.Posts))
+	}
+	if author, _ := resp.Data.Posts[0]["user"].(map[string]any); author["username"] != "alice" {
+		t.Fatalf("文章应包含作者的公开信息: %v", resp.Data.Posts[0]["user"])
+	}
+
+	var body any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	for _, key := range []string{"email", "role", "is_active", "storage_used", "email_verified_at"} {
+		if findKey(body, key) {
+			t.Fatalf("公开资料不应包含%s: %s", key, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/middleware"
+	"blog-system/models"
+	"blog-system/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewCounter 文章浏览量统计。同一用户(未登录时为同一IP)在时间窗口内重复浏览只计一次，作者浏览自己的文章不计数
+type ViewCounter struct {
+	reactionCRUD *models.ReactionCRUD
+	store        ratelimit.Store
+	limit        ratelimit.Limit
+}
+
+// NewViewCounter 创建浏览量统计，去重状态保存在限流存储中
+func NewViewCounter(reactionCRUD *models.ReactionCRUD, store ratelimit.Store, window time.Duration) *ViewCounter {
+	// 容量为1、周期为window的令牌桶：窗口内只有第一次浏览能取到令牌
+	return &ViewCounter{reactionCRUD: reactionCRUD, store: store, limit: ratelimit.Limit{Burst: 1, Period: window}}
+}
+
+// Record 记录一次浏览，计数成功时同步更新post中的浏览量
+func (v *ViewCounter) Record(c *gin.Context, post *models.Post, viewerID uint) {
+	if post.Status != models.PostStatusPublished || post.UserID == viewerID {
+		return
+	}
+
+	key := "view:" + strconv.FormatUint(uint64(post.ID), 10) + ":" + middleware.ClientKey(c)
+	if !v.store.Take(key, v.limit, time.Now()).Allowed {
+		return
+	}
+	if err := v.reactionCRUD.WithContext(c).RecordView(post.ID); err != nil {
+		slog.ErrorContext(c.Request.Context(), "记录文章浏览量失败", "post_id", post.ID, "error", err)
+		return
+	}
+	post.ViewCount++
+}
+
+// ReactionHandler 点赞和表情回应处理器
+type ReactionHandler struct {
+	reactionCRUD *models.ReactionCRUD
+	bus          *events.Bus
+}
+
+// NewReactionHandler 创建点赞和表情回应处理器
+func NewReactionHandler(reactionCRUD *models.ReactionCRUD, bus *events.Bus) *ReactionHandler {
+	return &ReactionHandler{reactionCRUD: reactionCRUD, bus: bus}
+}
+
+// ListReactionTypes 获取支持的表情回应
+// @Summary 获取支持的表情回应
+// @Description 返回表情回应名称与表情符号的对应关系，添加表情回应时可以使用名称或表情符号
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Router /reactions [get]
+func (h *ReactionHandler) ListReactionTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取表情回应成功",
+		Data:    models.ReactionTypes,
+	})
+}
+
+// LikePost 点赞文章
+// @Summary 点赞文章
+// @Description 点赞文章，重复点赞不产生影响；返回文章最新的点赞和表情回应统计
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Success 200 {object} models.Response{data=models.Interactions} "点赞成功"
+// @Failure 400 {object} models.Response "无效的文章ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /posts/{id}/like [put]
+func (h *ReactionHandler) LikePost(c *gin.Context) {
+	h.like(c, models.TargetPost, true)
+}
+
+// UnlikePost 取消点赞文章
+// @Summary 取消点赞文章
+// @Description 取消对文章的点赞，未点赞时不产生影响
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Success 200 {object} models.Response{data=models.Interactions} "取消成功"
+// @Failure 400 {object} models.Response "无效的文章ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /posts/{id}/like [delete]
+func (h *ReactionHandler) UnlikePost(c *gin.Context) {
+	h.like(c, models.TargetPost, false)
+}
+
+// ReactPost 对文章添加表情回应
+// @Summary 对文章添加表情回应
+// @Description 对文章添加表情回应，reaction可以是名称(如heart)或表情符号；每种表情每个用户只计一次
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param reaction path string true "表情回应名称或表情符号"
+// @Success 200 {object} models.Response{data=models.Interactions} "回应成功"
+// @Failure 400 {object} models.Response "无效的文章ID或不支持的表情回应"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /posts/{id}/reactions/{reaction} [put]
+func (h *ReactionHandler) ReactPost(c *gin.Context) {
+	h.react(c, models.TargetPost, true)
+}
+
+// UnreactPost 取消文章的表情回应
+// @Summary 取消文章的表情回应
+// @Description 取消对文章的表情回应，未回应时不产生影响
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param reaction path string true "表情回应名称或表情符号"
+// @Success 200 {object} models.Response{data=models.Interactions} "取消成功"
+// @Failure 400 {object} models.Response "无效的文章ID或不支持的表情回应"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /posts/{id}/reactions/{reaction} [delete]
+func (h *ReactionHandler) UnreactPost(c *gin.Context) {
+	h.react(c, models.TargetPost, false)
+}
+
+// LikeComment 点赞评论
+// @Summary 点赞评论
+// @Description 点赞评论，重复点赞不产生影响；返回评论最新的点赞和表情回应统计
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Success 200 {object} models.Response{data=models.Interactions} "点赞成功"
+// @Failure 400 {object} models.Response "无效的评论ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "评论不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /comments/{id}/like [put]
+func (h *ReactionHandler) LikeComment(c *gin.Context) {
+	h.like(c, models.TargetComment, true)
+}
+
+// UnlikeComment 取消点赞评论
+// @Summary 取消点赞评论
+// @Description 取消对评论的点赞，未点赞时不产生影响
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Success 200 {object} models.Response{data=models.Interactions} "取消成功"
+// @Failure 400 {object} models.Response "无效的评论ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "评论不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /comments/{id}/like [delete]
+func (h *ReactionHandler) UnlikeComment(c *gin.Context) {
+	h.like(c, models.TargetComment, false)
+}
+
+// ReactComment 对评论添加表情回应
+// @Summary 对评论添加表情回应
+// @Description 对评论添加表情回应，reaction可以是名称(如heart)或表情符号；每种表情每个用户只计一次
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param reaction path string true "表情回应名称或表情符号"
+// @Success 200 {object} models.Response{data=models.Interactions} "回应成功"
+// @Failure 400 {object} models.Response "无效的评论ID或不支持的表情回应"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "评论不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /comments/{id}/reactions/{reaction} [put]
+func (h *ReactionHandler) ReactComment(c *gin.Context) {
+	h.react(c, models.TargetComment, true)
+}
+
+// UnreactComment 取消评论的表情回应
+// @Summary 取消评论的表情回应
+// @Description 取消对评论的表情回应，未回应时不产生影响
+// @Tags 点赞和表情回应
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param reaction path string true "表情回应名称或表情符号"
+// @Success 200 {object} models.Response{data=models.Interactions} "取消成功"
+// @Failure 400 {object} models.Response "无效的评论ID或不支持的表情回应"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "评论不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /comments/{id}/reactions/{reaction} [delete]
+func (h *ReactionHandler) UnreactComment(c *gin.Context) {
+	h.react(c, models.TargetComment, false)
+}
+
+// like 点赞或取消点赞
+func (h *ReactionHandler) like(c *gin.Context, targetType string, add bool) {
+	targetID, userID, ok := h.parseTarget(c, targetType)
+	if !ok {
+		return
+	}
+
+	var stats *models.Interactions
+	var err error
+	message := "点赞成功"
+	if add {
+		stats, err = h.reactionCRUD.WithContext(c).Like(targetType, targetID, userID)
+		if err == nil {
+			eventType := events.PostLiked
+			if targetType == models.TargetComment {
+				eventType = events.CommentLiked
+			}
+			h.bus.Publish(events.Event{Type: eventType, ActorID: userID, TargetID: targetID})
+		}
+	} else {
+		stats, err = h.reactionCRUD.WithContext(c).Unlike(targetType, targetID, userID)
+		message = "取消点赞成功"
+	}
+	h.respond(c, stats, err, message)
+}
+
+// react 添加或取消表情回应
+func (h *ReactionHandler) react(c *gin.Context, targetType string, add bool) {
+	targetID, userID, ok := h.parseTarget(c, targetType)
+	if !ok {
+		return
+	}
+
+	reaction, ok := models.NormalizeReaction(c.Param("reaction"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "不支持的表情回应",
+		})
+		return
+	}
+
+	var stats *models.Interactions
+	var err error
+	message := "表情回应成功"
+	if add {
+		stats, err = h.reactionCRUD.WithContext(c).React(targetType, targetID, userID, reaction)
+	} else {
+		stats, err = h.reactionCRUD.WithContext(c).Unreact(targetType, targetID, userID, reaction)
+		message = "取消表情回应成功"
+	}
+	h.respond(c, stats, err, message)
+}
+
+// parseTarget 解析路径中的对象ID和当前用户ID，失败时已写入响应
+func (h *ReactionHandler) parseTarget(c *gin.Context, targetType string) (uint, uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "无效的文章ID"
+		if targetType == models.TargetComment {
+			message = "无效的评论ID"
+		}
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: message,
+		})
+		return 0, 0, false
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return 0, 0, false
+	}
+	return uint(id), userID, true
+}
+
+func (h *ReactionHandler) respond(c *gin.Context, stats *models.Interactions, err error, message string) {
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "文章不存在", "评论不存在":
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: message,
+		Data:    stats,
+	})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-system/auth"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevisionHandler 文章修订记录处理器
+type RevisionHandler struct {
+	revisionCRUD *models.RevisionCRUD
+	postCRUD     *models.PostCRUD
+	audit        *Auditor
+}
+
+// NewRevisionHandler 创建文章修订记录处理器
+func NewRevisionHandler(revisionCRUD *models.RevisionCRUD, postCRUD *models.PostCRUD, audit *Auditor) *RevisionHandler {
+	return &RevisionHandler{
+		revisionCRUD: revisionCRUD,
+		postCRUD:     postCRUD,
+		audit:        audit,
+	}
+}
+
+// authorize 解析文章ID并校验当前用户是否为文章作者或管理员，校验失败时已写入响应
+func (h *RevisionHandler) authorize(c *gin.Context) (postID uint, userID uint, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文章ID",
+		})
+		return 0, 0, false
+	}
+
+	userID, err = auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return 0, 0, false
+	}
+
+	authorID, err := h.postCRUD.WithContext(c).GetAuthorID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return 0, 0, false
+	}
+
+	if authorID != userID && !auth.Can(c, auth.PermPostModerate) {
+		c.JSON(http.StatusForbidden, models.Response{
+			Code:    403,
+			Message: "无权限查看此文章的修订记录",
+		})
+		return 0, 0, false
+	}
+
+	return uint(id), userID, true
+}
+
+// parseRevision 解析路径中的修订版本号，解析失败时已写入响应
+func parseRevision(c *gin.Context) (int, bool) {
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的修订版本号",
+		})
+		return 0, false
+	}
+	return rev, true
+}
+
+// ListRevisions 获取文章修订记录
+// @Summary 获取文章修订记录
+// @Description 获取文章的全部修订记录(不含正文)，按版本号倒序排列；仅文章作者和管理员可用
+// @Tags 文章修订
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Success 200 {object} models.Response{data=[]models.PostRevision} "获取成功"
+// @Failure 400 {object} models.Response "无效的文章ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限查看此文章的修订记录"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Router /posts/{id}/revisions [get]
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	postID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.revisionCRUD.WithContext(c).List(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取修订记录成功",
+		Data:    revisions,
+	})
+}
+
+// GetRevision 获取指定修订版本
+// @Summary 获取指定修订版本
+// @Description 获取文章指定修订版本的完整内容；仅文章作者和管理员可用
+// @Tags 文章修订
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param rev path int true "修订版本号"
+// @Success 200 {object} models.Response{data=models.PostRevision} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限查看此文章的修订记录"
+// @Failure 404 {object} models.Response "修订版本不存在"
+// @Router /posts/{id}/revisions/{rev} [get]
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	postID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c)
+	if !ok {
+		return
+	}
+
+	revision, err := h.revisionCRUD.WithContext(c).Get(postID, rev)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取修订版本成功",
+		Data:    revision,
+	})
+}
+
+// DiffRevisions 比较两个修订版本
+// @Summary 比较修订版本
+// @Description 按行比较两个修订版本的正文，并返回标题和摘要的变化；不提供to时与最新版本比较；仅文章作者和管理员可用
+// @Tags 文章修订
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param from query int true "旧版本号"
+// @Param to query int false "新版本号，默认为最新版本"
+// @Success 200 {object} models.Response{data=models.RevisionDiff} "比较成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限查看此文章的修订记录"
+// @Failure 404 {object} models.Response "修订版本不存在"
+// @Router /posts/{id}/revisions/diff [get]
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	postID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	var query models.RevisionDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.revisionCRUD.WithContext(c).Diff(postID, query.From, query.To)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "比较修订版本成功",
+		Data:    result,
+	})
+}
+
+// RestoreRevision 恢复到指定修订版本
+// @Summary 恢复修订版本
+// @Description 将文章的标题、摘要和正文恢复为指定修订版本的内容，恢复操作本身会记录为新的修订版本；仅文章作者和管理员可用
+// @Tags 文章修订
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param rev path int true "修订版本号"
+// @Success 200 {object} models.Response{data=models.Post} "恢复成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限修改此文章"
+// @Failure 404 {object} models.Response "修订版本不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /posts/{id}/revisions/{rev}/restore [post]
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	postID, userID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c)
+	if !ok {
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityPost, postID)
+	post, err := h.postCRUD.WithContext(c).RestoreRevision(postID, rev, userID, auth.Can(c, auth.PermPostModerate))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "文章不存在", "修订版本不存在":
+			statusCode = http.StatusNotFound
+		case "无权限修改此文章":
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityPost, post.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "文章已恢复到修订版本" + strconv.Itoa(rev),
+		Data:    post,
+	})
+}
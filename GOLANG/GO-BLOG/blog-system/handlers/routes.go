@@ -1,17 +1,42 @@
 package handlers
 
 import (
+	"log/slog"
+	"os"
+	"time"
+
 	"blog-system/auth"
 	"blog-system/config"
+	"blog-system/events"
+	"blog-system/mail"
+	"blog-system/metrics"
 	"blog-system/middleware"
 	"blog-system/models"
+	"blog-system/ratelimit"
+	"blog-system/realtime"
+	"blog-system/search"
+	"blog-system/spam"
+	"blog-system/storage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"gorm.io/gorm"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes(db *gorm.DB, cfg *config.Config) *gin.Engine {
+// newEngine 创建Gin路由，请求日志和panic恢复使用SetupRoutes中的结构化日志中间件。
+// gin.Context作为context使用时回退到请求的context，CRUD通过WithContext(c)执行的SQL会附带请求ID。
+// 只信任配置的反向代理传递的X-Forwarded-For，否则任何客户端都可以伪造IP绕过按IP的频率限制
+func newEngine(cfg *config.Config) (*gin.Engine, error) {
+	r := gin.New()
+	r.ContextWithFallback = true
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetupRoutes 设置路由，bus为业务事件总线，由调用方负责关闭
+func SetupRoutes(db *gorm.DB, cfg *config.Config, bus *events.Bus) *gin.Engine {
 	// 设置Gin模式
 	if cfg.App.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -20,31 +45,138 @@ func SetupRoutes(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		gin.SetMode(gin.DebugMode)
 	}
 
-	// 创建Gin路由
-	r := gin.Default()
+	r, err := newEngine(cfg)
+	if err != nil {
+		slog.Error("可信代理配置无效", "error", err)
+		os.Exit(1)
+	}
 
-	// 中间件
+	// 中间件，请求ID最先生成，供日志和审计记录使用
+	logger := slog.Default()
+	r.Use(middleware.RequestIDMiddleware())
 	if cfg.App.Env == "production" {
-		r.Use(middleware.LoggerMiddleware())
+		r.Use(middleware.LoggerMiddleware(logger))
 	} else {
-		// 开发环境使用详细的debug日志
-		r.Use(middleware.DebugLoggerMiddleware())
+		// 开发环境使用详细的debug日志，记录隐藏了敏感字段的请求体
+		r.Use(middleware.DebugLoggerMiddleware(logger, cfg.Log.BodyLimit))
 	}
-	r.Use(middleware.RecoveryMiddleware())
+	r.Use(middleware.RecoveryMiddleware(logger))
 	r.Use(middleware.CORSMiddleware())
 
+	// 监控指标：HTTP请求、SQL执行、连接池状态和业务计数
+	registry := metrics.NewRegistry()
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.NewHTTPMetrics(registry, metrics.Namespace).Handler())
+		if err := db.Use(metrics.NewGormPlugin(registry, metrics.Namespace)); err != nil {
+			slog.Warn("注册SQL监控指标失败", "error", err)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, cfg.Database.Name))
+		}
+	}
+
 	// 创建CRUD实例
 	userCRUD := models.NewUserCRUD(db)
 	postCRUD := models.NewPostCRUD(db)
 	commentCRUD := models.NewCommentCRUD(db)
+	revisionCRUD := models.NewRevisionCRUD(db)
+	trashCRUD := models.NewTrashCRUD(db)
+	tagCRUD := models.NewTagCRUD(db)
+	categoryCRUD := models.NewCategoryCRUD(db)
+	mediaCRUD := models.NewMediaCRUD(db)
+	reactionCRUD := models.NewReactionCRUD(db)
+	followCRUD := models.NewFollowCRUD(db)
+	bookmarkCRUD := models.NewBookmarkCRUD(db)
+	notificationCRUD := models.NewNotificationCRUD(db)
+	webhookCRUD := models.NewWebhookCRUD(db)
+	auditCRUD := models.NewAuditCRUD(db)
+	tokenCRUD := models.NewTokenCRUD(db, cfg.GetRefreshTokenExpireTime())
+	userTokenCRUD := models.NewUserTokenCRUD(db, cfg.JWT.Secret)
 
 	// 创建JWT管理器
-	jwtManager := auth.NewJWTManager(cfg)
+	jwtManager := auth.NewJWTManager(cfg, tokenCRUD, userCRUD)
+
+	// 创建搜索实现
+	searcher, err := search.New(cfg.Search.Driver, db)
+	if err != nil {
+		slog.Error("搜索初始化失败", "error", err)
+		os.Exit(1)
+	}
+
+	// 创建媒体文件存储
+	store, err := storage.New(cfg.Media)
+	if err != nil {
+		slog.Error("媒体存储初始化失败", "error", err)
+		os.Exit(1)
+	}
+
+	// 创建邮件发送实现
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		slog.Error("邮件初始化失败", "error", err)
+		os.Exit(1)
+	}
+	accountMailer := NewAccountMailer(mailer, userTokenCRUD, cfg)
+
+	// 未验证邮箱的用户禁止发布文章和评论
+	var emailVerifier auth.EmailVerifier
+	if cfg.Mail.RequireVerification {
+		emailVerifier = userCRUD
+	}
+	requireVerified := auth.RequireVerifiedEmail(emailVerifier)
+
+	// 创建频率限制器和登录防护
+	limitStore := ratelimit.NewMemory()
+	limiter := middleware.NewRateLimiter(limitStore, cfg.RateLimit.Enabled)
+	loginGuard := ratelimit.NewLoginGuard(limitStore, cfg.RateLimit.LockoutThreshold, cfg.GetLockoutWindow(), cfg.GetLockoutBase(), cfg.GetLockoutMax())
+	authLimit := limiter.Limit("auth", cfg.RateLimit.Auth)
+	writeLimit := limiter.Limit("write", cfg.RateLimit.Write)
+
+	// 订阅业务事件：站内通知在后台根据评论、点赞和关注事件生成，评论变更和新通知实时推送给在线的客户端，
+	// 文章和评论事件投递给订阅了该事件的Webhook
+	hub := realtime.NewHub(cfg.Stream.ClientBuffer, cfg.Stream.ReplaySize, cfg.GetStreamReplayTTL())
+	dispatcher := NewWebhookDispatcher(webhookCRUD, cfg)
+	dispatcher.Start()
+	SubscribeNotifications(bus, notificationCRUD)
+	SubscribeStreams(bus, hub)
+	SubscribeWebhooks(bus, webhookCRUD, dispatcher)
+
+	// 创建垃圾评论评分器
+	scorer := spam.NewScorer(cfg.Spam.HoldThreshold, cfg.Spam.SpamThreshold,
+		&spam.LinkRule{Max: cfg.Spam.MaxLinks},
+		&spam.BlocklistRule{Words: cfg.Spam.Blocklist},
+		&spam.RepeatRule{History: commentCRUD, Window: time.Duration(cfg.Spam.RepeatWindowMinutes) * time.Minute},
+		&spam.VelocityRule{History: commentCRUD, Window: time.Duration(cfg.Spam.VelocityWindowMinutes) * time.Minute, Limit: cfg.Spam.VelocityLimit},
+	)
+
+	// 文章、评论、用户和Webhook的修改操作记录审计日志
+	auditor := NewAuditor(auditCRUD)
 
 	// 创建处理器实例
-	userHandler := NewUserHandler(userCRUD, jwtManager)
-	postHandler := NewPostHandler(postCRUD)
-	commentHandler := NewCommentHandler(commentCRUD, postCRUD)
+	userHandler := NewUserHandler(userCRUD, postCRUD, tokenCRUD, jwtManager, accountMailer, loginGuard, auditor)
+	accountHandler := NewAccountHandler(userCRUD, tokenCRUD, userTokenCRUD, accountMailer, auditor)
+	adminHandler := NewAdminHandler(userCRUD, tokenCRUD, auditor)
+	auditHandler := NewAuditHandler(auditCRUD)
+	searchHandler := NewSearchHandler(searcher)
+	taxonomyHandler := NewTaxonomyHandler(tagCRUD, categoryCRUD, auditor)
+	postHandler := NewPostHandler(postCRUD, NewViewCounter(reactionCRUD, limitStore, cfg.GetViewWindow()), bus, auditor)
+	reactionHandler := NewReactionHandler(reactionCRUD, bus)
+	followHandler := NewFollowHandler(followCRUD, userCRUD, bus)
+	bookmarkHandler := NewBookmarkHandler(bookmarkCRUD)
+	notificationHandler := NewNotificationHandler(notificationCRUD)
+	streamHandler := NewStreamHandler(hub, postCRUD, jwtManager, cfg.GetStreamHeartbeat(), cfg.Stream.AllowedOrigins)
+	webhookHandler := NewWebhookHandler(webhookCRUD, dispatcher, auditor)
+	revisionHandler := NewRevisionHandler(revisionCRUD, postCRUD, auditor)
+	trashHandler := NewTrashHandler(trashCRUD, postCRUD, commentCRUD, auditor)
+	commentHandler := NewCommentHandler(commentCRUD, postCRUD, scorer, bus, auditor)
+	moderationHandler := NewModerationHandler(commentCRUD, bus, auditor)
+	mediaHandler := NewMediaHandler(mediaCRUD, userCRUD, store, cfg, auditor)
+	feedHandler := NewFeedHandler(postCRUD, userCRUD, cfg)
+
+	// 本地存储的媒体文件由静态文件服务提供
+	if local, ok := store.(*storage.Local); ok {
+		r.Static(cfg.Media.BaseURL, local.Root())
+	}
 
 	// 根路径欢迎页面
 	r.GET("/", func(c *gin.Context) {
@@ -54,38 +186,188 @@ func SetupRoutes(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			"docs":    "/swagger/index.html",
 			"api":     "/api",
 			"endpoints": gin.H{
-				"posts":    "GET /api/posts",
-				"register": "POST /api/register",
-				"login":    "POST /api/login",
+				"posts":     "GET /api/posts",
+				"search":    "GET /api/search?q=",
+				"register":  "POST /api/register",
+				"login":     "POST /api/login",
+				"refresh":   "POST /api/refresh",
+				"logout":    "POST /api/logout",
+				"rss":       "GET /feed.xml",
+				"atom":      "GET /atom.xml",
+				"json_feed": "GET /feed.json",
 			},
 		})
 	})
 
+	// 监控指标（不在/api下，配置METRICS_TOKEN后需要Bearer令牌）
+	if cfg.Metrics.Enabled {
+		r.GET(cfg.Metrics.Path, NewMetricsHandler(registry, cfg.Metrics.Token).Serve)
+	}
+
+	// 订阅源（公开，全站和单个作者）
+	anonymousLimit := limiter.Limit("anonymous", cfg.RateLimit.Anonymous)
+	r.GET("/feed.xml", anonymousLimit, feedHandler.RSS)
+	r.GET("/atom.xml", anonymousLimit, feedHandler.Atom)
+	r.GET("/feed.json", anonymousLimit, feedHandler.JSON)
+	r.GET("/users/:username/feed.xml", anonymousLimit, feedHandler.RSS)
+	r.GET("/users/:username/atom.xml", anonymousLimit, feedHandler.Atom)
+	r.GET("/users/:username/feed.json", anonymousLimit, feedHandler.JSON)
+
 	// 路由组
 	api := r.Group("/api")
 	{
-		// 公开路由（仅用户注册和登录）
-		api.POST("/register", userHandler.Register)
-		api.POST("/login", userHandler.Login)
+		// 公开路由（用户注册、登录和刷新令牌）
+		api.POST("/register", authLimit, userHandler.Register)
+		api.POST("/login", authLimit, userHandler.Login)
+		api.POST("/refresh", authLimit, userHandler.Refresh)
+
+		// 邮箱验证和密码找回
+		api.GET("/email/verify", authLimit, accountHandler.VerifyEmail)
+		api.POST("/email/verify", authLimit, accountHandler.VerifyEmail)
+		api.POST("/password/forgot", authLimit, accountHandler.ForgotPassword)
+		api.POST("/password/reset", authLimit, accountHandler.ResetPassword)
+
+		// 公开的用户资料
+		api.GET("/users/:username", anonymousLimit, userHandler.GetPublicProfile)
+		api.GET("/users/:username/followers", anonymousLimit, followHandler.ListFollowers)
+		api.GET("/users/:username/following", anonymousLimit, followHandler.ListFollowing)
 
-		// 需要认证的路由
+		// 实时推送，浏览器的EventSource和WebSocket无法设置请求头，允许通过access_token查询参数传递访问令牌
+		stream := api.Group("/stream")
+		stream.Use(auth.QueryTokenMiddleware(), jwtManager.AuthMiddleware(), limiter.Limit("user", cfg.RateLimit.User))
+		{
+			stream.GET("/posts/:id/comments", streamHandler.StreamComments)
+			stream.GET("/notifications", streamHandler.StreamNotifications)
+		}
+
+		// 需要认证的路由，按用户限制请求频率
 		authGroup := api.Group("/")
-		authGroup.Use(jwtManager.AuthMiddleware())
+		authGroup.Use(jwtManager.AuthMiddleware(), limiter.Limit("user", cfg.RateLimit.User))
 		{
+			// 用户管理
+			authGroup.POST("/logout", userHandler.Logout)
+			authGroup.GET("/users/me", userHandler.GetProfile)
+			authGroup.PUT("/users/me", userHandler.UpdateProfile)
+			authGroup.PUT("/users/me/password", userHandler.ChangePassword)
+			authGroup.POST("/email/verification", authLimit, accountHandler.RequestVerification)
+
 			// 文章管理
 			authGroup.GET("/posts", postHandler.GetAllPosts)
 			authGroup.GET("/latest-post", postHandler.GetLastPost)
 			authGroup.GET("/posts/:id", postHandler.GetPostByID)
-			authGroup.POST("/posts", postHandler.CreatePost)
+			authGroup.POST("/posts", auth.RequirePermission(auth.PermPostCreate), requireVerified, writeLimit, postHandler.CreatePost)
 			authGroup.PUT("/posts/:id", postHandler.UpdatePost)
 			authGroup.DELETE("/posts/:id", postHandler.DeletePost)
+			authGroup.POST("/posts/:id/publish", requireVerified, postHandler.PublishPost)
+			authGroup.POST("/posts/:id/unpublish", postHandler.UnpublishPost)
+
+			// 点赞和表情回应
+			authGroup.GET("/reactions", reactionHandler.ListReactionTypes)
+			authGroup.PUT("/posts/:id/like", reactionHandler.LikePost)
+			authGroup.DELETE("/posts/:id/like", reactionHandler.UnlikePost)
+			authGroup.PUT("/posts/:id/reactions/:reaction", reactionHandler.ReactPost)
+			authGroup.DELETE("/posts/:id/reactions/:reaction", reactionHandler.UnreactPost)
+			authGroup.PUT("/comments/:id/like", reactionHandler.LikeComment)
+			authGroup.DELETE("/comments/:id/like", reactionHandler.UnlikeComment)
+			authGroup.PUT("/comments/:id/reactions/:reaction", reactionHandler.ReactComment)
+			authGroup.DELETE("/comments/:id/reactions/:reaction", reactionHandler.UnreactComment)
+
+			// 关注、首页动态和收藏
+			authGroup.PUT("/users/:username/follow", followHandler.Follow)
+			authGroup.DELETE("/users/:username/follow", followHandler.Unfollow)
+			authGroup.GET("/feed", followHandler.HomeFeed)
+			authGroup.GET("/bookmarks", bookmarkHandler.ListBookmarks)
+			authGroup.POST("/bookmarks", bookmarkHandler.AddBookmark)
+			authGroup.DELETE("/bookmarks/:id", bookmarkHandler.RemoveBookmark)
+			authGroup.GET("/bookmarks/collections", bookmarkHandler.ListCollections)
+			authGroup.POST("/bookmarks/collections", bookmarkHandler.CreateCollection)
+			authGroup.PUT("/bookmarks/collections/:id", bookmarkHandler.UpdateCollection)
+			authGroup.DELETE("/bookmarks/collections/:id", bookmarkHandler.DeleteCollection)
+
+			// 站内通知
+			authGroup.GET("/notifications", notificationHandler.ListNotifications)
+			authGroup.GET("/notifications/unread-count", notificationHandler.GetUnreadCount)
+			authGroup.POST("/notifications/read-all", notificationHandler.MarkAllRead)
+			authGroup.POST("/notifications/:id/read", notificationHandler.MarkRead)
+			authGroup.GET("/notifications/preferences", notificationHandler.GetPreferences)
+			authGroup.PUT("/notifications/preferences", notificationHandler.UpdatePreferences)
+
+			// 文章修订记录
+			authGroup.GET("/posts/:id/revisions", revisionHandler.ListRevisions)
+			authGroup.GET("/posts/:id/revisions/diff", revisionHandler.DiffRevisions)
+			authGroup.GET("/posts/:id/revisions/:rev", revisionHandler.GetRevision)
+			authGroup.POST("/posts/:id/revisions/:rev/restore", revisionHandler.RestoreRevision)
 
 			// 评论管理
 			authGroup.GET("/posts/:id/comments", commentHandler.GetPostComments)
 			authGroup.GET("/comments/:id", commentHandler.GetCommentByID)
-			authGroup.POST("/posts/:id/comments", commentHandler.CreateComment)
+			authGroup.POST("/posts/:id/comments", auth.RequirePermission(auth.PermCommentCreate), requireVerified, writeLimit, commentHandler.CreateComment)
 			authGroup.PUT("/comments/:id", commentHandler.UpdateComment)
 			authGroup.DELETE("/comments/:id", commentHandler.DeleteComment)
+
+			// 回收站
+			authGroup.GET("/trash", trashHandler.ListTrash)
+			authGroup.POST("/posts/:id/restore", trashHandler.RestorePost)
+			authGroup.POST("/comments/:id/restore", trashHandler.RestoreComment)
+
+			// 媒体文件
+			authGroup.GET("/media", mediaHandler.ListMedia)
+			authGroup.GET("/media/usage", mediaHandler.GetUsage)
+			authGroup.POST("/media", auth.RequirePermission(auth.PermPostCreate), writeLimit, mediaHandler.UploadMedia)
+			authGroup.POST("/media/avatar", writeLimit, mediaHandler.UploadAvatar)
+			authGroup.DELETE("/media/:id", mediaHandler.DeleteMedia)
+
+			// 标签和分类
+			manageTaxonomy := auth.RequirePermission(auth.PermTaxonomyManage)
+			authGroup.GET("/tags", taxonomyHandler.ListTags)
+			authGroup.POST("/tags", manageTaxonomy, taxonomyHandler.CreateTag)
+			authGroup.PUT("/tags/:id", manageTaxonomy, taxonomyHandler.UpdateTag)
+			authGroup.DELETE("/tags/:id", manageTaxonomy, taxonomyHandler.DeleteTag)
+			authGroup.GET("/categories", taxonomyHandler.ListCategories)
+			authGroup.POST("/categories", manageTaxonomy, taxonomyHandler.CreateCategory)
+			authGroup.PUT("/categories/:id", manageTaxonomy, taxonomyHandler.UpdateCategory)
+			authGroup.DELETE("/categories/:id", manageTaxonomy, taxonomyHandler.DeleteCategory)
+
+			// 评论审核
+			moderationGroup := authGroup.Group("/moderation")
+			moderationGroup.Use(auth.RequirePermission(auth.PermCommentModerate))
+			{
+				moderationGroup.GET("/comments", moderationHandler.ListComments)
+				moderationGroup.POST("/comments", moderationHandler.ModerateComments)
+			}
+
+			// 搜索
+			authGroup.GET("/search", searchHandler.Search)
+
+			// 管理员路由
+			adminGroup := authGroup.Group("/admin")
+			adminGroup.Use(auth.RequirePermission(auth.PermUserManage))
+			{
+				adminGroup.GET("/users", adminHandler.ListUsers)
+				adminGroup.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+				adminGroup.POST("/users/:id/deactivate", adminHandler.DeactivateUser)
+				adminGroup.POST("/users/:id/activate", adminHandler.ActivateUser)
+				adminGroup.GET("/users/trash", adminHandler.ListDeletedUsers)
+				adminGroup.DELETE("/users/:id", adminHandler.DeleteUser)
+				adminGroup.POST("/users/:id/restore", adminHandler.RestoreUser)
+				adminGroup.GET("/audit", auditHandler.ListAuditLogs)
+				adminGroup.GET("/audit/verify", auditHandler.VerifyAuditLogs)
+			}
+
+			// Webhook管理
+			webhookGroup := authGroup.Group("/webhooks")
+			webhookGroup.Use(auth.RequirePermission(auth.PermWebhookManage))
+			{
+				webhookGroup.GET("/events", webhookHandler.ListWebhookEvents)
+				webhookGroup.GET("", webhookHandler.ListWebhooks)
+				webhookGroup.POST("", webhookHandler.CreateWebhook)
+				webhookGroup.GET("/:id", webhookHandler.GetWebhook)
+				webhookGroup.PUT("/:id", webhookHandler.UpdateWebhook)
+				webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+				webhookGroup.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+				webhookGroup.GET("/:id/deliveries/:delivery_id", webhookHandler.GetDelivery)
+				webhookGroup.POST("/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery)
+			}
 		}
 	}
 
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"blog-system/config"
+	"blog-system/middleware"
+	"blog-system/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSpoofedForwardedForDoesNotResetRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := config.RateLimitPolicy{Requests: 2, Period: time.Minute}
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantStatuses   []int
+	}{
+		// 未配置可信代理时忽略X-Forwarded-For，按连接地址计数
+		{"未配置可信代理", nil, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		// 请求来自可信代理时使用X-Forwarded-For中的客户端IP，每个客户端独立计数
+		{"来自可信代理", []string{"192.0.2.0/24"}, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newEngine(&config.Config{Server: config.ServerConfig{TrustedProxies: tt.trustedProxies}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			limiter := middleware.NewRateLimiter(ratelimit.NewMemory(), true)
+			r.POST("/api/login", limiter.Limit("auth", policy), func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			for i, want := range tt.wantStatuses {
+				req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+				req.RemoteAddr = "192.0.2.10:40000"
+				req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i+1))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Fatalf("第%d次请求 = %d, want %d", i+1, w.Code, want)
+				}
+			}
+		})
+	}
+
+	if _, err := newEngine(&config.Config{Server: config.ServerConfig{TrustedProxies: []string{"not-an-ip"}}}); err == nil {
+		t.Fatal("无效的可信代理地址应返回错误")
+	}
+}
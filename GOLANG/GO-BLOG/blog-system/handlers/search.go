@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog-system/models"
+	"blog-system/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler 搜索处理器
+type SearchHandler struct {
+	searcher search.Searcher
+}
+
+// NewSearchHandler 创建搜索处理器
+func NewSearchHandler(searcher search.Searcher) *SearchHandler {
+	return &SearchHandler{searcher: searcher}
+}
+
+// Search 全文搜索
+// @Summary 全文搜索
+// @Description 搜索已发布文章的标题、摘要和正文，可选包含评论；结果按相关度排序并返回高亮片段(命中词以<mark>标记)
+// @Tags 搜索
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "搜索关键词"
+// @Param comments query bool false "是否同时搜索评论"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大50"
+// @Success 200 {object} models.Response{data=[]search.Result} "搜索成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	var query search.Query
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	results, total, err := h.searcher.Search(c.Request.Context(), &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "搜索成功",
+		Data:    results,
+		Pagination: &models.Pagination{
+			Page:    query.Page,
+			Size:    query.Size,
+			Total:   total,
+			HasMore: int64(query.Offset()+len(results)) < total,
+		},
+	})
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-system/auth"
+	"blog-system/events"
+	"blog-system/models"
+	"blog-system/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamWriteTimeout 向客户端写入一条消息的超时时间，超时视为客户端失去响应
+	streamWriteTimeout = 10 * time.Second
+	// sseRetry 建议EventSource断线后的重连间隔
+	sseRetry = 3 * time.Second
+	// wsReadLimit 客户端发送的WebSocket消息的最大字节数，超过时以1009关闭连接
+	wsReadLimit = 64 << 10
+)
+
+// postTopic 文章评论的推送主题
+func postTopic(postID uint) string {
+	return "post:" + strconv.FormatUint(uint64(postID), 10)
+}
+
+// userTopic 用户通知的推送主题
+func userTopic(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// SubscribeStreams 订阅评论变更和新通知事件并推送给在线的客户端。
+// 只推送已通过审核的评论，修改后需要重新审核的评论按删除推送
+func SubscribeStreams(bus *events.Bus, hub *realtime.Hub) {
+	bus.Subscribe("实时推送", func(e events.Event) {
+		var err error
+		switch e.Type {
+		case events.CommentCreated, events.CommentUpdated:
+			comment, ok := e.Data.(*models.Comment)
+			if !ok {
+				return
+			}
+			if comment.Status == models.CommentStatusApproved {
+				err = hub.Publish(postTopic(comment.PostID), e.Type, comment)
+			} else if e.Type == events.CommentUpdated {
+				err = hub.Publish(postTopic(comment.PostID), events.CommentDeleted, gin.H{"id": comment.ID, "post_id": comment.PostID})
+			}
+		case events.CommentDeleted:
+			if comment, ok := e.Data.(*models.Comment); ok {
+				err = hub.Publish(postTopic(comment.PostID), e.Type, gin.H{"id": comment.ID, "post_id": comment.PostID})
+			}
+		case events.NotificationCreated:
+			err = hub.Publish(userTopic(e.TargetID), e.Type, e.Data)
+		}
+		if err != nil {
+			slog.Error("📡 推送事件失败", "event", e.Type, "error", err)
+		}
+	})
+}
+
+// StreamHandler 评论和通知实时推送处理器，同一地址同时支持Server-Sent Events和WebSocket
+type StreamHandler struct {
+	hub        *realtime.Hub
+	postCRUD   *models.PostCRUD
+	jwtManager *auth.JWTManager
+	heartbeat  time.Duration
+	origins    []string
+	upgrader   websocket.Upgrader
+}
+
+// NewStreamHandler 创建实时推送处理器，origins为允许发起WebSocket连接的页面域名，为空时只允许同域页面。
+// 连接期间每个心跳间隔通过jwtManager重新校验访问令牌和账户状态
+func NewStreamHandler(hub *realtime.Hub, postCRUD *models.PostCRUD, jwtManager *auth.JWTManager, heartbeat time.Duration, origins []string) *StreamHandler {
+	h := &StreamHandler{hub: hub, postCRUD: postCRUD, jwtManager: jwtManager, heartbeat: heartbeat, origins: origins}
+	h.upgrader = websocket.Upgrader{HandshakeTimeout: streamWriteTimeout, CheckOrigin: h.checkOrigin}
+	return h
+}
+
+// StreamComments 订阅文章评论
+// @Summary 订阅文章评论
+// @Description 实时推送文章新增(comment.created)、修改(comment.updated)和删除(comment.deleted)的评论。
+// @Description 默认使用Server-Sent Events；携带WebSocket握手请求头时升级为WebSocket，每条消息为{"id","event","data"}格式的JSON文本。
+// @Description 浏览器无法设置请求头时可通过access_token查询参数传递访问令牌；断线重连时通过Last-Event-ID请求头或last_event_id查询参数补发错过的消息
+// @Tags 实时推送
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Param access_token query string false "访问令牌"
+// @Param last_event_id query int false "最后收到的消息ID"
+// @Success 200 {string} string "事件流"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 404 {object} models.Response "文章不存在"
+// @Router /stream/posts/{id}/comments [get]
+func (h *StreamHandler) StreamComments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文章ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 文章撤回、删除后断开连接
+	visible := func() error {
+		if !h.postCRUD.WithContext(c).IsVisible(uint(id), userID) {
+			return errors.New("文章不存在")
+		}
+		return nil
+	}
+	if err := visible(); err != nil {
+		c.JSON(http.StatusNotFound, models.Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.serve(c, postTopic(uint(id)), visible)
+}
+
+// StreamNotifications 订阅我的通知
+// @Summary 订阅我的通知
+// @Description 实时推送当前用户新收到的站内通知(notification.created)，连接方式与订阅文章评论相同
+// @Tags 实时推送
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param access_token query string false "访问令牌"
+// @Param last_event_id query int false "最后收到的消息ID"
+// @Success 200 {string} string "事件流"
+// @Failure 401 {object} models.Response "未授权"
+// @Router /stream/notifications [get]
+func (h *StreamHandler) StreamNotifications(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	h.serve(c, userTopic(userID), nil)
+}
+
+// streamSession 连接期间需要持续满足的条件：访问令牌未过期、未被吊销，账户未被停用，
+// 以及recheck(如文章仍然可见)。连接只在建立时经过认证中间件，之后由推送循环定期检查
+type streamSession struct {
+	expired <-chan time.Time
+	check   func() error
+}
+
+// serve 根据请求头选择WebSocket或Server-Sent Events推送主题消息，recheck为nil时只校验认证状态
+func (h *StreamHandler) serve(c *gin.Context, topic string, recheck func() error) {
+	claims, err := auth.GetClaims(c)
+	if err != nil || claims.ExpiresAt == nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	// 访问令牌到期时立即断开，客户端刷新令牌后重连
+	expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+	defer expiry.Stop()
+	session := &streamSession{
+		expired: expiry.C,
+		check: func() error {
+			if h.jwtManager != nil {
+				if _, err := h.jwtManager.Validate(claims); err != nil {
+					return err
+				}
+			}
+			if recheck != nil {
+				return recheck()
+			}
+			return nil
+		},
+	}
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	since, _ := strconv.ParseUint(lastID, 10, 64)
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c, topic, since, session)
+		return
+	}
+	h.serveSSE(c, topic, since, session)
+}
+
+// serveSSE 以Server-Sent Events推送消息，定时发送注释行作为心跳，认证失效时结束响应
+func (h *StreamHandler) serveSSE(c *gin.Context, topic string, since uint64, session *streamSession) {
+	sub, missed := h.hub.Subscribe(topic, since)
+	defer h.hub.Unsubscribe(sub)
+
+	w := c.Writer
+	rc := http.NewResponseController(w)
+	defer rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // 禁止反向代理缓冲
+	w.WriteHeader(http.StatusOK)
+
+	write := func(chunk string) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := io.WriteString(w, chunk); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !write(fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())) {
+		return
+	}
+	for _, msg := range missed {
+		if !write(sseEvent(msg)) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			// 队列已满被取消订阅时断开连接，客户端携带Last-Event-ID重连后补发
+			if !ok || !write(sseEvent(msg)) {
+				return
+			}
+		case <-session.expired:
+			return
+		case <-ticker.C:
+			if err := session.check(); err != nil {
+				slog.DebugContext(c.Request.Context(), "实时推送连接已失效", "topic", topic, "error", err)
+				return
+			}
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// serveWebSocket 以WebSocket推送消息，定时发送ping，超过两个心跳间隔没有收到pong时断开，
+// 认证失效时以1008(Policy Violation)关闭。握手请求的Origin不被允许时返回403
+func (h *StreamHandler) serveWebSocket(c *gin.Context, topic string, since uint64, session *streamSession) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.DebugContext(c.Request.Context(), "WebSocket握手失败", "error", err)
+		return
+	}
+	closeCode, closeReason := websocket.CloseNormalClosure, ""
+	defer func() {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, closeReason), time.Now().Add(time.Second))
+		conn.Close()
+	}()
+
+	sub, missed := h.hub.Subscribe(topic, since)
+	defer h.hub.Unsubscribe(sub)
+
+	// 持续读取客户端数据以处理pong和关闭帧，客户端发送的消息被忽略
+	readTimeout := 2 * h.heartbeat
+	conn.SetReadLimit(wsReadLimit)
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(readTimeout))
+		}
+	}()
+
+	send := func(data []byte) bool {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteMessage(websocket.TextMessage, data) == nil
+	}
+
+	for _, msg := range missed {
+		if !send(wsMessage(msg)) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				closeCode, closeReason = websocket.CloseTryAgainLater, "处理过慢，请重新连接"
+				return
+			}
+			if !send(wsMessage(msg)) {
+				return
+			}
+		case <-session.expired:
+			closeCode, closeReason = websocket.ClosePolicyViolation, "认证令牌已过期"
+			return
+		case <-ticker.C:
+			if err := session.check(); err != nil {
+				closeCode, closeReason = websocket.ClosePolicyViolation, err.Error()
+				return
+			}
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)) != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkOrigin 校验WebSocket握手请求的Origin。没有Origin的非浏览器客户端和同域页面总是允许，
+// 其他页面的域名(含非默认端口)需要匹配允许的域名，支持*通配符
+func (h *StreamHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if host == strings.ToLower(r.Host) {
+		return true
+	}
+	for _, pattern := range h.origins {
+		if matched, _ := path.Match(strings.ToLower(pattern), host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sseEvent 将消息编码为Server-Sent Events格式
+func sseEvent(msg realtime.Message) string {
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event, msg.Data)
+}
+
+// wsMessage 将消息编码为WebSocket文本消息
+func wsMessage(msg realtime.Message) []byte {
+	data, _ := json.Marshal(struct {
+		ID    string          `json:"id"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}{strconv.FormatUint(msg.ID, 10), msg.Event, msg.Data})
+	return data
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-system/auth"
+	"blog-system/config"
+	"blog-system/internal/testdb"
+	"blog-system/models"
+	"blog-system/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// streamTestEnv 实时推送测试环境，心跳间隔很短，便于观察连接期间的重新校验
+type streamTestEnv struct {
+	server     *httptest.Server
+	users      *models.UserCRUD
+	posts      *models.PostCRUD
+	tokens     *models.TokenCRUD
+	jwtManager *auth.JWTManager
+	user       *models.User
+	post       *models.Post
+}
+
+func newStreamTestEnv(t *testing.T, heartbeat time.Duration) *streamTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	env := &streamTestEnv{
+		users:  models.NewUserCRUD(db),
+		posts:  models.NewPostCRUD(db),
+		tokens: models.NewTokenCRUD(db, time.Hour),
+	}
+	env.jwtManager = auth.NewJWTManager(&config.Config{JWT: config.JWTConfig{Secret: []byte("stream-secret"), AccessExpireMinutes: 15}}, env.tokens, env.users)
+
+	var err error
+	env.user, err = env.users.Create(&models.RegisterRequest{Username: "alice", Password: "password123", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 文章属于其他作者，撤回后对连接的用户不可见
+	author, err := env.users.Create(&models.RegisterRequest{Username: "author", Password: "password123", Email: "author@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.post, err = env.posts.Create(&models.PostRequest{Title: "文章", Content: "内容"}, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewStreamHandler(realtime.NewHub(16, 16, time.Minute), env.posts, env.jwtManager, heartbeat, nil)
+	r := gin.New()
+	stream := r.Group("/stream", auth.QueryTokenMiddleware(), env.jwtManager.AuthMiddleware())
+	stream.GET("/posts/:id/comments", handler.StreamComments)
+	stream.GET("/notifications", handler.StreamNotifications)
+	env.server = httptest.NewServer(r)
+	t.Cleanup(env.server.Close)
+	return env
+}
+
+func (e *streamTestEnv) token(t *testing.T) (string, *auth.Claims) {
+	t.Helper()
+	token, err := e.jwtManager.GenerateToken(e.user.ID, e.user.Username, e.user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := e.jwtManager.ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, claims
+}
+
+// openSSE 建立SSE连接，返回在连接结束时关闭的channel
+func openSSE(t *testing.T, url string) <-chan struct{} {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("GET %s = %d", url, resp.StatusCode)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+		}
+	}()
+	return done
+}
+
+func waitClosed(t *testing.T, done <-chan struct{}, reason string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s后连接应被断开", reason)
+	}
+}
+
+func TestStreamDisconnectsWhenSessionBecomesInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       func(env *streamTestEnv) string
+		invalidate func(t *testing.T, env *streamTestEnv, claims *auth.Claims)
+	}{
+		{"账户被停用", func(env *streamTestEnv) string { return "/stream/notifications" }, func(t *testing.T, env *streamTestEnv, claims *auth.Claims) {
+			if _, err := env.users.SetActive(env.user.ID, false); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"账户被删除", func(env *streamTestEnv) string { return "/stream/notifications" }, func(t *testing.T, env *streamTestEnv, claims *auth.Claims) {
+			if err := env.users.Delete(env.user.ID); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"访问令牌被吊销", func(env *streamTestEnv) string { return "/stream/notifications" }, func(t *testing.T, env *streamTestEnv, claims *auth.Claims) {
+			if err := env.tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"文章被撤回", func(env *streamTestEnv) string {
+			return "/stream/posts/" + strconv.Itoa(int(env.post.ID)) + "/comments"
+		}, func(t *testing.T, env *streamTestEnv, claims *auth.Claims) {
+			if _, err := env.posts.Unpublish(env.post.ID, env.post.UserID, false); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"文章被删除", func(env *streamTestEnv) string {
+			return "/stream/posts/" + strconv.Itoa(int(env.post.ID)) + "/comments"
+		}, func(t *testing.T, env *streamTestEnv, claims *auth.Claims) {
+			if _, err := env.posts.Delete(env.post.ID, env.post.UserID, false); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newStreamTestEnv(t, 20*time.Millisecond)
+			token, claims := env.token(t)
+			done := openSSE(t, env.server.URL+tt.path(env)+"?access_token="+token)
+
+			// 心跳时校验通过，连接保持
+			select {
+			case <-done:
+				t.Fatal("认证有效时连接不应断开")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			tt.invalidate(t, env, claims)
+			waitClosed(t, done, tt.name)
+		})
+	}
+}
+
+func TestStreamWebSocketClosesWhenTokenRevoked(t *testing.T) {
+	env := newStreamTestEnv(t, 20*time.Millisecond)
+	token, claims := env.token(t)
+
+	url := "ws" + strings.TrimPrefix(env.server.URL, "http") + "/stream/notifications?access_token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("WebSocket连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	if err := env.tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("令牌吊销后应以1008关闭连接: %v", err)
+	}
+	if ce := err.(*websocket.CloseError); ce.Text != "认证令牌已失效" {
+		t.Fatalf("关闭原因 = %q", ce.Text)
+	}
+}
+
+func TestStreamDisconnectsWhenTokenExpires(t *testing.T) {
+	// 心跳间隔远大于令牌剩余有效期，由到期定时器断开连接
+	env := newStreamTestEnv(t, time.Minute)
+	claims := &auth.Claims{
+		UserID:   env.user.ID,
+		Username: env.user.Username,
+		Role:     env.user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "short-lived",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Second)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("stream-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := openSSE(t, env.server.URL+"/stream/notifications?access_token="+token)
+	waitClosed(t, done, "访问令牌到期")
+}
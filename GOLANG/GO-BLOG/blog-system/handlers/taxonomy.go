@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaxonomyHandler 标签和分类处理器
+type TaxonomyHandler struct {
+	tagCRUD      *models.TagCRUD
+	categoryCRUD *models.CategoryCRUD
+	audit        *Auditor
+}
+
+// NewTaxonomyHandler 创建标签和分类处理器
+func NewTaxonomyHandler(tagCRUD *models.TagCRUD, categoryCRUD *models.CategoryCRUD, audit *Auditor) *TaxonomyHandler {
+	return &TaxonomyHandler{
+		tagCRUD:      tagCRUD,
+		categoryCRUD: categoryCRUD,
+		audit:        audit,
+	}
+}
+
+// taxonomyStatus 将标签和分类的错误信息映射为HTTP状态码
+func taxonomyStatus(err error) int {
+	switch err.Error() {
+	case "标签不存在", "分类不存在":
+		return http.StatusNotFound
+	case "标签已存在", "标签名不能为空", "同级分类名称已存在", "父分类不存在",
+		"不能将分类移动到自身或其子分类下", "分类下存在子分类，无法删除":
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// ListTags 获取标签列表
+// @Summary 获取标签列表
+// @Description 获取所有标签及其已发布文章数量，按文章数量倒序排列
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=[]models.Tag} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /tags [get]
+func (h *TaxonomyHandler) ListTags(c *gin.Context) {
+	tags, err := h.tagCRUD.WithContext(c).ListWithCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取标签列表成功",
+		Data:    tags,
+	})
+}
+
+// CreateTag 创建标签
+// @Summary 创建标签
+// @Description 创建新标签，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TagRequest true "标签信息"
+// @Success 201 {object} models.Response{data=models.Tag} "创建成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Router /tags [post]
+func (h *TaxonomyHandler) CreateTag(c *gin.Context) {
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	tag, err := h.tagCRUD.WithContext(c).Create(&req)
+	if err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityTag, tag.ID, nil)
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "标签创建成功",
+		Data:    tag,
+	})
+}
+
+// UpdateTag 重命名标签
+// @Summary 重命名标签
+// @Description 重命名标签，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "标签ID"
+// @Param request body models.TagRequest true "标签信息"
+// @Success 200 {object} models.Response{data=models.Tag} "更新成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "标签不存在"
+// @Router /tags/{id} [put]
+func (h *TaxonomyHandler) UpdateTag(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的标签ID",
+		})
+		return
+	}
+
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityTag, uint(id))
+	tag, err := h.tagCRUD.WithContext(c).Update(uint(id), &req)
+	if err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityTag, tag.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "标签更新成功",
+		Data:    tag,
+	})
+}
+
+// DeleteTag 删除标签
+// @Summary 删除标签
+// @Description 删除标签并解除其与文章的关联，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "标签ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "无效的标签ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "标签不存在"
+// @Router /tags/{id} [delete]
+func (h *TaxonomyHandler) DeleteTag(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的标签ID",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityTag, uint(id))
+	if err := h.tagCRUD.WithContext(c).Delete(uint(id)); err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityTag, uint(id), before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "标签删除成功",
+	})
+}
+
+// ListCategories 获取分类树
+// @Summary 获取分类树
+// @Description 获取多级分类树，每个分类的文章数量包含其子分类的已发布文章
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=[]models.Category} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /categories [get]
+func (h *TaxonomyHandler) ListCategories(c *gin.Context) {
+	categories, err := h.categoryCRUD.WithContext(c).Tree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取分类列表成功",
+		Data:    categories,
+	})
+}
+
+// CreateCategory 创建分类
+// @Summary 创建分类
+// @Description 创建分类，parent_id为空时创建顶级分类，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CategoryRequest true "分类信息"
+// @Success 201 {object} models.Response{data=models.Category} "创建成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Router /categories [post]
+func (h *TaxonomyHandler) CreateCategory(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	category, err := h.categoryCRUD.WithContext(c).Create(&req)
+	if err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityCategory, category.ID, nil)
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "分类创建成功",
+		Data:    category,
+	})
+}
+
+// UpdateCategory 更新分类
+// @Summary 更新分类
+// @Description 更新分类名称、描述或移动到其他父分类，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分类ID"
+// @Param request body models.CategoryRequest true "分类信息"
+// @Success 200 {object} models.Response{data=models.Category} "更新成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "分类不存在"
+// @Router /categories/{id} [put]
+func (h *TaxonomyHandler) UpdateCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的分类ID",
+		})
+		return
+	}
+
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityCategory, uint(id))
+	category, err := h.categoryCRUD.WithContext(c).Update(uint(id), &req)
+	if err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityCategory, category.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "分类更新成功",
+		Data:    category,
+	})
+}
+
+// DeleteCategory 删除分类
+// @Summary 删除分类
+// @Description 删除没有子分类的分类，其下文章变为未分类，需要版主及以上角色
+// @Tags 标签分类
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分类ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "分类下存在子分类"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "分类不存在"
+// @Router /categories/{id} [delete]
+func (h *TaxonomyHandler) DeleteCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的分类ID",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityCategory, uint(id))
+	if err := h.categoryCRUD.WithContext(c).Delete(uint(id)); err != nil {
+		statusCode := taxonomyStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityCategory, uint(id), before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "分类删除成功",
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-system/internal/testdb"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTaxonomyChangesAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	admin, err := models.NewUserCRUD(db).Create(&models.RegisterRequest{Username: "admin", Password: "password123", Email: "admin@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditCRUD := models.NewAuditCRUD(db)
+	handler := NewTaxonomyHandler(models.NewTagCRUD(db), models.NewCategoryCRUD(db), NewAuditor(auditCRUD))
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", admin.ID) })
+	r.POST("/categories", handler.CreateCategory)
+	r.PUT("/categories/:id", handler.UpdateCategory)
+	r.DELETE("/categories/:id", handler.DeleteCategory)
+	do := func(method, path string, body any, want int) []byte {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s %s = %d, %s", method, path, w.Code, w.Body.String())
+		}
+		return w.Body.Bytes()
+	}
+
+	var created struct {
+		Data models.Category `json:"data"`
+	}
+	if err := json.Unmarshal(do(http.MethodPost, "/categories", models.CategoryRequest{Name: "技术"}, http.StatusCreated), &created); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/categories/%d", created.Data.ID)
+	do(http.MethodPut, path, models.CategoryRequest{Name: "编程"}, http.StatusOK)
+	do(http.MethodDelete, path, nil, http.StatusOK)
+
+	logs, _, err := auditCRUD.List(&models.AuditQuery{EntityType: models.AuditEntityCategory, EntityID: created.Data.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("记录了%d条审计日志, want 3", len(logs))
+	}
+	// 按时间倒序排列
+	for i, action := range []string{models.AuditDelete, models.AuditUpdate, models.AuditCreate} {
+		if logs[i].Action != action || logs[i].ActorID != admin.ID {
+			t.Fatalf("logs[%d] = %+v, want action %s", i, logs[i], action)
+		}
+	}
+	if !bytes.Contains(logs[1].Before, []byte("技术")) || !bytes.Contains(logs[1].After, []byte("编程")) {
+		t.Fatalf("修改的审计日志 = before %s, after %s", logs[1].Before, logs[1].After)
+	}
+	if !bytes.Contains(logs[0].Before, []byte("编程")) {
+		t.Fatalf("删除的审计日志 = before %s", logs[0].Before)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-system/auth"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler 回收站处理器
+type TrashHandler struct {
+	trashCRUD   *models.TrashCRUD
+	postCRUD    *models.PostCRUD
+	commentCRUD *models.CommentCRUD
+	audit       *Auditor
+}
+
+// NewTrashHandler 创建回收站处理器
+func NewTrashHandler(trashCRUD *models.TrashCRUD, postCRUD *models.PostCRUD, commentCRUD *models.CommentCRUD, audit *Auditor) *TrashHandler {
+	return &TrashHandler{
+		trashCRUD:   trashCRUD,
+		postCRUD:    postCRUD,
+		commentCRUD: commentCRUD,
+		audit:       audit,
+	}
+}
+
+// ListTrash 获取回收站内容
+// @Summary 获取回收站
+// @Description 分页获取当前用户已删除的文章或评论，按删除时间倒序排列；超过保留期的内容会被后台任务永久删除
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "内容类型: post(默认), comment"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /trash [get]
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	var query models.TrashQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	var data interface{}
+	var pagination *models.Pagination
+	if query.Type == models.TrashTypeComment {
+		data, pagination, err = h.trashCRUD.WithContext(c).ListComments(userID, &query.PageQuery)
+	} else {
+		data, pagination, err = h.trashCRUD.WithContext(c).ListPosts(userID, &query.PageQuery)
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "无效的分页游标" {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取回收站成功",
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+// RestorePost 从回收站恢复文章
+// @Summary 恢复文章
+// @Description 从回收站恢复文章，作者只能恢复自己删除的文章，管理员可以恢复任意文章
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文章ID"
+// @Success 200 {object} models.Response{data=models.Post} "恢复成功"
+// @Failure 400 {object} models.Response "无效的文章ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限恢复此文章"
+// @Failure 404 {object} models.Response "回收站中不存在该文章"
+// @Router /posts/{id}/restore [post]
+func (h *TrashHandler) RestorePost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的文章ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityPost, uint(id))
+	post, err := h.postCRUD.WithContext(c).Restore(uint(id), userID, auth.Can(c, auth.PermPostModerate))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "回收站中不存在该文章":
+			statusCode = http.StatusNotFound
+		case "无权限恢复此文章":
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditRestore, models.AuditEntityPost, post.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "文章恢复成功",
+		Data:    post,
+	})
+}
+
+// RestoreComment 从回收站恢复评论
+// @Summary 恢复评论
+// @Description 从回收站恢复评论及与其一同删除的回复，评论者只能恢复自己删除的评论，版主和管理员可以恢复任意评论；父评论或文章已删除时需先恢复它们
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Success 200 {object} models.Response{data=models.Comment} "恢复成功"
+// @Failure 400 {object} models.Response "无效的评论ID"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "无权限恢复此评论"
+// @Failure 404 {object} models.Response "回收站中不存在该评论"
+// @Failure 409 {object} models.Response "父评论或文章已删除"
+// @Router /comments/{id}/restore [post]
+func (h *TrashHandler) RestoreComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的评论ID",
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityComment, uint(id))
+	comment, err := h.commentCRUD.WithContext(c).Restore(uint(id), userID, auth.Can(c, auth.PermCommentModerate))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "回收站中不存在该评论":
+			statusCode = http.StatusNotFound
+		case "无权限恢复此评论":
+			statusCode = http.StatusForbidden
+		case "文章已删除，请先恢复文章", "父评论已删除，请先恢复父评论":
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditRestore, models.AuditEntityComment, comment.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "评论恢复成功",
+		Data:    comment,
+	})
+}
@@ -0,0 +1,716 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-system/auth"
+	"blog-system/config"
+	"blog-system/events"
+	"blog-system/models"
+	"blog-system/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookPollInterval 检查到期重试的间隔，新事件产生时会立即投递而不等待轮询
+const webhookPollInterval = 5 * time.Second
+
+// webhookPayload 投递的请求内容。重新投递时id保持不变，接收方可据此去重
+type webhookPayload struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// webhookPost 投递给第三方的文章内容，作者只包含公开信息
+type webhookPost struct {
+	ID          uint                `json:"id"`
+	Title       string              `json:"title"`
+	Slug        string              `json:"slug"`
+	Summary     string              `json:"summary"`
+	Content     string              `json:"content"`
+	ContentHTML string              `json:"content_html"`
+	Status      string              `json:"status"`
+	UserID      uint                `json:"user_id"`
+	CategoryID  *uint               `json:"category_id"`
+	Tags        []string            `json:"tags"`
+	PublishedAt *time.Time          `json:"published_at"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	User        *models.UserSummary `json:"user,omitempty"`
+}
+
+// webhookComment 投递给第三方的评论内容，评论者只包含公开信息，不包含IP等来源信息
+type webhookComment struct {
+	ID          uint                `json:"id"`
+	Content     string              `json:"content"`
+	ContentHTML string              `json:"content_html"`
+	UserID      uint                `json:"user_id"`
+	PostID      uint                `json:"post_id"`
+	ParentID    *uint               `json:"parent_id"`
+	Depth       int                 `json:"depth"`
+	Status      string              `json:"status"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	User        *models.UserSummary `json:"user,omitempty"`
+}
+
+func newWebhookPost(post *models.Post) *webhookPost {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+	return &webhookPost{
+		ID:          post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Summary:     post.Summary,
+		Content:     post.Content,
+		ContentHTML: post.ContentHTML,
+		Status:      post.Status,
+		UserID:      post.UserID,
+		CategoryID:  post.CategoryID,
+		Tags:        tags,
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		User:        webhookUser(&post.User),
+	}
+}
+
+func newWebhookComment(comment *models.Comment) *webhookComment {
+	return &webhookComment{
+		ID:          comment.ID,
+		Content:     comment.Content,
+		ContentHTML: comment.ContentHTML,
+		UserID:      comment.UserID,
+		PostID:      comment.PostID,
+		ParentID:    comment.ParentID,
+		Depth:       comment.Depth,
+		Status:      comment.Status,
+		CreatedAt:   comment.CreatedAt,
+		UpdatedAt:   comment.UpdatedAt,
+		User:        webhookUser(&comment.User),
+	}
+}
+
+// webhookUser 返回预加载的用户的公开信息，未加载时返回nil
+func webhookUser(user *models.User) *models.UserSummary {
+	if user.ID == 0 {
+		return nil
+	}
+	summary := user.Summary()
+	return &summary
+}
+
+// SubscribeWebhooks 订阅文章和评论事件，为订阅了该事件的Webhook创建投递记录并通知投递器。
+// 只投递已发布的文章和已通过审核的评论，修改后需要重新审核的评论按删除投递
+func SubscribeWebhooks(bus *events.Bus, webhookCRUD *models.WebhookCRUD, dispatcher *WebhookDispatcher) {
+	bus.Subscribe("Webhook", func(e events.Event) {
+		eventType := e.Type
+		var data any
+		switch e.Type {
+		case events.PostPublished, events.PostUpdated, events.PostUnpublished, events.PostDeleted:
+			post, ok := e.Data.(*models.Post)
+			if !ok {
+				return
+			}
+			data = newWebhookPost(post)
+		case events.CommentCreated, events.CommentUpdated, events.CommentDeleted:
+			comment, ok := e.Data.(*models.Comment)
+			if !ok {
+				return
+			}
+			if comment.Status != models.CommentStatusApproved {
+				if e.Type != events.CommentUpdated {
+					return
+				}
+				eventType = events.CommentDeleted
+			}
+			data = newWebhookComment(comment)
+		default:
+			return
+		}
+
+		id, err := newEventID()
+		if err != nil {
+			slog.Error("🪝 生成事件ID失败", "event", eventType, "error", err)
+			return
+		}
+		payload, err := json.Marshal(webhookPayload{ID: id, Event: eventType, CreatedAt: e.At, Data: data})
+		if err != nil {
+			slog.Error("🪝 编码事件失败", "event", eventType, "error", err)
+			return
+		}
+
+		count, err := webhookCRUD.Enqueue(eventType, id, payload)
+		if err != nil {
+			slog.Error("🪝 创建投递记录失败", "event", eventType, "error", err)
+			return
+		}
+		if count > 0 {
+			dispatcher.Kick()
+		}
+	})
+}
+
+// newEventID 生成随机的事件ID
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WebhookDispatcher 在后台投递待发送的Webhook请求，失败后按指数退避重试
+type WebhookDispatcher struct {
+	webhookCRUD *models.WebhookCRUD
+	client      *webhook.Client
+	policy      models.RetryPolicy
+	workers     int
+	lease       time.Duration
+	kick        chan struct{}
+	startOnce   sync.Once
+}
+
+// NewWebhookDispatcher 创建投递器，需调用Start启动
+func NewWebhookDispatcher(webhookCRUD *models.WebhookCRUD, cfg *config.Config) *WebhookDispatcher {
+	workers := max(cfg.Webhook.Workers, 1)
+	return &WebhookDispatcher{
+		webhookCRUD: webhookCRUD,
+		client:      webhook.NewClient(cfg.GetWebhookTimeout()),
+		policy: models.RetryPolicy{
+			MaxAttempts:  max(cfg.Webhook.MaxAttempts, 1),
+			BaseDelay:    time.Duration(cfg.Webhook.RetryBaseSeconds) * time.Second,
+			MaxDelay:     time.Duration(cfg.Webhook.RetryMaxMinutes) * time.Minute,
+			DisableAfter: cfg.Webhook.DisableAfterFailures,
+		},
+		workers: workers,
+		// 每批领取的记录并发投递，租约留出请求超时之外的余量
+		lease: cfg.GetWebhookTimeout() + time.Minute,
+		kick:  make(chan struct{}, 1),
+	}
+}
+
+// Start 启动后台投递循环，重复调用不产生影响
+func (d *WebhookDispatcher) Start() {
+	d.startOnce.Do(func() {
+		go d.loop()
+	})
+}
+
+// Kick 有新的待投递记录时立即开始投递，不等待下一次轮询
+func (d *WebhookDispatcher) Kick() {
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (d *WebhookDispatcher) loop() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue()
+		select {
+		case <-ticker.C:
+		case <-d.kick:
+		}
+	}
+}
+
+// deliverDue 分批领取并投递所有已到投递时间的记录
+func (d *WebhookDispatcher) deliverDue() {
+	for {
+		claimed, err := d.webhookCRUD.ClaimDue(time.Now(), d.lease, d.workers)
+		if err != nil {
+			slog.Error("🪝 领取待投递记录失败", "error", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := range claimed {
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				d.deliver(delivery)
+			}(&claimed[i])
+		}
+		wg.Wait()
+
+		if len(claimed) < d.workers {
+			return
+		}
+	}
+}
+
+// deliver 发送一条投递记录并保存结果，投递过程中的panic不会影响投递循环
+func (d *WebhookDispatcher) deliver(delivery *models.WebhookDelivery) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("🪝 投递异常", "delivery_id", delivery.ID, "error", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+
+	hook := delivery.Webhook
+	if !hook.Active {
+		if err := d.webhookCRUD.Cancel(delivery, "Webhook已停用"); err != nil {
+			slog.Error("🪝 取消投递失败", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	result := d.client.Send(context.Background(), webhook.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      delivery.Event,
+		DeliveryID: strconv.FormatUint(uint64(delivery.ID), 10),
+		Body:       []byte(delivery.Payload),
+	})
+
+	disabled, err := d.webhookCRUD.RecordAttempt(delivery, models.DeliveryAttempt{
+		StatusCode: result.StatusCode,
+		Response:   result.Body,
+		Error:      result.Error(),
+		Duration:   result.Duration,
+	}, d.policy)
+	if err != nil {
+		slog.Error("🪝 保存投递结果失败", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	if !result.OK() {
+		slog.Warn("🪝 投递失败", "event", delivery.Event, "webhook_id", hook.ID, "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", result.Error())
+	}
+	if disabled {
+		slog.Warn("🪝 Webhook连续投递失败，已自动停用", "webhook_id", hook.ID)
+	}
+}
+
+// webhookErrorStatus 根据Webhook操作的错误确定响应状态码
+func webhookErrorStatus(err error) int {
+	switch {
+	case err.Error() == "Webhook不存在", err.Error() == "投递记录不存在":
+		return http.StatusNotFound
+	case err.Error() == "Webhook已停用，请先启用":
+		return http.StatusConflict
+	case err.Error() == "Webhook地址必须是http或https地址", err.Error() == "无效的分页游标",
+		strings.HasPrefix(err.Error(), "无效的事件类型"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WebhookHandler Webhook管理处理器
+type WebhookHandler struct {
+	webhookCRUD *models.WebhookCRUD
+	dispatcher  *WebhookDispatcher
+	audit       *Auditor
+}
+
+// NewWebhookHandler 创建Webhook管理处理器
+func NewWebhookHandler(webhookCRUD *models.WebhookCRUD, dispatcher *WebhookDispatcher, audit *Auditor) *WebhookHandler {
+	return &WebhookHandler{webhookCRUD: webhookCRUD, dispatcher: dispatcher, audit: audit}
+}
+
+// ListWebhookEvents 获取可订阅的事件类型
+// @Summary 获取可订阅的事件类型
+// @Description 获取Webhook可以订阅的全部事件类型
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=[]string} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Router /webhooks/events [get]
+func (h *WebhookHandler) ListWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取事件类型成功",
+		Data:    models.WebhookEvents,
+	})
+}
+
+// ListWebhooks 获取Webhook列表
+// @Summary 获取Webhook列表
+// @Description 获取全部Webhook，不包含签名密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Response{data=[]models.Webhook} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookCRUD.WithContext(c).List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取Webhook成功",
+		Data:    webhooks,
+	})
+}
+
+// GetWebhook 获取Webhook
+// @Summary 获取Webhook
+// @Description 获取单个Webhook的配置和状态，不包含签名密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Success 200 {object} models.Response{data=models.Webhook} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "Webhook不存在"
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	hook, err := h.webhookCRUD.WithContext(c).GetByID(id)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取Webhook成功",
+		Data:    hook,
+	})
+}
+
+// CreateWebhook 创建Webhook
+// @Summary 创建Webhook
+// @Description 创建Webhook并订阅事件。未指定secret时随机生成签名密钥，密钥只在创建响应中返回一次。
+// @Description 每次投递以POST发送JSON，请求头X-Blog-Event为事件类型，X-Blog-Delivery为投递记录ID，X-Blog-Timestamp为Unix时间戳，
+// @Description X-Blog-Signature为"sha256="加上以密钥对"时间戳.请求体"计算的HMAC-SHA256十六进制值
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body models.WebhookRequest true "Webhook信息"
+// @Success 201 {object} models.Response{data=models.WebhookWithSecret} "创建成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "获取用户信息失败",
+		})
+		return
+	}
+
+	hook, err := h.webhookCRUD.WithContext(c).Create(&req, userID)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditCreate, models.AuditEntityWebhook, hook.ID, nil)
+
+	c.JSON(http.StatusCreated, models.Response{
+		Code:    201,
+		Message: "Webhook创建成功，请妥善保存签名密钥",
+		Data:    hook,
+	})
+}
+
+// UpdateWebhook 修改Webhook
+// @Summary 修改Webhook
+// @Description 修改Webhook的地址、订阅的事件和启用状态，secret为空时保留原密钥。
+// @Description 重新启用被自动停用的Webhook会清零连续失败次数；停用时尚未完成的投递会被取消
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Param webhook body models.WebhookRequest true "Webhook信息"
+// @Success 200 {object} models.Response{data=models.Webhook} "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "Webhook不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityWebhook, id)
+	hook, err := h.webhookCRUD.WithContext(c).Update(id, &req)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditUpdate, models.AuditEntityWebhook, hook.ID, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "Webhook修改成功",
+		Data:    hook,
+	})
+}
+
+// DeleteWebhook 删除Webhook
+// @Summary 删除Webhook
+// @Description 删除Webhook及其全部投递记录
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "Webhook不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	before := h.audit.Snapshot(models.AuditEntityWebhook, id)
+	if err := h.webhookCRUD.WithContext(c).Delete(id); err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, models.AuditDelete, models.AuditEntityWebhook, id, before)
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "Webhook删除成功",
+	})
+}
+
+// ListDeliveries 获取投递记录
+// @Summary 获取投递记录
+// @Description 分页获取Webhook的投递记录，按创建时间倒序排列，可按状态筛选。
+// @Description status为pending表示等待投递或等待重试，success表示投递成功，failed表示重试次数用尽或Webhook已停用
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Param status query string false "投递状态" Enums(pending, success, failed)
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10，最大100"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor，提供时忽略page"
+// @Success 200 {object} models.Response{data=[]models.WebhookDelivery} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "Webhook不存在"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var query models.DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	deliveries, pagination, err := h.webhookCRUD.WithContext(c).ListDeliveries(id, &query)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:       200,
+		Message:    "获取投递记录成功",
+		Data:       deliveries,
+		Pagination: pagination,
+	})
+}
+
+// GetDelivery 获取投递记录详情
+// @Summary 获取投递记录详情
+// @Description 获取单条投递记录，包括请求内容和最后一次请求的响应
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Param delivery_id path int true "投递记录ID"
+// @Success 200 {object} models.Response{data=models.WebhookDelivery} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "投递记录不存在"
+// @Router /webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookCRUD.WithContext(c).GetDelivery(id, deliveryID)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "获取投递记录成功",
+		Data:    delivery,
+	})
+}
+
+// ReplayDelivery 重新投递
+// @Summary 重新投递
+// @Description 以相同的事件ID和请求内容重新投递一次，创建一条新的投递记录并立即开始发送。已停用的Webhook需先启用
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "WebhookID"
+// @Param delivery_id path int true "投递记录ID"
+// @Success 202 {object} models.Response{data=models.WebhookDelivery} "已加入投递队列"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "权限不足"
+// @Failure 404 {object} models.Response "投递记录不存在"
+// @Failure 409 {object} models.Response "Webhook已停用"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	id, deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookCRUD.WithContext(c).Replay(id, deliveryID)
+	if err != nil {
+		statusCode := webhookErrorStatus(err)
+		c.JSON(statusCode, models.Response{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.dispatcher.Kick()
+
+	c.JSON(http.StatusAccepted, models.Response{
+		Code:    202,
+		Message: "已加入投递队列",
+		Data:    delivery,
+	})
+}
+
+// parseWebhookID 解析路径中的WebhookID，失败时已写入错误响应
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的WebhookID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseDeliveryID 解析路径中的WebhookID和投递记录ID，失败时已写入错误响应
+func parseDeliveryID(c *gin.Context) (uint, uint, bool) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的投递记录ID",
+		})
+		return 0, 0, false
+	}
+	return id, uint(deliveryID), true
+}
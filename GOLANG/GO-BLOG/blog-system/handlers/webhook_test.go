@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-system/config"
+	"blog-system/events"
+	"blog-system/internal/testdb"
+	"blog-system/models"
+	"blog-system/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookReceiver 记录收到的投递请求并校验签名，按statuses依次返回状态码
+type webhookReceiver struct {
+	*httptest.Server
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header    http.Header
+	body      []byte
+	verifyErr error
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	r := &webhookReceiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, receivedWebhook{
+			header:    req.Header.Clone(),
+			body:      body,
+			verifyErr: webhook.Verify(r.secret, req.Header, body, time.Minute, time.Now()),
+		})
+		status := http.StatusOK
+		if n := len(r.requests); n <= len(r.statuses) {
+			status = r.statuses[n-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+// newTestDispatcher 创建立即重试的投递器
+func newTestDispatcher(crud *models.WebhookCRUD, maxAttempts, disableAfter int) *WebhookDispatcher {
+	return NewWebhookDispatcher(crud, &config.Config{Webhook: config.WebhookConfig{
+		TimeoutSeconds:       5,
+		Workers:              2,
+		MaxAttempts:          maxAttempts,
+		RetryBaseSeconds:     0,
+		RetryMaxMinutes:      1,
+		DisableAfterFailures: disableAfter,
+	}})
+}
+
+// createTestWebhook 创建指向receiver的Webhook，并让receiver使用其密钥校验签名
+func createTestWebhook(t *testing.T, crud *models.WebhookCRUD, receiver *webhookReceiver) *models.WebhookWithSecret {
+	t.Helper()
+	hook, err := crud.Create(&models.WebhookRequest{
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef-secret",
+		Events: []string{events.CommentCreated},
+	}, 1)
+	if err != nil {
+		t.Fatalf("创建Webhook失败: %v", err)
+	}
+	receiver.secret = hook.Secret
+	return hook
+}
+
+func TestWebhookDispatcherRetriesUntilSuccess(t *testing.T) {
+	crud := models.NewWebhookCRUD(testdb.New(t, models.AutoMigrate))
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	hook := createTestWebhook(t, crud, receiver)
+	dispatcher := newTestDispatcher(crud, 3, 0)
+
+	bus := events.NewBus(8)
+	SubscribeWebhooks(bus, crud, dispatcher)
+	bus.Publish(events.Event{Type: events.CommentCreated, TargetID: 7, Data: &models.Comment{ID: 7, Content: "hi", Status: models.CommentStatusApproved}})
+	bus.Publish(events.Event{Type: events.CommentCreated, TargetID: 8, Data: &models.Comment{ID: 8, Content: "spam", Status: models.CommentStatusSpam}})
+	bus.Close()
+
+	// 第一次返回500，按退避策略(此处为0)安排重试，下一轮投递成功
+	dispatcher.deliverDue()
+	dispatcher.deliverDue()
+
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("收到%d次请求, want 2(未通过审核的评论不投递)", len(requests))
+	}
+	for i, req := range requests {
+		if req.verifyErr != nil {
+			t.Fatalf("第%d次请求签名校验失败: %v", i+1, req.verifyErr)
+		}
+		if req.header.Get(webhook.HeaderEvent) != events.CommentCreated {
+			t.Fatalf("第%d次请求的事件 = %s", i+1, req.header.Get(webhook.HeaderEvent))
+		}
+	}
+	if requests[0].header.Get(webhook.HeaderDelivery) != requests[1].header.Get(webhook.HeaderDelivery) ||
+		string(requests[0].body) != string(requests[1].body) {
+		t.Fatal("重试应使用相同的投递ID和请求内容")
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(requests[0].body, &payload); err != nil || payload.ID == "" || payload.Event != events.CommentCreated {
+		t.Fatalf("请求内容 = %s, %v", requests[0].body, err)
+	}
+
+	deliveries, _, err := crud.ListDeliveries(hook.ID, &models.DeliveryQuery{})
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries() = %v, %v", deliveries, err)
+	}
+	if d := deliveries[0]; d.Status != models.DeliverySuccess || d.Attempts != 2 || d.ResponseStatus != http.StatusOK {
+		t.Fatalf("投递记录 = %+v", d)
+	}
+}
+
+func TestWebhookDispatcherDisablesFailingWebhook(t *testing.T) {
+	crud := models.NewWebhookCRUD(testdb.New(t, models.AutoMigrate))
+	receiver := newWebhookReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	hook := createTestWebhook(t, crud, receiver)
+	dispatcher := newTestDispatcher(crud, 1, 2)
+
+	for _, id := range []string{"evt-1", "evt-2"} {
+		if _, err := crud.Enqueue(events.CommentCreated, id, []byte(`{"id":"`+id+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+		dispatcher.deliverDue()
+	}
+
+	webhookAfter, err := crud.GetByID(hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhookAfter.Active || webhookAfter.ConsecutiveFailures != 2 || webhookAfter.DisabledAt == nil {
+		t.Fatalf("连续两次投递失败后应停用: %+v", webhookAfter)
+	}
+
+	// 停用后领取到的记录直接取消，不再发送请求
+	if _, err := crud.Enqueue(events.CommentCreated, "evt-3", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.deliverDue()
+	if n := len(receiver.received()); n != 2 {
+		t.Fatalf("收到%d次请求, want 2", n)
+	}
+}
+
+func TestReplayDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.New(t, models.AutoMigrate)
+	crud := models.NewWebhookCRUD(db)
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable)
+	hook := createTestWebhook(t, crud, receiver)
+	dispatcher := newTestDispatcher(crud, 1, 0)
+
+	if _, err := crud.Enqueue(events.CommentCreated, "evt-replay", []byte(`{"id":"evt-replay"}`)); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.deliverDue()
+	deliveries, _, _ := crud.ListDeliveries(hook.ID, &models.DeliveryQuery{})
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryFailed {
+		t.Fatalf("首次投递应失败: %+v", deliveries)
+	}
+	original := deliveries[0]
+
+	handler := NewWebhookHandler(crud, dispatcher, NewAuditor(models.NewAuditCRUD(db)))
+	r := gin.New()
+	r.POST("/webhooks/:id/deliveries/:delivery_id/replay", handler.ReplayDelivery)
+	replay := func(path string) (int, models.WebhookDelivery) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		var resp struct {
+			Data models.WebhookDelivery `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	code, created := replay("/webhooks/1/deliveries/1/replay")
+	if code != http.StatusAccepted || created.ReplayOf == nil || *created.ReplayOf != original.ID || created.Status != models.DeliveryPending {
+		t.Fatalf("ReplayDelivery = %d, %+v", code, created)
+	}
+	dispatcher.deliverDue()
+
+	requests := receiver.received()
+	if len(requests) != 2 || requests[1].verifyErr != nil {
+		t.Fatalf("重新投递的请求 = %d次, %v", len(requests), requests)
+	}
+	if string(requests[1].body) != string(requests[0].body) {
+		t.Fatal("重新投递应使用原记录的请求内容，接收方可按事件ID去重")
+	}
+	saved, err := crud.GetDelivery(hook.ID, created.ID)
+	if err != nil || saved.Status != models.DeliverySuccess {
+		t.Fatalf("重新投递的记录 = %+v, %v", saved, err)
+	}
+
+	if code, _ := replay("/webhooks/1/deliveries/999/replay"); code != http.StatusNotFound {
+		t.Fatalf("不存在的投递记录 = %d, want 404", code)
+	}
+	if code, _ := replay("/webhooks/1/deliveries/abc/replay"); code != http.StatusBadRequest {
+		t.Fatalf("无效的投递记录ID = %d, want 400", code)
+	}
+	inactive := false
+	if _, err := crud.Update(hook.ID, &models.WebhookRequest{URL: hook.URL, Events: hook.Events, Active: &inactive}); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := replay("/webhooks/1/deliveries/1/replay"); code != http.StatusConflict {
+		t.Fatalf("已停用的Webhook = %d, want 409", code)
+	}
+}
+
+func TestWebhookPayloadHidesPrivateUserFields(t *testing.T) {
+	crud := models.NewWebhookCRUD(testdb.New(t, models.AutoMigrate))
+	receiver := newWebhookReceiver(t)
+	createTestWebhook(t, crud, receiver)
+	dispatcher := newTestDispatcher(crud, 1, 0)
+
+	bus := events.NewBus(8)
+	SubscribeWebhooks(bus, crud, dispatcher)
+	bus.Publish(events.Event{Type: events.CommentCreated, TargetID: 7, Data: &models.Comment{
+		ID:        7,
+		Content:   "hi",
+		Status:    models.CommentStatusApproved,
+		IPAddress: "203.0.113.9",
+		UserID:    3,
+		User:      models.User{ID: 3, Username: "bob", Email: "bob@example.com", Role: models.RoleAdmin, StorageUsed: 1024},
+	}})
+	bus.Close()
+	dispatcher.deliverDue()
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("收到%d次请求, want 1", len(requests))
+	}
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(requests[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	user, ok := payload.Data["user"].(map[string]any)
+	if !ok || user["username"] != "bob" {
+		t.Fatalf("请求内容应包含评论者的公开信息: %s", requests[0].body)
+	}
+	for _, key := range []string{"email", "role", "is_active", "storage_used", "email_verified_at"} {
+		if _, ok := user[key]; ok {
+			t.Fatalf("评论者信息不应包含%s: %s", key, requests[0].body)
+		}
+	}
+	if strings.Contains(string(requests[0].body), "203.0.113.9") {
+		t.Fatalf("请求内容不应包含评论者IP: %s", requests[0].body)
+	}
+}
@@ -0,0 +1,36 @@
+// Package testdb 提供测试使用的内存SQLite数据库
+package testdb
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// New 创建内存SQLite数据库并执行migrate完成迁移，每个测试使用独立的数据库，测试结束时关闭。
+// migrate通常为models.AutoMigrate，由调用方传入以便models包自身的测试也能使用
+func New(t testing.TB, migrate func(*gorm.DB) error) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	// 内存数据库在最后一个连接关闭时销毁，使用单个连接也避免了SQLite的锁冲突
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+	return db
+}
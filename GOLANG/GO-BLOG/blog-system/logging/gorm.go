@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger 将GORM日志输出到slog：执行失败的SQL记录为error，慢查询记录为warn，其余SQL记录为debug。
+// 通过db.WithContext传入携带请求ID的context时，SQL日志会附带请求ID。
+// SQL中的参数以占位符输出，避免密码哈希和令牌等数据写入日志
+type GormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger 创建GORM日志记录器，slowThreshold为0时不记录慢查询
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: logger, level: gormlogger.Info, slowThreshold: slowThreshold}
+}
+
+// LogMode 返回使用指定级别的副本，db.Debug()等调用会用到
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace 记录一条SQL的执行结果，记录不存在不视为错误
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	var level slog.Level
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		level, msg = slog.LevelError, "SQL执行失败"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "慢查询"
+	case l.level >= gormlogger.Info:
+		level, msg = slog.LevelDebug, "SQL"
+	default:
+		return
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("elapsed_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter 实现gorm的ParamsFilter接口，输出的SQL不包含参数值
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
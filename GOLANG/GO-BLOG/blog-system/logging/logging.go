@@ -0,0 +1,83 @@
+// Package logging 基于log/slog的结构化日志：按配置输出JSON或文本格式，并为携带请求ID的上下文自动附加request_id字段
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"blog-system/config"
+)
+
+// requestIDKey 请求ID在context中的键
+type requestIDKey struct{}
+
+// WithRequestID 返回携带请求ID的context，使用该context输出的日志和执行的SQL都会附带请求ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 获取context中的请求ID，不存在时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ParseLevel 解析日志级别，无法识别时使用info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// New 创建输出到w的日志记录器，Format为text时输出key=value格式，否则输出JSON
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+	var handler slog.Handler
+	if strings.ToLower(cfg.Format) == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Setup 创建输出到标准输出的日志记录器并设为默认记录器。
+// 标准库log的输出也会转为info级别的结构化日志，旧代码无需修改即可统一格式
+func Setup(cfg config.LogConfig) *slog.Logger {
+	logger := New(cfg, os.Stdout)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// contextHandler 从context中读取请求ID附加到每条日志
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
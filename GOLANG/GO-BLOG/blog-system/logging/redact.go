@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted 替换敏感字段值的文本
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名(不区分大小写)包含这些片段时视为敏感字段，值不写入日志
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie", "api_key", "apikey"}
+
+// jsonPairPattern 匹配JSON中的"键":值，用于无法完整解析(例如被截断)的请求体；值的结尾引号可以缺失
+var jsonPairPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+
+// IsSensitive 判断字段名是否为敏感字段
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactQuery 隐藏查询字符串中敏感参数的值，例如实时推送接口的access_token
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted
+	}
+	return redactValues(values)
+}
+
+// TextBody 判断请求体是否为可以写入日志的JSON或表单，文件上传等其他类型不读取内容
+func TextBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return isJSON(mediaType) || mediaType == "application/x-www-form-urlencoded"
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// RedactBody 按Content-Type隐藏请求体中的敏感字段，只输出JSON和表单请求体，其他类型只输出类型和长度
+func RedactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case isJSON(mediaType):
+		return redactJSON(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return Redacted
+		}
+		return redactValues(values)
+	default:
+		return fmt.Sprintf("[%s %d字节]", mediaType, len(body))
+	}
+}
+
+// redactValues 隐藏敏感参数后重新编码，替换文本保持原样以便阅读
+func redactValues(values url.Values) string {
+	for key := range values {
+		if IsSensitive(key) {
+			values[key] = []string{Redacted}
+		}
+	}
+	return strings.ReplaceAll(values.Encode(), url.QueryEscape(Redacted), Redacted)
+}
+
+// redactJSON 解析JSON后逐层隐藏敏感字段；解析失败时按文本匹配"键":值进行替换
+func redactJSON(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err == nil {
+		var out bytes.Buffer
+		encoder := json.NewEncoder(&out)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(redactValue(data)); err == nil {
+			return strings.TrimSuffix(out.String(), "\n")
+		}
+	}
+	return jsonPairPattern.ReplaceAllStringFunc(string(body), func(pair string) string {
+		m := jsonPairPattern.FindStringSubmatch(pair)
+		if !IsSensitive(m[1]) {
+			return pair
+		}
+		return `"` + m[1] + `":"` + Redacted + `"`
+	})
+}
+
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, item := range val {
+			if IsSensitive(key) {
+				val[key] = Redacted
+			} else {
+				val[key] = redactValue(item)
+			}
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+	}
+	return v
+}
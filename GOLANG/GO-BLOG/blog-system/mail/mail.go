@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"errors"
+
+	"blog-system/config"
+)
+
+// Message 邮件内容，Text和HTML至少提供一个
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New 根据配置创建邮件发送实现
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "outbox":
+		return NewOutbox(cfg.OutboxDir)
+	case "smtp":
+		return NewSMTP(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		})
+	default:
+		return nil, errors.New("不支持的邮件驱动: " + cfg.Driver)
+	}
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outbox 开发和测试用的邮件实现：不实际发送，邮件保存在内存中，
+// 指定目录时同时写入.eml文件，可以用邮件客户端直接打开
+type Outbox struct {
+	dir      string
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewOutbox 创建Outbox，dir为空时只保存在内存中
+func NewOutbox(dir string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建邮件输出目录失败: %w", err)
+		}
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+func (o *Outbox) Send(ctx context.Context, msg *Message) error {
+	o.mu.Lock()
+	o.messages = append(o.messages, *msg)
+	o.mu.Unlock()
+
+	if o.dir == "" {
+		return nil
+	}
+	data, err := build("outbox@localhost", msg)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(o.dir, name), data, 0o644)
+}
+
+// Messages 返回已发送的邮件副本
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// Last 返回最近一封发送给to的邮件
+func (o *Outbox) Last(to string) (*Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].To == to {
+			msg := o.messages[i]
+			return &msg, true
+		}
+	}
+	return nil, false
+}
+
+// sanitize 将收件人地址转换为可用作文件名的字符串
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
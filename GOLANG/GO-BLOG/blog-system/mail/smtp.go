@@ -0,0 +1,210 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPOptions SMTP服务器连接参数
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // 发件人，如"Blog System <noreply@example.com>"
+}
+
+// SMTP 通过SMTP服务器发送邮件。465端口使用隐式TLS，其他端口在服务器支持时使用STARTTLS
+type SMTP struct {
+	opts SMTPOptions
+	from *mail.Address
+}
+
+// NewSMTP 创建SMTP邮件发送实现
+func NewSMTP(opts SMTPOptions) (*SMTP, error) {
+	if opts.Host == "" {
+		return nil, errors.New("SMTP服务器地址不能为空")
+	}
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, errors.New("无效的发件人地址: " + opts.From)
+	}
+	return &SMTP{opts: opts, from: from}, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return errors.New("无效的收件人地址: " + msg.To)
+	}
+	data, err := build(s.from.String(), msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.opts.Username != "" {
+		auth := smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 连接SMTP服务器并完成TLS握手
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	tlsConfig := &tls.Config{ServerName: s.opts.Host}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if s.opts.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	if s.opts.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("SMTP STARTTLS失败: %w", err)
+			}
+		}
+	}
+	return client, nil
+}
+
+// build 生成MIME格式的邮件，同时提供纯文本和HTML时使用multipart/alternative
+func build(from string, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", msg.To)
+	header.Set("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(from))
+	header.Set("MIME-Version", "1.0")
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, body := "text/plain; charset=UTF-8", msg.Text
+		if msg.HTML != "" {
+			contentType, body = "text/html; charset=UTF-8", msg.HTML
+		}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		return buf.Bytes(), writeQP(&buf, body)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQP(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID 生成邮件的Message-ID，域名取自发件人地址
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
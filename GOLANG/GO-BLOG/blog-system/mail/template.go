@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 邮件模板名称
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+// 支持的语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// TemplateData 模板数据
+type TemplateData struct {
+	AppName   string
+	Username  string
+	Link      string
+	ExpiresIn string
+}
+
+// Render 使用指定语言的模板生成邮件。每个模板文件定义subject、text和html三部分，
+// html部分使用html/template渲染以转义用户输入
+func Render(name, lang, to string, data TemplateData) (*Message, error) {
+	if lang != LangEN {
+		lang = LangZH
+	}
+	file := fmt.Sprintf("templates/%s.%s.tmpl", name, lang)
+
+	text, err := template.ParseFS(templateFS, file)
+	if err != nil {
+		return nil, fmt.Errorf("邮件模板不存在: %s", file)
+	}
+	html, err := htmltemplate.ParseFS(templateFS, file)
+	if err != nil {
+		return nil, fmt.Errorf("邮件模板不存在: %s", file)
+	}
+
+	var subject, body, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return nil, err
+	}
+	if err := html.ExecuteTemplate(&htmlBody, "html", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    body.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// Lang 根据Accept-Language请求头选择邮件语言，首选英文时使用英文，否则使用中文
+func Lang(acceptLanguage string) string {
+	first := strings.TrimSpace(strings.Split(acceptLanguage, ",")[0])
+	if strings.HasPrefix(strings.ToLower(first), "en") {
+		return LangEN
+	}
+	return LangZH
+}
+
+// FormatDuration 将有效期格式化为指定语言的文字，整小时按小时显示，否则按分钟显示
+func FormatDuration(d time.Duration, lang string) string {
+	value, unit := int(d.Minutes()), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		value, unit = int(d.Hours()), "hour"
+	}
+
+	if lang == LangEN {
+		if value == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", value, unit)
+	}
+	if unit == "hour" {
+		return fmt.Sprintf("%d小时", value)
+	}
+	return fmt.Sprintf("%d分钟", value)
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
 
 	"blog-system/config"
 	"blog-system/database"
 	"blog-system/docs"
+	"blog-system/events"
 	"blog-system/handlers"
+	"blog-system/logging"
+	"blog-system/metrics"
 	"blog-system/models"
+	"blog-system/scheduler"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -45,28 +52,93 @@ func main() {
 	// 加载配置
 	cfg := config.Load()
 
+	// 初始化结构化日志，标准库log的输出也会使用相同的格式
+	logging.Setup(cfg.Log)
+
 	// 初始化数据库
 	database.InitDB(cfg)
 	defer database.CloseDB()
 
 	// 自动迁移数据库表结构
 	if err := models.AutoMigrate(database.GetDB()); err != nil {
-		log.Fatal("数据库迁移失败:", err)
+		slog.Error("数据库迁移失败", "error", err)
+		os.Exit(1)
+	}
+
+	// 初始化第一个管理员
+	if cfg.Admin.Username != "" {
+		admin, created, err := models.NewUserCRUD(database.GetDB()).BootstrapAdmin(cfg.Admin.Username, cfg.Admin.Password, cfg.Admin.Email)
+		if err != nil {
+			slog.Error("初始化管理员失败", "error", err)
+			os.Exit(1)
+		}
+		if created {
+			slog.Info("已初始化管理员账户", "username", admin.Username)
+		}
 	}
 
+	// 创建事件总线，请求处理和后台任务产生的业务事件由订阅者在后台处理
+	bus := events.NewBus(cfg.Notification.QueueSize)
+	defer bus.Close()
+
+	// 启动后台任务
+	postCRUD := models.NewPostCRUD(database.GetDB())
+	tokenCRUD := models.NewTokenCRUD(database.GetDB(), cfg.GetRefreshTokenExpireTime())
+	trashCRUD := models.NewTrashCRUD(database.GetDB())
+	userTokenCRUD := models.NewUserTokenCRUD(database.GetDB(), cfg.JWT.Secret)
+	notificationCRUD := models.NewNotificationCRUD(database.GetDB())
+	webhookCRUD := models.NewWebhookCRUD(database.GetDB())
+	jobs := scheduler.New()
+	jobs.Every(time.Duration(cfg.Scheduler.PublishIntervalSeconds)*time.Second, "定时发布文章", func() error {
+		published, err := postCRUD.PublishDue(time.Now())
+		if len(published) > 0 {
+			slog.Info("已定时发布文章", "count", len(published))
+			metrics.PostsPublished.Add(float64(len(published)))
+		}
+		for i := range published {
+			bus.Publish(events.Event{Type: events.PostPublished, ActorID: published[i].UserID, TargetID: published[i].ID, Data: &published[i]})
+		}
+		return err
+	})
+	jobs.Every(time.Hour, "清理过期令牌", tokenCRUD.PurgeExpired)
+	jobs.Every(time.Hour, "清理过期邮件令牌", userTokenCRUD.PurgeExpired)
+	jobs.Every(time.Hour, "清理回收站", func() error {
+		count, err := trashCRUD.Purge(time.Now().Add(-cfg.GetTrashRetention()))
+		if count > 0 {
+			slog.Info("已永久删除回收站内容", "count", count)
+		}
+		return err
+	})
+	jobs.Every(time.Hour, "清理已读通知", func() error {
+		_, err := notificationCRUD.Purge(time.Now().Add(-cfg.GetNotificationRetention()))
+		return err
+	})
+	jobs.Every(time.Hour, "清理Webhook投递记录", func() error {
+		_, err := webhookCRUD.PurgeDeliveries(time.Now().Add(-cfg.GetWebhookDeliveryRetention()))
+		return err
+	})
+	jobs.Start()
+	defer jobs.Stop()
+
 	// 设置路由
-	r := handlers.SetupRoutes(database.GetDB(), cfg)
+	r := handlers.SetupRoutes(database.GetDB(), cfg, bus)
 
 	// 添加Swagger路由
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// 启动服务器
-	log.Printf("🚀 服务器启动在 %s", cfg.GetServerAddr())
-	log.Printf("📚 Swagger文档地址: http://%s/swagger/index.html", cfg.GetServerAddr())
-	log.Printf("🔧 运行模式: %s", cfg.App.Env)
-	log.Printf("📊 日志级别: %s", cfg.Log.Level)
-	log.Printf("🌐 API基础路径: /api")
-	log.Printf("🔐 JWT过期时间: %d小时", cfg.JWT.ExpireHours)
-	log.Printf("💾 数据库: %s@%s:%d/%s", cfg.Database.Username, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
-	log.Fatal(r.Run(cfg.GetServerAddr()))
+	slog.Info("服务器启动",
+		"addr", cfg.GetServerAddr(),
+		"swagger", "http://"+cfg.GetServerAddr()+"/swagger/index.html",
+		"env", cfg.App.Env,
+		"log_level", cfg.Log.Level,
+		"api_base", "/api",
+		"access_token_minutes", cfg.JWT.AccessExpireMinutes,
+		"refresh_token_hours", cfg.JWT.RefreshExpireHours,
+		"database", fmt.Sprintf("%s@%s:%d/%s", cfg.Database.Username, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name),
+	)
+	if err := r.Run(cfg.GetServerAddr()); err != nil {
+		slog.Error("服务器异常退出", "error", err)
+		os.Exit(1)
+	}
 }
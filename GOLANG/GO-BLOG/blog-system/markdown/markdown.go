@@ -0,0 +1,120 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"blog-system/slug"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// SummaryLength 自动生成摘要的最大字符数
+const SummaryLength = 200
+
+var (
+	// postRenderer 文章渲染器：支持GFM表格、删除线、任务列表、自动链接和脚注，
+	// 代码块输出language-xxx样式类，供前端高亮库使用；原始HTML不输出
+	postRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	// commentRenderer 评论渲染器：只支持基础语法，标题、图片、表格等由评论白名单过滤
+	commentRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+	)
+
+	postPolicy    = newPostPolicy()
+	commentPolicy = newCommentPolicy()
+	textPolicy    = bluemonday.StrictPolicy()
+
+	whitespace = regexp.MustCompile(`\s+`)
+	blockEnd   = regexp.MustCompile(`</(p|li|h[1-6]|td|th|blockquote|pre)>|<br\s*/?>`)
+	footnotes  = regexp.MustCompile(`(?s)<sup[^>]*>.*?</sup>|<div class="footnotes".*?</div>`)
+)
+
+// newPostPolicy 文章HTML白名单：在UGC策略基础上允许代码高亮类、脚注和任务列表
+func newPostPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote-(ref|backref)$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w:.-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// newCommentPolicy 评论HTML白名单：只允许段落、强调、链接、代码、引用和列表
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// headingIDs 使用与文章别名相同的规则生成标题锚点，中文标题转写为拼音，重复时追加序号
+type headingIDs struct {
+	used map[string]bool
+}
+
+func (h *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	base := slug.Make(string(value))
+	id := base
+	for n := 1; h.used[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	h.used[id] = true
+	return []byte(id)
+}
+
+func (h *headingIDs) Put(value []byte) {
+	h.used[string(value)] = true
+}
+
+// RenderPost 将文章Markdown渲染为经过清洗的HTML
+func RenderPost(src string) string {
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{used: make(map[string]bool)}))
+	return render(postRenderer, postPolicy, src, parser.WithContext(ctx))
+}
+
+// RenderComment 将评论Markdown渲染为经过清洗的HTML，只保留受限的语法子集
+func RenderComment(src string) string {
+	return render(commentRenderer, commentPolicy, src)
+}
+
+func render(md goldmark.Markdown, policy *bluemonday.Policy, src string, opts ...parser.ParseOption) string {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf, opts...); err != nil {
+		// 渲染失败时退化为转义后的纯文本
+		return "<p>" + html.EscapeString(src) + "</p>"
+	}
+	return policy.Sanitize(buf.String())
+}
+
+// Summary 从渲染后的HTML中提取纯文本摘要(不含脚注)，超过SummaryLength个字符时截断并添加省略号
+func Summary(renderedHTML string) string {
+	stripped := blockEnd.ReplaceAllString(footnotes.ReplaceAllString(renderedHTML, ""), "$0 ")
+	text := html.UnescapeString(textPolicy.Sanitize(stripped))
+	text = strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+	if utf8.RuneCountInString(text) <= SummaryLength {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:SummaryLength])) + "…"
+}
@@ -0,0 +1,100 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels 允许解码的最大像素数，防止小体积的超大尺寸图片耗尽内存
+const MaxPixels = 40_000_000
+
+// extensions 支持的文件类型及其扩展名
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Detect 根据文件内容识别MIME类型，不信任客户端声明的类型和文件扩展名
+func Detect(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Extension 返回MIME类型对应的文件扩展名，不支持的类型返回空字符串
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// IsImage 判断是否为可生成缩略图的图片类型
+func IsImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Image 图片处理结果
+type Image struct {
+	Width         int
+	Height        int
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// Process 读取图片尺寸并生成缩略图，缩略图的长边不超过maxSize，原图更小时不放大。
+// JPEG缩略图保持JPEG格式，其他格式输出PNG以保留透明度；GIF只取第一帧。
+func Process(data []byte, maxSize int) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("无法识别的图片")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, errors.New("图片尺寸超过限制")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("图片解码失败")
+	}
+
+	width, height := thumbnailSize(cfg.Width, cfg.Height, maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	result := &Image{Width: cfg.Width, Height: cfg.Height}
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		result.ThumbnailType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, dst)
+		result.ThumbnailType = "image/png"
+	}
+	if err != nil {
+		return nil, errors.New("缩略图生成失败")
+	}
+	result.Thumbnail = buf.Bytes()
+	return result, nil
+}
+
+// thumbnailSize 按比例计算缩略图尺寸
+func thumbnailSize(width, height, maxSize int) (int, int) {
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+	return max(1, width*maxSize/height), maxSize
+}
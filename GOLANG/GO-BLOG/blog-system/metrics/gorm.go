@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// gormStartKey 保存SQL开始执行时间的键
+const gormStartKey = "metrics:start"
+
+// GormPlugin 通过GORM回调统计每类操作的执行时间和错误数，使用db.Use注册
+type GormPlugin struct {
+	durations *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+}
+
+// NewGormPlugin 创建GORM插件并注册指标，指标名以namespace为前缀
+func NewGormPlugin(registerer prometheus.Registerer, namespace string) *GormPlugin {
+	p := &GormPlugin{
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "SQL执行时间(秒)",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "table"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "执行失败的SQL数量，不包括记录不存在",
+		}, []string{"operation", "table"}),
+	}
+	registerer.MustRegister(p.durations, p.errors)
+	return p
+}
+
+// Name 实现gorm.Plugin接口
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize 实现gorm.Plugin接口，在每类操作的全部回调前后记录时间
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("*").Register, callback.Create().After("*").Register},
+		{"query", callback.Query().Before("*").Register, callback.Query().After("*").Register},
+		{"update", callback.Update().Before("*").Register, callback.Update().After("*").Register},
+		{"delete", callback.Delete().Before("*").Register, callback.Delete().After("*").Register},
+		{"row", callback.Row().Before("*").Register, callback.Row().After("*").Register},
+		{"raw", callback.Raw().Before("*").Register, callback.Raw().After("*").Register},
+	}
+	for _, proc := range processors {
+		if err := proc.before("metrics:before_"+proc.operation, p.start); err != nil {
+			return err
+		}
+		if err := proc.after("metrics:after_"+proc.operation, p.finish(proc.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) start(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func (p *GormPlugin) finish(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		p.durations.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.errors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
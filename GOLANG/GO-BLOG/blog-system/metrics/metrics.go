@@ -0,0 +1,48 @@
+// Package metrics 基于Prometheus官方客户端提供监控指标：HTTP请求、SQL执行、连接池状态以及业务计数
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Namespace 监控指标名的前缀
+const Namespace = "blog"
+
+// 业务指标，在注册、发文、发布和评论成功时同步计数，不经过事件总线
+var (
+	UserRegistrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "user_registrations_total",
+		Help:      "注册用户数",
+	})
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "posts_created_total",
+		Help:      "创建的文章数",
+	})
+	PostsPublished = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "posts_published_total",
+		Help:      "发布的文章数，包括定时发布",
+	})
+	CommentsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "comments_created_total",
+		Help:      "新评论数，按审核状态区分",
+	}, []string{"status"})
+)
+
+// NewRegistry 创建指标注册表，包含Go运行时、进程和业务指标
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		UserRegistrations,
+		PostsCreated,
+		PostsPublished,
+		CommentsCreated,
+	)
+	return registry
+}
@@ -1,9 +1,6 @@
 package middleware
 
 import (
-	"log"
-	"time"
-
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,7 +9,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -22,58 +20,3 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// LoggerMiddleware 日志中间件
-func LoggerMiddleware() gin.HandlerFunc {
-	return gin.Logger()
-}
-
-// DebugLoggerMiddleware 详细的debug日志中间件
-func DebugLoggerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 开始时间
-		start := time.Now()
-		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
-
-		// 处理请求
-		c.Next()
-
-		// 结束时间
-		end := time.Now()
-		latency := end.Sub(start)
-
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-		statusCode := c.Writer.Status()
-		bodySize := c.Writer.Size()
-
-		if raw != "" {
-			path = path + "?" + raw
-		}
-
-		// 详细的debug日志
-		log.Printf("[DEBUG] %s | %3d | %13v | %15s | %-7s %s | BodySize: %d",
-			end.Format("2006/01/02 - 15:04:05"),
-			statusCode,
-			latency,
-			clientIP,
-			method,
-			path,
-			bodySize,
-		)
-
-		// 如果是POST/PUT请求，记录请求体（仅限debug模式）
-		if method == "POST" || method == "PUT" {
-			log.Printf("[DEBUG] Request Body: %s", c.Request.Body)
-		}
-
-		// 记录响应头
-		log.Printf("[DEBUG] Response Headers: %v", c.Writer.Header())
-	}
-}
-
-// RecoveryMiddleware 恢复中间件
-func RecoveryMiddleware() gin.HandlerFunc {
-	return gin.Recovery()
-}
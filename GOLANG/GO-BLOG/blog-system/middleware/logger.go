@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"blog-system/logging"
+	"blog-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoggerMiddleware 日志中间件，每个请求输出一条结构化日志。
+// 5xx响应记录为error，4xx响应记录为warn，查询字符串中的令牌等敏感参数会被隐藏
+func LoggerMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return requestLogger(logger, 0, false)
+}
+
+// DebugLoggerMiddleware 详细的debug日志中间件，在LoggerMiddleware的基础上记录请求体和响应头。
+// 只读取JSON和表单请求体的前bodyLimit个字节，密码和令牌等字段的值会被隐藏
+func DebugLoggerMiddleware(logger *slog.Logger, bodyLimit int) gin.HandlerFunc {
+	return requestLogger(logger, bodyLimit, true)
+}
+
+func requestLogger(logger *slog.Logger, bodyLimit int, verbose bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		query := logging.RedactQuery(c.Request.URL.RawQuery)
+
+		var body string
+		if verbose {
+			body = captureBody(c, bodyLimit)
+		}
+
+		// 处理请求
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		ctx := c.Request.Context()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		if verbose {
+			if body != "" {
+				attrs = append(attrs, slog.String("body", body))
+			}
+			attrs = append(attrs, slog.Any("response_headers", c.Writer.Header()))
+		}
+		logger.LogAttrs(ctx, level, "HTTP请求", attrs...)
+	}
+}
+
+// captureBody 读取请求体的前limit个字节用于记录日志，并将读取的内容放回请求体，不影响后续处理。
+// 文件上传等非文本请求体不读取，只记录类型和长度
+func captureBody(c *gin.Context, limit int) string {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || limit <= 0 {
+		return ""
+	}
+	contentType := c.ContentType()
+	if !logging.TextBody(contentType) {
+		switch {
+		case c.Request.ContentLength <= 0:
+			return ""
+		case contentType == "":
+			return fmt.Sprintf("[%d字节]", c.Request.ContentLength)
+		}
+		return fmt.Sprintf("[%s %d字节]", contentType, c.Request.ContentLength)
+	}
+
+	original := c.Request.Body
+	buf, err := io.ReadAll(io.LimitReader(original, int64(limit)+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), original), original}
+	if err != nil {
+		return ""
+	}
+
+	truncated := len(buf) > limit
+	if truncated {
+		buf = buf[:limit]
+	}
+	body := logging.RedactBody(contentType, buf)
+	if truncated {
+		body += "…(已截断)"
+	}
+	return body
+}
+
+// RecoveryMiddleware 恢复中间件，处理请求时发生panic时记录错误和调用栈并返回500
+func RecoveryMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		logger.ErrorContext(c.Request.Context(), "请求处理异常",
+			"error", fmt.Sprint(err),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Response{
+			Code:    500,
+			Message: "服务器内部错误",
+		})
+	})
+}
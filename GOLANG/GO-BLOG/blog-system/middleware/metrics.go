@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute 没有匹配到路由的请求使用的路由标签，避免任意路径产生大量标签值
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics 统计HTTP请求数、处理时间和正在处理的请求数，按路由模板而不是实际路径区分
+type HTTPMetrics struct {
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	inFlight  prometheus.Gauge
+}
+
+// NewHTTPMetrics 创建并注册HTTP请求指标，指标名以namespace为前缀
+func NewHTTPMetrics(registerer prometheus.Registerer, namespace string) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP请求数",
+		}, []string{"method", "route", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP请求处理时间(秒)",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "正在处理的HTTP请求数",
+		}),
+	}
+	registerer.MustRegister(m.requests, m.durations, m.inFlight)
+	return m
+}
+
+// Handler 返回记录请求指标的中间件
+func (m *HTTPMetrics) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.durations.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
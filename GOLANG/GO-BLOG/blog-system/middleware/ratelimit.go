@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog-system/config"
+	"blog-system/models"
+	"blog-system/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter 按IP或用户限制请求频率
+type RateLimiter struct {
+	store   ratelimit.Store
+	enabled bool
+}
+
+// NewRateLimiter 创建频率限制器，enabled为false时所有策略都不生效
+func NewRateLimiter(store ratelimit.Store, enabled bool) *RateLimiter {
+	return &RateLimiter{store: store, enabled: enabled}
+}
+
+// Limit 返回按策略限制请求频率的中间件。在AuthMiddleware之后使用时按用户计数，否则按客户端IP计数；
+// name区分不同策略的计数，响应中包含RateLimit-*头，超出限制时返回429和Retry-After头
+func (l *RateLimiter) Limit(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+	if !l.enabled || policy.Requests <= 0 || policy.Period <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limit := ratelimit.Limit{Burst: policy.Requests, Period: policy.Period}
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Requests, int(policy.Period.Seconds()))
+
+	return func(c *gin.Context) {
+		result := l.store.Take(name+":"+ClientKey(c), limit, time.Now())
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			TooManyRequests(c, result.RetryAfter, "请求过于频繁，请稍后再试")
+			return
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests 返回429响应并设置Retry-After头
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, models.Response{
+		Code:    429,
+		Message: message,
+	})
+}
+
+// ClientKey 已认证的请求使用用户ID，否则使用客户端IP
+func ClientKey(c *gin.Context) string {
+	if userID, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds 向上取整为秒，至少为1秒
+func ceilSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"blog-system/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey 请求ID在gin上下文中的键
+const requestIDKey = "request_id"
+
+// RequestIDMiddleware 为每个请求分配请求ID并写入响应头和请求的context。
+// 客户端或反向代理传入的合法请求ID会被沿用，便于跨服务追踪同一个请求；
+// 使用c.Request.Context()输出的日志和执行的SQL会附带请求ID
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的请求ID，未经过RequestIDMiddleware时返回空字符串
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// validRequestID 只接受不超过64个字符的字母、数字和"-_."，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,376 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 审计操作
+const (
+	AuditCreate    = "create"
+	AuditUpdate    = "update"
+	AuditDelete    = "delete"
+	AuditRestore   = "restore"
+	AuditPublish   = "publish"
+	AuditUnpublish = "unpublish"
+	AuditPassword  = "password" // 修改或重置密码，密码本身不记录
+)
+
+// 审计对象类型
+const (
+	AuditEntityPost     = "post"
+	AuditEntityComment  = "comment"
+	AuditEntityUser     = "user"
+	AuditEntityWebhook  = "webhook"
+	AuditEntityTag      = "tag"
+	AuditEntityCategory = "category"
+	AuditEntityMedia    = "media"
+)
+
+// auditEntities 审计对象类型对应的模型，用于读取修改前后的快照
+var auditEntities = map[string]reflect.Type{
+	AuditEntityPost:     reflect.TypeFor[Post](),
+	AuditEntityComment:  reflect.TypeFor[Comment](),
+	AuditEntityUser:     reflect.TypeFor[User](),
+	AuditEntityWebhook:  reflect.TypeFor[Webhook](),
+	AuditEntityTag:      reflect.TypeFor[Tag](),
+	AuditEntityCategory: reflect.TypeFor[Category](),
+	AuditEntityMedia:    reflect.TypeFor[Media](),
+}
+
+// auditIgnoredFields 不记录的字段：自动维护的时间戳、派生内容和统计数据
+var auditIgnoredFields = map[string]bool{
+	"updated_at":   true,
+	"content_html": true,
+	"view_count":   true,
+	"like_count":   true,
+	"liked":        true,
+	"reactions":    true,
+	"my_reactions": true,
+	"post_count":   true,
+	"storage_used": true,
+}
+
+// genesisHash 哈希链第一条记录的前一条哈希
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// AuditLog 审计日志，只追加不修改。每条记录的哈希包含前一条记录的哈希，
+// 修改或删除任意一条记录都会使之后的哈希链校验失败
+type AuditLog struct {
+	ID         uint            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Seq        uint64          `gorm:"not null;uniqueIndex;comment:哈希链序号" json:"seq"`
+	ActorID    uint            `gorm:"not null;index;comment:操作人ID" json:"actor_id"`
+	ActorName  string          `gorm:"size:50;comment:操作人用户名" json:"actor_name"`
+	IP         string          `gorm:"size:45;comment:客户端IP" json:"ip"`
+	RequestID  string          `gorm:"size:64;index;comment:请求ID" json:"request_id"`
+	Action     string          `gorm:"not null;size:20;index;comment:操作" json:"action"`
+	EntityType string          `gorm:"not null;size:20;index:idx_audit_entity,priority:1;comment:对象类型" json:"entity_type"`
+	EntityID   uint            `gorm:"not null;index:idx_audit_entity,priority:2;comment:对象ID" json:"entity_id"`
+	Before     json.RawMessage `gorm:"type:longtext;comment:修改前的字段" json:"before" swaggertype:"object"`
+	After      json.RawMessage `gorm:"type:longtext;comment:修改后的字段" json:"after" swaggertype:"object"`
+	CreatedAt  time.Time       `gorm:"not null;index;comment:记录时间" json:"created_at"`
+	PrevHash   string          `gorm:"not null;size:64;comment:前一条记录的哈希" json:"prev_hash"`
+	Hash       string          `gorm:"not null;size:64;comment:记录哈希" json:"hash"`
+}
+
+// computeHash 计算记录的哈希：对前一条哈希和记录内容的JSON数组做SHA-256，时间精确到毫秒以兼容数据库的时间精度
+func (a *AuditLog) computeHash() string {
+	content, _ := json.Marshal([]any{
+		a.PrevHash, a.Seq, a.CreatedAt.UnixMilli(), a.ActorID, a.ActorName, a.IP, a.RequestID,
+		a.Action, a.EntityType, a.EntityID, string(a.Before), string(a.After),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChain 哈希链的链尾，只有一行。写入审计日志时锁定该行，保证多实例并发写入时序号和哈希链连续
+type AuditChain struct {
+	ID   uint   `gorm:"primaryKey"`
+	Seq  uint64 `gorm:"not null;comment:最后一条记录的序号"`
+	Hash string `gorm:"not null;size:64;comment:最后一条记录的哈希"`
+}
+
+// AuditEntry 待记录的审计内容，before和after为对象修改前后的快照
+type AuditEntry struct {
+	ActorID    uint
+	IP         string
+	RequestID  string
+	Action     string
+	EntityType string
+	EntityID   uint
+	Before     map[string]any
+	After      map[string]any
+}
+
+// AuditQuery 审计日志查询参数
+type AuditQuery struct {
+	PageQuery
+	ActorID    uint   `form:"actor_id"`
+	Action     string `form:"action"`
+	EntityType string `form:"entity_type"`
+	EntityID   uint   `form:"entity_id"`
+	RequestID  string `form:"request_id"`
+	From       string `form:"from"`
+	To         string `form:"to"`
+}
+
+// AuditVerification 哈希链校验结果
+type AuditVerification struct {
+	Valid     bool   `json:"valid"`
+	Checked   int64  `json:"checked"`
+	BrokenSeq uint64 `json:"broken_seq,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AuditCRUD 审计日志操作
+type AuditCRUD struct {
+	db *gorm.DB
+}
+
+// NewAuditCRUD 创建审计日志CRUD实例
+func NewAuditCRUD(db *gorm.DB) *AuditCRUD {
+	return &AuditCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (a *AuditCRUD) WithContext(ctx context.Context) *AuditCRUD {
+	copied := *a
+	copied.db = a.db.WithContext(ctx)
+	return &copied
+}
+
+// Snapshot 读取对象当前的字段值(包括已移入回收站的对象)，对象不存在时返回nil。
+// 按JSON输出的字段记录，不输出的字段(如密码)不会出现在审计日志中
+func (a *AuditCRUD) Snapshot(entityType string, id uint) map[string]any {
+	typ, ok := auditEntities[entityType]
+	if !ok {
+		return nil
+	}
+	model := reflect.New(typ).Interface()
+	if err := a.db.Unscoped().First(model, id).Error; err != nil {
+		return nil
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	// 只保留对象自身的字段，关联对象由各自的审计记录跟踪
+	for key, value := range fields {
+		if auditIgnoredFields[key] || isNestedObject(value) {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+// isNestedObject 判断JSON值是否为对象或对象数组
+func isNestedObject(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return true
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(map[string]any); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Record 追加一条审计日志。修改前后都有快照时只记录发生变化的字段
+func (a *AuditCRUD) Record(entry *AuditEntry) (*AuditLog, error) {
+	before, after := entry.Before, entry.After
+	if before != nil && after != nil {
+		before, after = diffFields(before, after)
+	}
+
+	log := AuditLog{
+		ActorID:    entry.ActorID,
+		IP:         entry.IP,
+		RequestID:  entry.RequestID,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		CreatedAt:  time.Now().Truncate(time.Millisecond),
+	}
+	var err error
+	if log.Before, err = json.Marshal(before); err != nil {
+		return nil, err
+	}
+	if log.After, err = json.Marshal(after); err != nil {
+		return nil, err
+	}
+	if entry.ActorID != 0 {
+		var actor User
+		if err := a.db.Unscoped().Select("username").First(&actor, entry.ActorID).Error; err == nil {
+			log.ActorName = actor.Username
+		}
+	}
+
+	err = transaction(a.db, func(tx *gorm.DB) error {
+		// 首次写入时创建链尾，已存在时不做修改
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&AuditChain{ID: 1, Hash: genesisHash}).Error; err != nil {
+			return err
+		}
+		var chain AuditChain
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&chain, 1).Error; err != nil {
+			return err
+		}
+
+		log.Seq = chain.Seq + 1
+		log.PrevHash = chain.Hash
+		log.Hash = log.computeHash()
+		if err := tx.Create(&log).Error; err != nil {
+			return err
+		}
+		return tx.Model(&chain).Updates(map[string]interface{}{"seq": log.Seq, "hash": log.Hash}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// diffFields 返回发生变化的字段在修改前后的值
+func diffFields(before, after map[string]any) (map[string]any, map[string]any) {
+	changedBefore := make(map[string]any)
+	changedAfter := make(map[string]any)
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			changedBefore[key] = before[key]
+			changedAfter[key] = value
+		}
+	}
+	for key, old := range before {
+		if _, ok := after[key]; !ok {
+			changedBefore[key] = old
+			changedAfter[key] = nil
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// List 分页获取审计日志，按时间倒序排列
+func (a *AuditCRUD) List(q *AuditQuery) ([]AuditLog, *Pagination, error) {
+	q.normalize()
+
+	db := a.db.Model(&AuditLog{})
+	if q.ActorID != 0 {
+		db = db.Where("actor_id = ?", q.ActorID)
+	}
+	if q.Action != "" {
+		db = db.Where("action = ?", q.Action)
+	}
+	if q.EntityType != "" {
+		db = db.Where("entity_type = ?", q.EntityType)
+	}
+	if q.EntityID != 0 {
+		db = db.Where("entity_id = ?", q.EntityID)
+	}
+	if q.RequestID != "" {
+		db = db.Where("request_id = ?", q.RequestID)
+	}
+	if q.From != "" {
+		from, err := parseTimeParam(q.From, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		db = db.Where("created_at >= ?", from)
+	}
+	if q.To != "" {
+		to, err := parseTimeParam(q.To, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		db = db.Where("created_at < ?", to)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取审计日志失败")
+	}
+
+	paged, err := paginate(db, &q.PageQuery, "created_at", "id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var logs []AuditLog
+	if err := paged.Find(&logs).Error; err != nil {
+		return nil, nil, errors.New("获取审计日志失败")
+	}
+
+	pagination := buildPagination(&q.PageQuery, total, len(logs), func() (time.Time, uint) {
+		last := logs[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(logs) > q.Size {
+		logs = logs[:q.Size]
+	}
+	return logs, pagination, nil
+}
+
+// Verify 按序号逐条校验哈希链：序号连续、每条记录的前一条哈希与上一条记录一致、重新计算的哈希与保存的一致，
+// 最后一条记录与链尾一致。返回第一处不一致的位置
+func (a *AuditCRUD) Verify() (*AuditVerification, error) {
+	const batch = 500
+	result := &AuditVerification{Valid: true}
+	prevSeq, prevHash := uint64(0), genesisHash
+
+	fail := func(seq uint64, reason string) (*AuditVerification, error) {
+		result.Valid, result.BrokenSeq, result.Reason = false, seq, reason
+		return result, nil
+	}
+
+	for {
+		var logs []AuditLog
+		if err := a.db.Where("seq > ?", prevSeq).Order("seq").Limit(batch).Find(&logs).Error; err != nil {
+			return nil, errors.New("校验审计日志失败")
+		}
+		for i := range logs {
+			log := &logs[i]
+			switch {
+			case log.Seq != prevSeq+1:
+				return fail(prevSeq+1, "记录缺失")
+			case log.PrevHash != prevHash:
+				return fail(log.Seq, "与前一条记录的哈希不一致")
+			case log.computeHash() != log.Hash:
+				return fail(log.Seq, "记录内容已被修改")
+			}
+			prevSeq, prevHash = log.Seq, log.Hash
+			result.Checked++
+		}
+		if len(logs) < batch {
+			break
+		}
+	}
+
+	var chain AuditChain
+	if err := a.db.First(&chain, 1).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) && prevSeq == 0 {
+			return result, nil
+		}
+		return fail(prevSeq, "哈希链链尾不存在")
+	}
+	if chain.Seq != prevSeq || chain.Hash != prevHash {
+		return fail(prevSeq+1, fmt.Sprintf("链尾序号为%d，与最后一条记录不一致，末尾的记录可能已被删除", chain.Seq))
+	}
+	return result, nil
+}
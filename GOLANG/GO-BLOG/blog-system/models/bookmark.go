@@ -0,0 +1,264 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultCollectionName 收藏时未指定收藏夹使用的默认收藏夹名称
+const DefaultCollectionName = "默认收藏夹"
+
+// BookmarkCollection 收藏夹，公开收藏夹中的收藏会出现在关注者的首页动态中
+type BookmarkCollection struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_collection_user_name;comment:用户ID" json:"user_id"`
+	Name      string    `gorm:"not null;size:50;uniqueIndex:idx_collection_user_name;comment:收藏夹名称" json:"name"`
+	Public    bool      `gorm:"default:false;comment:是否公开" json:"public"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	// 收藏数量，查询时统计
+	BookmarkCount int64 `gorm:"->;-:migration" json:"bookmark_count"`
+
+	// 多对一关系：多个收藏夹属于一个用户
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Bookmark 收藏记录，同一篇文章可以收藏到多个收藏夹
+type Bookmark struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint      `gorm:"not null;index;comment:用户ID" json:"user_id"`
+	CollectionID uint      `gorm:"not null;uniqueIndex:idx_bookmark_collection_post;comment:收藏夹ID" json:"collection_id"`
+	PostID       uint      `gorm:"not null;uniqueIndex:idx_bookmark_collection_post;index;comment:文章ID" json:"post_id"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;index;comment:收藏时间" json:"created_at"`
+
+	User       User               `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Collection BookmarkCollection `gorm:"foreignKey:CollectionID;constraint:OnDelete:CASCADE" json:"-"`
+	Post       Post               `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"post"`
+}
+
+// CollectionRequest 创建或修改收藏夹请求
+type CollectionRequest struct {
+	Name   string `json:"name" binding:"required,max=50"`
+	Public bool   `json:"public"`
+}
+
+// BookmarkRequest 收藏文章请求，collection为收藏夹名称，不存在时自动创建，为空时使用默认收藏夹
+type BookmarkRequest struct {
+	PostID     uint   `json:"post_id" binding:"required"`
+	Collection string `json:"collection" binding:"max=50"`
+}
+
+// BookmarkQuery 收藏列表查询参数
+type BookmarkQuery struct {
+	PageQuery
+	CollectionID uint `form:"collection_id"`
+}
+
+// BookmarkCRUD 收藏和收藏夹操作
+type BookmarkCRUD struct {
+	db *gorm.DB
+}
+
+// NewBookmarkCRUD 创建收藏操作实例
+func NewBookmarkCRUD(db *gorm.DB) *BookmarkCRUD {
+	return &BookmarkCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (b *BookmarkCRUD) WithContext(ctx context.Context) *BookmarkCRUD {
+	copied := *b
+	copied.db = b.db.WithContext(ctx)
+	return &copied
+}
+
+// withBookmarkCount 查询收藏夹时统计收藏数量
+func withBookmarkCount(db *gorm.DB) *gorm.DB {
+	return db.Select("bookmark_collections.*, (SELECT COUNT(*) FROM bookmarks WHERE bookmarks.collection_id = bookmark_collections.id) AS bookmark_count")
+}
+
+// ListCollections 获取用户的所有收藏夹
+func (b *BookmarkCRUD) ListCollections(userID uint) ([]BookmarkCollection, error) {
+	var collections []BookmarkCollection
+	if err := b.db.Scopes(withBookmarkCount).Where("user_id = ?", userID).Order("id ASC").Find(&collections).Error; err != nil {
+		return nil, errors.New("获取收藏夹失败")
+	}
+	return collections, nil
+}
+
+// CreateCollection 创建收藏夹
+func (b *BookmarkCRUD) CreateCollection(userID uint, req *CollectionRequest) (*BookmarkCollection, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("收藏夹名称不能为空")
+	}
+	if b.collectionExists(userID, name, 0) {
+		return nil, errors.New("收藏夹已存在")
+	}
+
+	collection := BookmarkCollection{UserID: userID, Name: name, Public: req.Public}
+	if err := b.db.Omit("User").Create(&collection).Error; err != nil {
+		return nil, errors.New("创建收藏夹失败")
+	}
+	return &collection, nil
+}
+
+// UpdateCollection 修改收藏夹名称和公开状态
+func (b *BookmarkCRUD) UpdateCollection(id, userID uint, req *CollectionRequest) (*BookmarkCollection, error) {
+	collection, err := b.getCollection(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("收藏夹名称不能为空")
+	}
+	if b.collectionExists(userID, name, id) {
+		return nil, errors.New("收藏夹已存在")
+	}
+
+	if err := b.db.Model(collection).Updates(map[string]interface{}{"name": name, "public": req.Public}).Error; err != nil {
+		return nil, errors.New("修改收藏夹失败")
+	}
+	return b.getCollection(id, userID)
+}
+
+// DeleteCollection 删除收藏夹及其中的收藏
+func (b *BookmarkCRUD) DeleteCollection(id, userID uint) error {
+	collection, err := b.getCollection(id, userID)
+	if err != nil {
+		return err
+	}
+	return transaction(b.db, func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&Bookmark{}).Error; err != nil {
+			return errors.New("删除收藏夹失败")
+		}
+		if err := tx.Delete(collection).Error; err != nil {
+			return errors.New("删除收藏夹失败")
+		}
+		return nil
+	})
+}
+
+// Add 收藏文章，文章已在该收藏夹中时返回已有的收藏
+func (b *BookmarkCRUD) Add(userID uint, req *BookmarkRequest) (*Bookmark, error) {
+	var count int64
+	b.db.Model(&Post{}).Scopes(visibleTo(userID)).Where("posts.id = ?", req.PostID).Count(&count)
+	if count == 0 {
+		return nil, errors.New("文章不存在")
+	}
+
+	name := strings.TrimSpace(req.Collection)
+	if name == "" {
+		name = DefaultCollectionName
+	}
+
+	var bookmark Bookmark
+	err := transaction(b.db, func(tx *gorm.DB) error {
+		// 收藏夹不存在时自动创建，并发创建同名收藏夹时以唯一索引为准
+		collection := BookmarkCollection{UserID: userID, Name: name}
+		if err := tx.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).Create(&collection).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND name = ?", userID, name).First(&collection).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Omit("User", "Collection", "Post").Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Bookmark{UserID: userID, CollectionID: collection.ID, PostID: req.PostID}).Error; err != nil {
+			return err
+		}
+		return tx.Where("collection_id = ? AND post_id = ?", collection.ID, req.PostID).First(&bookmark).Error
+	})
+	if err != nil {
+		return nil, errors.New("收藏失败")
+	}
+	return &bookmark, nil
+}
+
+// Remove 取消收藏
+func (b *BookmarkCRUD) Remove(id, userID uint) error {
+	result := b.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Bookmark{})
+	if result.Error != nil {
+		return errors.New("取消收藏失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("收藏不存在")
+	}
+	return nil
+}
+
+// List 分页获取用户的收藏，按收藏时间倒序排列；collection_id为0时返回所有收藏夹中的收藏。
+// 已删除或不再可见的文章不会出现在列表中
+func (b *BookmarkCRUD) List(userID uint, q *BookmarkQuery) ([]Bookmark, *Pagination, error) {
+	q.normalize()
+
+	db := b.db.Model(&Bookmark{}).
+		Joins("JOIN posts ON posts.id = bookmarks.post_id AND posts.deleted_at IS NULL").
+		Scopes(visibleTo(userID)).
+		Where("bookmarks.user_id = ?", userID)
+	if q.CollectionID != 0 {
+		if _, err := b.getCollection(q.CollectionID, userID); err != nil {
+			return nil, nil, err
+		}
+		db = db.Where("bookmarks.collection_id = ?", q.CollectionID)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取收藏列表失败")
+	}
+
+	paged, err := paginate(db, &q.PageQuery, "bookmarks.created_at", "bookmarks.id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var bookmarks []Bookmark
+	if err := paged.Select("bookmarks.*").Preload("Post.User").Preload("Post.Tags").Find(&bookmarks).Error; err != nil {
+		return nil, nil, errors.New("获取收藏列表失败")
+	}
+
+	pagination := buildPagination(&q.PageQuery, total, len(bookmarks), func() (time.Time, uint) {
+		last := bookmarks[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(bookmarks) > q.Size {
+		bookmarks = bookmarks[:q.Size]
+	}
+
+	posts := make([]Post, len(bookmarks))
+	for i := range bookmarks {
+		posts[i] = bookmarks[i].Post
+	}
+	if err := fillPostInteractions(b.db, posts, userID); err != nil {
+		return nil, nil, errors.New("获取收藏列表失败")
+	}
+	for i := range bookmarks {
+		bookmarks[i].Post = posts[i]
+	}
+
+	return bookmarks, pagination, nil
+}
+
+// getCollection 获取用户自己的收藏夹
+func (b *BookmarkCRUD) getCollection(id, userID uint) (*BookmarkCollection, error) {
+	var collection BookmarkCollection
+	if err := b.db.Scopes(withBookmarkCount).Where("id = ? AND user_id = ?", id, userID).First(&collection).Error; err != nil {
+		return nil, errors.New("收藏夹不存在")
+	}
+	return &collection, nil
+}
+
+// collectionExists 判断用户是否已有同名收藏夹，excludeID用于修改时排除自身
+func (b *BookmarkCRUD) collectionExists(userID uint, name string, excludeID uint) bool {
+	var count int64
+	b.db.Model(&BookmarkCollection{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+
+	"blog-system/markdown"
+
+	"gorm.io/gorm"
+)
+
+// renderPost 渲染文章正文的HTML，摘要为空时从正文自动生成
+func renderPost(post *Post) {
+	post.ContentHTML = markdown.RenderPost(post.Content)
+	if strings.TrimSpace(post.Summary) == "" {
+		post.Summary = markdown.Summary(post.ContentHTML)
+	}
+}
+
+// backfillContentHTML 为尚未渲染HTML的文章和评论生成HTML，只更新对应列，不修改更新时间
+func backfillContentHTML(db *gorm.DB) error {
+	var posts []Post
+	err := db.Unscoped().Select("id", "content", "summary").
+		Where("content_html IS NULL OR content_html = ''").
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for i := range posts {
+				renderPost(&posts[i])
+				if err := db.Unscoped().Model(&posts[i]).UpdateColumns(map[string]interface{}{
+					"content_html": posts[i].ContentHTML,
+					"summary":      posts[i].Summary,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	var comments []Comment
+	return db.Unscoped().Select("id", "content").
+		Where("content_html IS NULL OR content_html = ''").
+		FindInBatches(&comments, 100, func(tx *gorm.DB, batch int) error {
+			for i := range comments {
+				html := markdown.RenderComment(comments[i].Content)
+				if err := db.Unscoped().Model(&comments[i]).UpdateColumn("content_html", html).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
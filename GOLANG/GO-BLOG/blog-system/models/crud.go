@@ -1,7 +1,13 @@
 package models
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"time"
+
+	"blog-system/markdown"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -17,6 +23,13 @@ func NewUserCRUD(db *gorm.DB) *UserCRUD {
 	return &UserCRUD{db: db}
 }
 
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (u *UserCRUD) WithContext(ctx context.Context) *UserCRUD {
+	copied := *u
+	copied.db = u.db.WithContext(ctx)
+	return &copied
+}
+
 // Create 创建用户
 func (u *UserCRUD) Create(req *RegisterRequest) (*User, error) {
 	// 检查用户名是否已存在
@@ -41,6 +54,7 @@ func (u *UserCRUD) Create(req *RegisterRequest) (*User, error) {
 		Username: req.Username,
 		Password: string(hashedPassword),
 		Email:    req.Email,
+		Role:     RoleAuthor,
 	}
 
 	if err := u.db.Create(&user).Error; err != nil {
@@ -59,6 +73,283 @@ func (u *UserCRUD) GetByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+// GetByID 根据ID获取用户
+func (u *UserCRUD) GetByID(id uint) (*User, error) {
+	var user User
+	if err := u.db.First(&user, id).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return &user, nil
+}
+
+// List 获取用户列表
+func (u *UserCRUD) List() ([]User, error) {
+	var users []User
+	if err := u.db.Order("id ASC").Find(&users).Error; err != nil {
+		return nil, errors.New("获取用户列表失败")
+	}
+	return users, nil
+}
+
+// UpdateRole 修改用户角色
+func (u *UserCRUD) UpdateRole(id uint, role string) (*User, error) {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.db.Model(user).Update("role", role).Error; err != nil {
+		return nil, errors.New("用户角色更新失败")
+	}
+	return user, nil
+}
+
+// UpdateAvatar 修改用户头像地址
+func (u *UserCRUD) UpdateAvatar(id uint, avatar string) (*User, error) {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.db.Model(user).Update("avatar", avatar).Error; err != nil {
+		return nil, errors.New("用户头像更新失败")
+	}
+	return user, nil
+}
+
+// UpdateProfile 修改个人资料，修改邮箱时检查邮箱是否已被其他用户使用
+func (u *UserCRUD) UpdateProfile(id uint, req *ProfileRequest) (*User, error) {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"nickname": req.Nickname,
+		"avatar":   req.Avatar,
+		"bio":      req.Bio,
+	}
+	if req.Email != "" && req.Email != user.Email {
+		var count int64
+		if err := u.db.Unscoped().Model(&User{}).Where("email = ? AND id <> ?", req.Email, id).Count(&count).Error; err != nil {
+			return nil, errors.New("个人资料更新失败")
+		}
+		if count > 0 {
+			return nil, errors.New("邮箱已存在")
+		}
+		// 修改邮箱后需要重新验证
+		updates["email"] = req.Email
+		updates["email_verified_at"] = nil
+	}
+
+	if err := u.db.Model(user).Updates(updates).Error; err != nil {
+		return nil, errors.New("个人资料更新失败")
+	}
+	return u.GetByID(id)
+}
+
+// ChangePassword 校验当前密码后修改密码
+func (u *UserCRUD) ChangePassword(id uint, currentPassword, newPassword string) error {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.VerifyPassword(user, currentPassword); err != nil {
+		return errors.New("当前密码错误")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+
+	if err := u.db.Model(user).Update("password", string(hashedPassword)).Error; err != nil {
+		return errors.New("密码修改失败")
+	}
+	return nil
+}
+
+// GetByEmail 根据邮箱获取用户
+func (u *UserCRUD) GetByEmail(email string) (*User, error) {
+	var user User
+	if err := u.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return &user, nil
+}
+
+// MarkEmailVerified 将用户邮箱标记为已验证。email为令牌签发时的邮箱，
+// 用户之后修改过邮箱时验证无效
+func (u *UserCRUD) MarkEmailVerified(id uint, email string) (*User, error) {
+	result := u.db.Model(&User{}).Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return nil, errors.New("邮箱验证失败")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("邮箱已变更，请重新验证")
+	}
+	return u.GetByID(id)
+}
+
+// IsEmailVerified 判断用户邮箱是否已验证
+func (u *UserCRUD) IsEmailVerified(id uint) (bool, error) {
+	var users []User
+	if err := u.db.Select("id", "email_verified_at").Where("id = ?", id).Limit(1).Find(&users).Error; err != nil {
+		return false, err
+	}
+	return len(users) > 0 && users[0].EmailVerifiedAt != nil, nil
+}
+
+// ResetPassword 通过密码重置链接设置新密码。能收到重置邮件说明邮箱属于该用户，同时标记邮箱已验证
+func (u *UserCRUD) ResetPassword(id uint, email, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+
+	// 重置链接只对签发时的邮箱有效，修改邮箱后发往旧邮箱的链接随之失效
+	result := u.db.Model(&User{}).Where("id = ? AND email = ?", id, email).Update("password", string(hashedPassword))
+	if result.Error != nil {
+		return errors.New("密码重置失败")
+	}
+	if result.RowsAffected == 0 {
+		if _, err := u.GetByID(id); err != nil {
+			return err
+		}
+		return errors.New("邮箱已变更，请重新申请重置密码")
+	}
+
+	u.db.Model(&User{}).Where("id = ? AND email = ? AND email_verified_at IS NULL", id, email).
+		Update("email_verified_at", time.Now())
+	return nil
+}
+
+// SetActive 启用或停用用户账户，停用的账户无法登录，已签发的访问令牌也会被拒绝
+func (u *UserCRUD) SetActive(id uint, active bool) (*User, error) {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.db.Model(user).Update("is_active", active).Error; err != nil {
+		return nil, errors.New("用户状态更新失败")
+	}
+	return user, nil
+}
+
+// AccountStatus 获取用户账户是否可用和当前角色，已删除的用户视为不可用
+func (u *UserCRUD) AccountStatus(id uint) (bool, string, error) {
+	var users []User
+	if err := u.db.Select("id", "is_active", "role").Where("id = ?", id).Limit(1).Find(&users).Error; err != nil {
+		return false, "", err
+	}
+	if len(users) == 0 {
+		return false, "", nil
+	}
+	return users[0].IsActive, users[0].Role, nil
+}
+
+// GetPublicProfile 根据用户名获取用户公开资料，停用的账户视为不存在
+func (u *UserCRUD) GetPublicProfile(username string) (*PublicProfile, error) {
+	user, err := u.GetByUsername(username)
+	if err != nil || !user.IsActive {
+		return nil, errors.New("用户不存在")
+	}
+
+	profile := &PublicProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+		Bio:       user.Bio,
+		CreatedAt: user.CreatedAt,
+	}
+	if err := u.db.Model(&Post{}).Scopes(visibleTo(0)).
+		Where("posts.user_id = ?", user.ID).Count(&profile.PostCount).Error; err != nil {
+		return nil, errors.New("获取用户资料失败")
+	}
+
+	followers, following, err := NewFollowCRUD(u.db).Counts(user.ID)
+	if err != nil {
+		return nil, errors.New("获取用户资料失败")
+	}
+	profile.FollowerCount, profile.FollowingCount = followers, following
+	return profile, nil
+}
+
+// Delete 将用户移入回收站，用户的文章和评论保留，永久删除时才一并清除
+func (u *UserCRUD) Delete(id uint) error {
+	user, err := u.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := u.db.Delete(user).Error; err != nil {
+		return errors.New("用户删除失败")
+	}
+	return nil
+}
+
+// Restore 从回收站恢复用户
+func (u *UserCRUD) Restore(id uint) (*User, error) {
+	var user User
+	if err := u.db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		return nil, errors.New("回收站中不存在该用户")
+	}
+	if err := u.db.Unscoped().Model(&user).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, errors.New("用户恢复失败")
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	return &user, nil
+}
+
+// ListDeleted 获取回收站中的用户，按删除时间倒序排列
+func (u *UserCRUD) ListDeleted() ([]User, error) {
+	var users []User
+	if err := u.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&users).Error; err != nil {
+		return nil, errors.New("获取已删除用户失败")
+	}
+	return users, nil
+}
+
+// BootstrapAdmin 初始化第一个管理员账户。
+// 已存在管理员时不做任何操作；用户名已存在时只有密码与配置的密码一致才提升为管理员，
+// 避免他人抢先注册该用户名获得管理员权限；用户名不存在时新建管理员账户。
+func (u *UserCRUD) BootstrapAdmin(username, password, email string) (*User, bool, error) {
+	var count int64
+	if err := u.db.Model(&User{}).Where("role = ?", RoleAdmin).Count(&count).Error; err != nil {
+		return nil, false, errors.New("查询管理员失败")
+	}
+	if count > 0 {
+		return nil, false, nil
+	}
+
+	if user, err := u.GetByUsername(username); err == nil {
+		if password == "" || u.VerifyPassword(user, password) != nil {
+			return nil, false, errors.New("用户名" + username + "已被注册且密码与配置不一致，拒绝提升为管理员")
+		}
+		if err := u.db.Model(user).Update("role", RoleAdmin).Error; err != nil {
+			return nil, false, errors.New("用户角色更新失败")
+		}
+		return user, true, nil
+	}
+
+	if password == "" || email == "" {
+		return nil, false, errors.New("创建管理员需要提供密码和邮箱")
+	}
+
+	user, err := u.Create(&RegisterRequest{Username: username, Password: password, Email: email})
+	if err != nil {
+		return nil, false, err
+	}
+	// 管理员邮箱由部署配置提供，无需验证
+	if err := u.db.Model(user).Updates(map[string]interface{}{"role": RoleAdmin, "email_verified_at": time.Now()}).Error; err != nil {
+		return nil, false, errors.New("用户角色更新失败")
+	}
+	return user, true, nil
+}
+
 // VerifyPassword 验证密码
 func (u *UserCRUD) VerifyPassword(user *User, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
@@ -74,25 +365,180 @@ func NewPostCRUD(db *gorm.DB) *PostCRUD {
 	return &PostCRUD{db: db}
 }
 
-// GetAll 获取所有文章
-func (p *PostCRUD) GetAll() ([]Post, error) {
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (p *PostCRUD) WithContext(ctx context.Context) *PostCRUD {
+	copied := *p
+	copied.db = p.db.WithContext(ctx)
+	return &copied
+}
+
+// visibleTo 文章可见性查询条件，与Post.VisibleTo保持一致
+func visibleTo(viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(posts.status = ? OR posts.user_id = ?)", PostStatusPublished, viewerID)
+	}
+}
+
+// applyStatus 设置文章的发布状态。status为空时根据publishAt推断：
+// 未来时间为定时发布，否则立即发布。
+func applyStatus(post *Post, status string, publishAt *time.Time) error {
+	now := time.Now()
+	if status == "" {
+		status = PostStatusPublished
+		if publishAt != nil && publishAt.After(now) {
+			status = PostStatusScheduled
+		}
+	}
+
+	switch status {
+	case PostStatusDraft:
+		post.Status = PostStatusDraft
+		post.ScheduledAt = nil
+		post.PublishedAt = nil
+	case PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return errors.New("定时发布时间必须晚于当前时间")
+		}
+		post.Status = PostStatusScheduled
+		post.ScheduledAt = publishAt
+		post.PublishedAt = nil
+	case PostStatusPublished:
+		post.Status = PostStatusPublished
+		post.ScheduledAt = nil
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+		}
+	default:
+		return errors.New("无效的文章状态")
+	}
+	return nil
+}
+
+// List 分页获取文章列表，支持按作者、状态、时间范围过滤和排序。
+// 未发布的文章只对作者本人可见。
+func (p *PostCRUD) List(q *PostListQuery, viewerID uint) ([]Post, *Pagination, error) {
+	q.normalize()
+
+	db := p.db.Model(&Post{}).Scopes(visibleTo(viewerID))
+	if q.AuthorID != 0 {
+		db = db.Where("posts.user_id = ?", q.AuthorID)
+	}
+	if q.Author != "" {
+		db = db.Where("posts.user_id IN (?)", p.db.Model(&User{}).Select("id").Where("username = ?", q.Author))
+	}
+	if q.Status != "" {
+		db = db.Where("posts.status = ?", q.Status)
+	}
+	if q.Tag != "" {
+		db = db.Where("posts.id IN (?)", p.db.Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ?", normalizeTagName(q.Tag)))
+	}
+	if q.Category != 0 {
+		// 按分类过滤时包含所有子分类
+		ids, err := descendantCategoryIDs(p.db, q.Category)
+		if err != nil {
+			return nil, nil, errors.New("获取文章列表失败")
+		}
+		db = db.Where("posts.category_id IN ?", ids)
+	}
+
+	column := "posts.created_at"
+	if q.Sort == "updated_at" {
+		column = "posts.updated_at"
+	}
+	if q.From != "" {
+		from, err := parseTimeParam(q.From, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		db = db.Where(column+" >= ?", from)
+	}
+	if q.To != "" {
+		to, err := parseTimeParam(q.To, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		db = db.Where(column+" < ?", to)
+	}
+
+	// 使用独立会话，避免Count修改后续分页查询的语句
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取文章列表失败")
+	}
+
+	paged, err := paginate(db, &q.PageQuery, column, "posts.id", q.Order != "asc")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var posts []Post
-	if err := p.db.Preload("User").Order("created_at DESC").Find(&posts).Error; err != nil {
-		return nil, errors.New("获取文章列表失败")
+	if err := paged.Preload("User").Preload("Category").Preload("Tags").Find(&posts).Error; err != nil {
+		return nil, nil, errors.New("获取文章列表失败")
 	}
-	return posts, nil
+
+	pagination := buildPagination(&q.PageQuery, total, len(posts), func() (time.Time, uint) {
+		last := posts[q.Size-1]
+		if q.Sort == "updated_at" {
+			return last.UpdatedAt, last.ID
+		}
+		return last.CreatedAt, last.ID
+	})
+	if len(posts) > q.Size {
+		posts = posts[:q.Size]
+	}
+	if err := fillPostInteractions(p.db, posts, viewerID); err != nil {
+		return nil, nil, errors.New("获取文章列表失败")
+	}
+
+	return posts, pagination, nil
 }
 
-// GetByID 根据ID获取文章
-func (p *PostCRUD) GetByID(id uint) (*Post, error) {
+// GetByID 根据ID获取文章，未发布的文章只对作者本人可见；未通过审核的评论只对评论者本人可见
+func (p *PostCRUD) GetByID(id uint, viewerID uint) (*Post, error) {
 	var post Post
-	if err := p.db.Preload("User").Preload("Comments.User").First(&post, id).Error; err != nil {
+	if err := p.db.Scopes(visibleTo(viewerID)).Preload("User").Preload("Category").Preload("Tags").
+		Preload("Comments", "status = ? OR user_id = ?", CommentStatusApproved, viewerID).Preload("Comments.User").
+		First(&post, id).Error; err != nil {
 		return nil, errors.New("文章不存在")
 	}
+	if err := p.fillInteractions(&post, viewerID); err != nil {
+		return nil, errors.New("获取文章失败")
+	}
 	return &post, nil
 }
 
-// Create 创建文章
+// fillInteractions 为单篇文章及其评论填充互动统计
+func (p *PostCRUD) fillInteractions(post *Post, viewerID uint) error {
+	posts := []Post{*post}
+	if err := fillPostInteractions(p.db, posts, viewerID); err != nil {
+		return err
+	}
+	post.ViewCount, post.Interactions = posts[0].ViewCount, posts[0].Interactions
+	return fillCommentInteractions(p.db, post.Comments, viewerID)
+}
+
+// IsVisible 判断文章是否存在且对指定用户可见
+func (p *PostCRUD) IsVisible(id uint, viewerID uint) bool {
+	var count int64
+	p.db.Model(&Post{}).Scopes(visibleTo(viewerID)).Where("posts.id = ?", id).Count(&count)
+	return count > 0
+}
+
+// GetAuthorID 获取文章作者ID
+func (p *PostCRUD) GetAuthorID(id uint) (uint, error) {
+	var post Post
+	if err := p.db.Select("id", "user_id").First(&post, id).Error; err != nil {
+		return 0, errors.New("文章不存在")
+	}
+	return post.UserID, nil
+}
+
+// Create 创建文章，未指定状态时立即发布
 func (p *PostCRUD) Create(req *PostRequest, userID uint) (*Post, error) {
 	post := Post{
 		Title:   req.Title,
@@ -100,73 +546,351 @@ func (p *PostCRUD) Create(req *PostRequest, userID uint) (*Post, error) {
 		Summary: req.Summary,
 		UserID:  userID,
 	}
+	renderPost(&post)
+	if err := applyStatus(&post, req.Status, req.PublishAt); err != nil {
+		return nil, err
+	}
+	if err := p.applyCategory(&post, req.CategoryID); err != nil {
+		return nil, err
+	}
 
-	if err := p.db.Create(&post).Error; err != nil {
-		return nil, errors.New("文章创建失败")
+	err := transaction(p.db, func(tx *gorm.DB) error {
+		if err := assignSlug(tx, &post, req.Slug); err != nil {
+			return err
+		}
+		if err := tx.Create(&post).Error; err != nil {
+			return errors.New("文章创建失败")
+		}
+		if err := recordRevision(tx, &post, userID, "初始版本"); err != nil {
+			return err
+		}
+		return replaceTags(tx, &post, req.Tags)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 预加载用户信息
-	p.db.Preload("User").First(&post, post.ID)
+	// 预加载关联信息
+	p.preload(&post)
 	return &post, nil
 }
 
-// Update 更新文章
-func (p *PostCRUD) Update(id uint, req *PostRequest, userID uint) (*Post, error) {
+// Update 更新文章，moderate为true时允许修改他人的文章。
+// 标题、摘要或正文发生变化时写入一条修订记录。
+func (p *PostCRUD) Update(id uint, req *PostRequest, userID uint, moderate bool) (*Post, error) {
 	var post Post
 	if err := p.db.First(&post, id).Error; err != nil {
 		return nil, errors.New("文章不存在")
 	}
 
 	// 检查权限
-	if post.UserID != userID {
+	if post.UserID != userID && !moderate {
 		return nil, errors.New("无权限修改此文章")
 	}
 
-	// 更新文章
+	before := post
+	post.PreviousStatus = before.Status
+	renamed := post.Title != req.Title || req.Slug != ""
+
+	// 更新文章，未指定状态和发布时间时保持原状态
 	post.Title = req.Title
 	post.Content = req.Content
 	post.Summary = req.Summary
+	renderPost(&post)
+	edited := before.Title != post.Title || before.Content != post.Content || before.Summary != post.Summary
+	if req.Status != "" || req.PublishAt != nil {
+		if err := applyStatus(&post, req.Status, req.PublishAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.applyCategory(&post, req.CategoryID); err != nil {
+		return nil, err
+	}
 
-	if err := p.db.Save(&post).Error; err != nil {
-		return nil, errors.New("文章更新失败")
+	err := transaction(p.db, func(tx *gorm.DB) error {
+		if edited {
+			if err := ensureBaseRevision(tx, &before); err != nil {
+				return err
+			}
+		}
+		// 标题修改或指定新别名时更新别名，旧别名保留用于重定向
+		if renamed {
+			if err := assignSlug(tx, &post, req.Slug); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Tags", "Category", "User", "Comments").Save(&post).Error; err != nil {
+			return errors.New("文章更新失败")
+		}
+		if edited {
+			if err := recordRevision(tx, &post, userID, ""); err != nil {
+				return err
+			}
+		}
+		// 未提供tags时保持原有标签
+		if req.Tags == nil {
+			return nil
+		}
+		return replaceTags(tx, &post, req.Tags)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 预加载用户信息
-	p.db.Preload("User").First(&post, post.ID)
+	// 预加载关联信息
+	p.preload(&post)
 	return &post, nil
 }
 
-// Delete 删除文章
-func (p *PostCRUD) Delete(id uint, userID uint) error {
+// RestoreRevision 将文章的标题、摘要和正文恢复为指定修订版本的内容，并记录为一个新的修订版本
+func (p *PostCRUD) RestoreRevision(id uint, revision int, userID uint, moderate bool) (*Post, error) {
 	var post Post
 	if err := p.db.First(&post, id).Error; err != nil {
-		return errors.New("文章不存在")
+		return nil, errors.New("文章不存在")
 	}
 
 	// 检查权限
-	if post.UserID != userID {
-		return errors.New("无权限删除此文章")
+	if post.UserID != userID && !moderate {
+		return nil, errors.New("无权限修改此文章")
+	}
+
+	var rev PostRevision
+	if err := p.db.Where("post_id = ? AND revision = ?", id, revision).First(&rev).Error; err != nil {
+		return nil, errors.New("修订版本不存在")
 	}
 
-	if err := p.db.Delete(&post).Error; err != nil {
-		return errors.New("文章删除失败")
+	renamed := post.Title != rev.Title
+	post.Title = rev.Title
+	post.Content = rev.Content
+	post.Summary = rev.Summary
+	renderPost(&post)
+
+	err := transaction(p.db, func(tx *gorm.DB) error {
+		if renamed {
+			if err := assignSlug(tx, &post, ""); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&post).Select("title", "slug", "content", "content_html", "summary").Updates(&post).Error; err != nil {
+			return errors.New("文章恢复失败")
+		}
+		return recordRevision(tx, &post, userID, fmt.Sprintf("恢复自版本%d", revision))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 预加载关联信息
+	p.preload(&post)
+	return &post, nil
+}
+
+// applyCategory 设置文章分类：nil表示不修改，0表示取消分类
+func (p *PostCRUD) applyCategory(post *Post, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	if *categoryID == 0 {
+		post.CategoryID = nil
+		return nil
+	}
+
+	var count int64
+	p.db.Model(&Category{}).Where("id = ?", *categoryID).Count(&count)
+	if count == 0 {
+		return errors.New("分类不存在")
+	}
+	post.CategoryID = categoryID
+	return nil
+}
+
+// replaceTags 用给定名称的标签替换文章的全部标签
+func replaceTags(tx *gorm.DB, post *Post, names []string) error {
+	tags, err := findOrCreateTags(tx, names)
+	if err != nil {
+		return err
 	}
 
+	association := tx.Model(post).Association("Tags")
+	if len(tags) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(tags)
+	}
+	if err != nil {
+		return errors.New("文章标签保存失败")
+	}
 	return nil
 }
 
-// GetLastPost 获取最后一篇文章
-func (p *PostCRUD) GetLastPost() (*Post, error) {
+// preload 加载文章的作者、分类和标签
+func (p *PostCRUD) preload(post *Post) {
+	p.db.Preload("User").Preload("Category").Preload("Tags").First(post, post.ID)
+}
+
+// Delete 将文章移入回收站，moderate为true时允许删除他人的文章。
+// 文章的评论保留在数据库中，恢复文章后随之恢复可见。
+func (p *PostCRUD) Delete(id uint, userID uint, moderate bool) (*Post, error) {
 	var post Post
-	if err := p.db.Order("id DESC").First(&post).Error; err != nil {
+	if err := p.db.First(&post, id).Error; err != nil {
+		return nil, errors.New("文章不存在")
+	}
+
+	// 检查权限
+	if post.UserID != userID && !moderate {
+		return nil, errors.New("无权限删除此文章")
+	}
+
+	err := transaction(p.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&post).UpdateColumn("deleted_by", userID).Error; err != nil {
+			return errors.New("文章删除失败")
+		}
+		if err := tx.Delete(&post).Error; err != nil {
+			return errors.New("文章删除失败")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// Restore 从回收站恢复文章。作者只能恢复自己删除的文章，moderate为true时可恢复任意文章。
+func (p *PostCRUD) Restore(id uint, userID uint, moderate bool) (*Post, error) {
+	var post Post
+	if err := p.db.Unscoped().Where("deleted_at IS NOT NULL").First(&post, id).Error; err != nil {
+		return nil, errors.New("回收站中不存在该文章")
+	}
+
+	if !moderate && (post.UserID != userID || (post.DeletedBy != nil && *post.DeletedBy != userID)) {
+		return nil, errors.New("无权限恢复此文章")
+	}
+
+	if err := p.db.Unscoped().Model(&post).UpdateColumns(map[string]interface{}{
+		"deleted_at": nil,
+		"deleted_by": nil,
+	}).Error; err != nil {
+		return nil, errors.New("文章恢复失败")
+	}
+
+	// 预加载关联信息
+	p.preload(&post)
+	return &post, nil
+}
+
+// Publish 发布文章：publishAt为未来时间时定时发布，否则立即发布
+func (p *PostCRUD) Publish(id uint, userID uint, moderate bool, publishAt *time.Time) (*Post, error) {
+	return p.changeStatus(id, userID, moderate, "", publishAt)
+}
+
+// Unpublish 撤回文章为草稿
+func (p *PostCRUD) Unpublish(id uint, userID uint, moderate bool) (*Post, error) {
+	return p.changeStatus(id, userID, moderate, PostStatusDraft, nil)
+}
+
+func (p *PostCRUD) changeStatus(id uint, userID uint, moderate bool, status string, publishAt *time.Time) (*Post, error) {
+	var post Post
+	if err := p.db.First(&post, id).Error; err != nil {
+		return nil, errors.New("文章不存在")
+	}
+
+	// 检查权限
+	if post.UserID != userID && !moderate {
+		return nil, errors.New("无权限修改此文章")
+	}
+
+	post.PreviousStatus = post.Status
+	if err := applyStatus(&post, status, publishAt); err != nil {
+		return nil, err
+	}
+
+	if err := p.db.Model(&post).Select("status", "published_at", "scheduled_at").Updates(&post).Error; err != nil {
+		return nil, errors.New("文章状态更新失败")
+	}
+
+	// 预加载关联信息
+	p.preload(&post)
+	return &post, nil
+}
+
+// PublishDue 发布所有已到发布时间的定时文章，返回本次发布的文章。
+// 逐条按状态条件更新，多实例同时运行时不会重复发布。
+func (p *PostCRUD) PublishDue(now time.Time) ([]Post, error) {
+	var due []Post
+	if err := p.db.Where("status = ? AND scheduled_at <= ?", PostStatusScheduled, now).Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	var published []Post
+	for i := range due {
+		post := &due[i]
+		result := p.db.Model(post).Where("status = ?", PostStatusScheduled).Updates(map[string]interface{}{
+			"status":       PostStatusPublished,
+			"published_at": post.ScheduledAt,
+			"scheduled_at": nil,
+		})
+		if result.Error != nil {
+			return published, result.Error
+		}
+		if result.RowsAffected > 0 {
+			p.preload(post)
+			published = append(published, *post)
+		}
+	}
+	return published, nil
+}
+
+// GetLastPost 获取对当前用户可见的最后一篇文章
+func (p *PostCRUD) GetLastPost(viewerID uint) (*Post, error) {
+	var post Post
+	if err := p.db.Scopes(visibleTo(viewerID)).Order("id DESC").First(&post).Error; err != nil {
 		return nil, errors.New("没有找到文章")
 	}
 
-	// 预加载用户信息
-	p.db.Preload("User").First(&post, post.ID)
+	// 预加载关联信息
+	p.preload(&post)
+	if err := p.fillInteractions(&post, viewerID); err != nil {
+		return nil, errors.New("获取文章失败")
+	}
 	return &post, nil
 }
 
+// ListFeed 获取最近发布的文章用于订阅源，authorID为0时返回全站文章
+func (p *PostCRUD) ListFeed(authorID uint, limit int) ([]Post, error) {
+	db := p.db.Scopes(visibleTo(0))
+	if authorID != 0 {
+		db = db.Where("posts.user_id = ?", authorID)
+	}
+
+	var posts []Post
+	if err := db.Preload("User").Preload("Category").Preload("Tags").
+		Order("posts.published_at DESC").Order("posts.id DESC").Limit(limit).Find(&posts).Error; err != nil {
+		return nil, errors.New("获取文章列表失败")
+	}
+	return posts, nil
+}
+
+// FeedUpdatedAt 获取订阅源的最后修改时间：文章的修改、撤回和删除都会更新该时间，authorID为0时统计全站文章
+func (p *PostCRUD) FeedUpdatedAt(authorID uint) (time.Time, error) {
+	db := p.db.Unscoped().Model(&Post{})
+	if authorID != 0 {
+		db = db.Where("user_id = ?", authorID)
+	}
+
+	var row struct {
+		Updated sql.NullTime
+		Deleted sql.NullTime
+	}
+	if err := db.Select("MAX(updated_at) AS updated, MAX(deleted_at) AS deleted").Scan(&row).Error; err != nil {
+		return time.Time{}, errors.New("获取文章列表失败")
+	}
+	if row.Deleted.Valid && row.Deleted.Time.After(row.Updated.Time) {
+		return row.Deleted.Time, nil
+	}
+	return row.Updated.Time, nil
+}
+
 // CommentCRUD 评论CRUD操作
 type CommentCRUD struct {
 	db *gorm.DB
@@ -177,26 +901,125 @@ func NewCommentCRUD(db *gorm.DB) *CommentCRUD {
 	return &CommentCRUD{db: db}
 }
 
-// GetByID 根据评论ID获取评论
-func (c *CommentCRUD) GetByID(id uint) (*Comment, error) {
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (c *CommentCRUD) WithContext(ctx context.Context) *CommentCRUD {
+	copied := *c
+	copied.db = c.db.WithContext(ctx)
+	return &copied
+}
+
+// GetByID 根据评论ID获取评论，viewerID用于返回该用户的点赞和表情回应状态
+func (c *CommentCRUD) GetByID(id uint, viewerID uint) (*Comment, error) {
 	var comment Comment
 	if err := c.db.Preload("User").Preload("Post").First(&comment, id).Error; err != nil {
 		return nil, errors.New("评论不存在")
 	}
-	return &comment, nil
+	comments := []Comment{comment}
+	if err := fillCommentInteractions(c.db, comments, viewerID); err != nil {
+		return nil, errors.New("获取评论失败")
+	}
+	return &comments[0], nil
+}
+
+// visibleTo 评论可见性条件：已通过审核的评论，或查看者自己的评论
+func (c *CommentCRUD) visibleTo(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(comments.status = ? OR comments.user_id = ?)", CommentStatusApproved, viewerID)
+	}
+}
+
+// GetByPostID 根据文章ID分页获取评论，按创建时间正序排列。
+// 返回平铺的评论列表，每条评论带有parent_id和depth，客户端可据此还原层级。
+// 未通过审核的评论只对评论者本人可见。
+func (c *CommentCRUD) GetByPostID(postID uint, q *PageQuery, viewerID uint) ([]Comment, *Pagination, error) {
+	db := c.db.Model(&Comment{}).Scopes(c.visibleTo(viewerID)).Where("comments.post_id = ?", postID)
+	comments, pagination, err := c.page(db, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := fillCommentInteractions(c.db, comments, viewerID); err != nil {
+		return nil, nil, errors.New("获取评论列表失败")
+	}
+	return comments, pagination, nil
 }
 
-// GetByPostID 根据文章ID获取评论
-func (c *CommentCRUD) GetByPostID(postID uint) ([]Comment, error) {
+// GetThreadsByPostID 根据文章ID分页获取顶级评论，每条顶级评论附带完整的回复树。
+// 未通过审核的评论(及其下的回复)只对评论者本人可见。
+func (c *CommentCRUD) GetThreadsByPostID(postID uint, q *PageQuery, viewerID uint) ([]Comment, *Pagination, error) {
+	db := c.db.Model(&Comment{}).Scopes(c.visibleTo(viewerID)).Where("comments.post_id = ? AND comments.parent_id IS NULL", postID)
+	roots, pagination, err := c.page(db, q)
+	if err != nil || len(roots) == 0 {
+		return roots, pagination, err
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	var replies []Comment
+	if err := c.db.Preload("User").Scopes(c.visibleTo(viewerID)).Where("root_id IN ?", rootIDs).
+		Order("created_at ASC").Order("id ASC").Find(&replies).Error; err != nil {
+		return nil, nil, errors.New("获取评论列表失败")
+	}
+
+	children := make(map[uint][]Comment)
+	for _, reply := range replies {
+		children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+	}
+
+	// 递归组装回复树
+	var attach func(node *Comment)
+	attach = func(node *Comment) {
+		node.Replies = children[node.ID]
+		for i := range node.Replies {
+			attach(&node.Replies[i])
+		}
+	}
+	for i := range roots {
+		attach(&roots[i])
+	}
+	if err := fillCommentInteractions(c.db, roots, viewerID); err != nil {
+		return nil, nil, errors.New("获取评论列表失败")
+	}
+
+	return roots, pagination, nil
+}
+
+// page 按创建时间正序分页查询评论
+func (c *CommentCRUD) page(db *gorm.DB, q *PageQuery) ([]Comment, *Pagination, error) {
+	q.normalize()
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取评论列表失败")
+	}
+
+	paged, err := paginate(db, q, "comments.created_at", "comments.id", false)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var comments []Comment
-	if err := c.db.Preload("User").Where("post_id = ?", postID).Order("created_at ASC").Find(&comments).Error; err != nil {
-		return nil, errors.New("获取评论列表失败")
+	if err := paged.Preload("User").Find(&comments).Error; err != nil {
+		return nil, nil, errors.New("获取评论列表失败")
+	}
+
+	pagination := buildPagination(q, total, len(comments), func() (time.Time, uint) {
+		last := comments[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(comments) > q.Size {
+		comments = comments[:q.Size]
 	}
-	return comments, nil
+
+	return comments, pagination, nil
 }
 
-// Create 创建评论
-func (c *CommentCRUD) Create(req *CommentRequest, userID uint, postID uint) (*Comment, error) {
+// Create 创建评论，提供parent_id时作为回复，回复层级不能超过MaxCommentDepth。
+// meta记录评论来源和垃圾评论评分结果，其中的Status决定评论是否需要审核。
+func (c *CommentCRUD) Create(req *CommentRequest, userID uint, postID uint, meta *CommentMeta) (*Comment, error) {
 	// 检查文章是否存在
 	var post Post
 	if err := c.db.First(&post, postID).Error; err != nil {
@@ -204,9 +1027,37 @@ func (c *CommentCRUD) Create(req *CommentRequest, userID uint, postID uint) (*Co
 	}
 
 	comment := Comment{
-		Content: req.Content,
-		UserID:  userID,
-		PostID:  postID,
+		Content:     req.Content,
+		ContentHTML: markdown.RenderComment(req.Content),
+		UserID:      userID,
+		PostID:      postID,
+		Status:      CommentStatusApproved,
+		IPAddress:   meta.IPAddress,
+		UserAgent:   meta.UserAgent,
+		SpamScore:   meta.SpamScore,
+		SpamReason:  meta.SpamReason,
+	}
+	if meta.Status != "" {
+		comment.Status = meta.Status
+	}
+
+	if req.ParentID != nil {
+		// 只能回复自己可见的评论
+		var parent Comment
+		if err := c.db.Scopes(c.visibleTo(userID)).Where("id = ? AND post_id = ?", *req.ParentID, postID).First(&parent).Error; err != nil {
+			return nil, errors.New("父评论不存在")
+		}
+		if parent.Depth+1 > MaxCommentDepth {
+			return nil, errors.New("回复层级超过限制")
+		}
+
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = &rootID
+		comment.Depth = parent.Depth + 1
 	}
 
 	if err := c.db.Create(&comment).Error; err != nil {
@@ -218,19 +1069,27 @@ func (c *CommentCRUD) Create(req *CommentRequest, userID uint, postID uint) (*Co
 	return &comment, nil
 }
 
-// Update 更新评论
-func (c *CommentCRUD) Update(id uint, req *CommentRequest, userID uint) (*Comment, error) {
+// Update 更新评论，moderate为true时允许修改他人的评论。
+// meta不为nil且内容发生变化时，按新内容的评分结果更新审核状态。
+func (c *CommentCRUD) Update(id uint, req *CommentRequest, userID uint, moderate bool, meta *CommentMeta) (*Comment, error) {
 	var comment Comment
 	if err := c.db.First(&comment, id).Error; err != nil {
 		return nil, errors.New("评论不存在")
 	}
 
-	// 检查权限：只有评论作者可以更新
-	if comment.UserID != userID {
+	// 检查权限：评论作者或版主可以更新
+	if comment.UserID != userID && !moderate {
 		return nil, errors.New("权限不足")
 	}
 
+	// 内容未变化时保留原审核状态，避免与自身重复的内容被误判
+	if meta != nil && comment.Content != req.Content {
+		comment.Status = meta.Status
+		comment.SpamScore = meta.SpamScore
+		comment.SpamReason = meta.SpamReason
+	}
 	comment.Content = req.Content
+	comment.ContentHTML = markdown.RenderComment(req.Content)
 	if err := c.db.Save(&comment).Error; err != nil {
 		return nil, errors.New("评论更新失败")
 	}
@@ -240,21 +1099,100 @@ func (c *CommentCRUD) Update(id uint, req *CommentRequest, userID uint) (*Commen
 	return &comment, nil
 }
 
-// Delete 删除评论
-func (c *CommentCRUD) Delete(id uint, userID uint) error {
+// Delete 删除评论，moderate为true时允许删除他人的评论
+func (c *CommentCRUD) Delete(id uint, userID uint, moderate bool) (*Comment, error) {
 	var comment Comment
 	if err := c.db.First(&comment, id).Error; err != nil {
-		return errors.New("评论不存在")
+		return nil, errors.New("评论不存在")
 	}
 
-	// 检查权限：只有评论作者可以删除
-	if comment.UserID != userID {
-		return errors.New("权限不足")
+	// 检查权限：评论作者或版主可以删除
+	if comment.UserID != userID && !moderate {
+		return nil, errors.New("权限不足")
 	}
 
-	if err := c.db.Delete(&comment).Error; err != nil {
-		return errors.New("评论删除失败")
+	// 删除评论时一并删除其下的所有回复，同一次删除的评论具有相同的删除时间
+	subtree, err := c.subtree(c.db, &comment)
+	if err != nil {
+		return nil, errors.New("评论删除失败")
+	}
+	err = transaction(c.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&subtree).UpdateColumn("deleted_by", userID).Error; err != nil {
+			return errors.New("评论删除失败")
+		}
+		if err := tx.Delete(&subtree).Error; err != nil {
+			return errors.New("评论删除失败")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Restore 从回收站恢复评论及与其一同删除的回复。
+// 评论者只能恢复自己删除的评论，moderate为true时可恢复任意评论；父评论或文章已删除时需先恢复它们。
+func (c *CommentCRUD) Restore(id uint, userID uint, moderate bool) (*Comment, error) {
+	var comment Comment
+	if err := c.db.Unscoped().Where("deleted_at IS NOT NULL").First(&comment, id).Error; err != nil {
+		return nil, errors.New("回收站中不存在该评论")
 	}
 
-	return nil
+	if !moderate && (comment.UserID != userID || (comment.DeletedBy != nil && *comment.DeletedBy != userID)) {
+		return nil, errors.New("无权限恢复此评论")
+	}
+
+	var count int64
+	c.db.Model(&Post{}).Where("id = ?", comment.PostID).Count(&count)
+	if count == 0 {
+		return nil, errors.New("文章已删除，请先恢复文章")
+	}
+	if comment.ParentID != nil {
+		c.db.Model(&Comment{}).Where("id = ?", *comment.ParentID).Count(&count)
+		if count == 0 {
+			return nil, errors.New("父评论已删除，请先恢复父评论")
+		}
+	}
+
+	subtree, err := c.subtree(c.db.Unscoped().Where("deleted_at = ?", comment.DeletedAt.Time), &comment)
+	if err != nil {
+		return nil, errors.New("评论恢复失败")
+	}
+	if err := c.db.Unscoped().Model(&subtree).UpdateColumns(map[string]interface{}{
+		"deleted_at": nil,
+		"deleted_by": nil,
+	}).Error; err != nil {
+		return nil, errors.New("评论恢复失败")
+	}
+
+	// 预加载用户信息
+	c.db.Preload("User").First(&comment, comment.ID)
+	return &comment, nil
+}
+
+// subtree 在db的查询范围内获取评论及其所有子孙回复
+func (c *CommentCRUD) subtree(db *gorm.DB, comment *Comment) ([]Comment, error) {
+	rootID := comment.ID
+	if comment.RootID != nil {
+		rootID = *comment.RootID
+	}
+
+	var thread []Comment
+	if err := db.Select("id", "parent_id").Where("root_id = ?", rootID).Find(&thread).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, reply := range thread {
+		children[*reply.ParentID] = append(children[*reply.ParentID], reply.ID)
+	}
+
+	result := []Comment{{ID: comment.ID}}
+	for i := 0; i < len(result); i++ {
+		for _, id := range children[result[i].ID] {
+			result = append(result, Comment{ID: id})
+		}
+	}
+	return result, nil
 }
@@ -0,0 +1,297 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 首页动态类型
+const (
+	FeedItemPost     = "post"     // 关注的作者发布了文章
+	FeedItemBookmark = "bookmark" // 关注的用户将文章收藏到公开收藏夹
+)
+
+// Follow 用户关注关系
+type Follow struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follow_pair;comment:关注者ID" json:"follower_id"`
+	FolloweeID uint      `gorm:"not null;uniqueIndex:idx_follow_pair;index;comment:被关注者ID" json:"followee_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;comment:关注时间" json:"created_at"`
+
+	// 多对一关系：关注双方都是用户
+	Follower User `gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE" json:"-"`
+	Followee User `gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// UserSummary 关注和粉丝列表中的用户信息
+type UserSummary struct {
+	ID         uint       `json:"id"`
+	Username   string     `json:"username"`
+	Nickname   string     `json:"nickname"`
+	Avatar     string     `json:"avatar"`
+	FollowedAt *time.Time `json:"followed_at,omitempty"`
+
+	FollowID uint `json:"-"`
+}
+
+// Summary 返回用户的公开摘要信息，不包含邮箱、角色等字段
+func (u *User) Summary() UserSummary {
+	return UserSummary{ID: u.ID, Username: u.Username, Nickname: u.Nickname, Avatar: u.Avatar}
+}
+
+// FeedItem 首页动态：关注的作者发布的文章，或关注的用户收藏的文章
+type FeedItem struct {
+	Type       string       `json:"type"`
+	At         time.Time    `json:"at"`
+	Actor      *UserSummary `json:"actor"`
+	Post       *Post        `json:"post"`
+	Collection string       `json:"collection,omitempty"`
+}
+
+// FollowCRUD 关注关系和首页动态操作
+type FollowCRUD struct {
+	db *gorm.DB
+}
+
+// NewFollowCRUD 创建关注关系操作实例
+func NewFollowCRUD(db *gorm.DB) *FollowCRUD {
+	return &FollowCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (f *FollowCRUD) WithContext(ctx context.Context) *FollowCRUD {
+	copied := *f
+	copied.db = f.db.WithContext(ctx)
+	return &copied
+}
+
+// Follow 关注用户，重复关注不产生影响
+func (f *FollowCRUD) Follow(followerID, followeeID uint) error {
+	if followerID == followeeID {
+		return errors.New("不能关注自己")
+	}
+	if err := f.db.Omit("Follower", "Followee").Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Follow{FollowerID: followerID, FolloweeID: followeeID}).Error; err != nil {
+		return errors.New("关注失败")
+	}
+	return nil
+}
+
+// Unfollow 取消关注，未关注时不产生影响
+func (f *FollowCRUD) Unfollow(followerID, followeeID uint) error {
+	if err := f.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&Follow{}).Error; err != nil {
+		return errors.New("取消关注失败")
+	}
+	return nil
+}
+
+// IsFollowing 判断是否已关注
+func (f *FollowCRUD) IsFollowing(followerID, followeeID uint) bool {
+	var count int64
+	f.db.Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count)
+	return count > 0
+}
+
+// ListFollowers 分页获取用户的粉丝，按关注时间倒序排列，不包含停用和已删除的用户
+func (f *FollowCRUD) ListFollowers(userID uint, q *PageQuery) ([]UserSummary, *Pagination, error) {
+	return f.list("follows.follower_id", "follows.followee_id", userID, q)
+}
+
+// ListFollowing 分页获取用户关注的人，按关注时间倒序排列，不包含停用和已删除的用户
+func (f *FollowCRUD) ListFollowing(userID uint, q *PageQuery) ([]UserSummary, *Pagination, error) {
+	return f.list("follows.followee_id", "follows.follower_id", userID, q)
+}
+
+func (f *FollowCRUD) list(userColumn, filterColumn string, userID uint, q *PageQuery) ([]UserSummary, *Pagination, error) {
+	q.normalize()
+
+	db := f.db.Table("follows").
+		Joins("JOIN users ON users.id = "+userColumn+" AND users.is_active = ? AND users.deleted_at IS NULL", true).
+		Where(filterColumn+" = ?", userID).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取关注列表失败")
+	}
+
+	paged, err := paginate(db, q, "follows.created_at", "follows.id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var users []UserSummary
+	if err := paged.Select("users.id, users.username, users.nickname, users.avatar, follows.created_at AS followed_at, follows.id AS follow_id").
+		Scan(&users).Error; err != nil {
+		return nil, nil, errors.New("获取关注列表失败")
+	}
+
+	pagination := buildPagination(q, total, len(users), func() (time.Time, uint) {
+		last := users[q.Size-1]
+		return *last.FollowedAt, last.FollowID
+	})
+	if len(users) > q.Size {
+		users = users[:q.Size]
+	}
+	return users, pagination, nil
+}
+
+// Counts 获取用户的粉丝数和关注数，不包含停用和已删除的用户
+func (f *FollowCRUD) Counts(userID uint) (followers, following int64, err error) {
+	count := func(userColumn, filterColumn string) (int64, error) {
+		var n int64
+		err := f.db.Table("follows").
+			Joins("JOIN users ON users.id = "+userColumn+" AND users.is_active = ? AND users.deleted_at IS NULL", true).
+			Where(filterColumn+" = ?", userID).Count(&n).Error
+		return n, err
+	}
+	if followers, err = count("follows.follower_id", "follows.followee_id"); err != nil {
+		return 0, 0, errors.New("获取关注数失败")
+	}
+	if following, err = count("follows.followee_id", "follows.follower_id"); err != nil {
+		return 0, 0, errors.New("获取关注数失败")
+	}
+	return followers, following, nil
+}
+
+// feedRow 首页动态的合并查询结果。seq由来源记录的ID生成(文章为2n，收藏为2n+1)，
+// 保证两类动态合并后仍有唯一且稳定的排序键，可以复用游标分页
+type feedRow struct {
+	Type         string
+	Seq          uint
+	PostID       uint
+	ActorID      uint
+	CollectionID uint
+	At           time.Time
+}
+
+// HomeFeed 分页获取用户的首页动态：关注的作者最近发布的文章，以及关注的用户收藏到公开收藏夹的文章，按时间倒序合并
+func (f *FollowCRUD) HomeFeed(userID uint, q *PageQuery) ([]FeedItem, *Pagination, error) {
+	q.normalize()
+
+	followees := f.db.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", userID)
+	published := func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.status = ? AND posts.deleted_at IS NULL", PostStatusPublished)
+	}
+
+	posts := f.db.Table("posts").Scopes(published).
+		Select("? AS type, posts.id * 2 AS seq, posts.id AS post_id, posts.user_id AS actor_id, 0 AS collection_id, posts.published_at AS at", FeedItemPost).
+		Where("posts.user_id IN (?)", followees)
+	bookmarks := f.db.Table("bookmarks").
+		Joins("JOIN bookmark_collections ON bookmark_collections.id = bookmarks.collection_id AND bookmark_collections.public = ?", true).
+		Joins("JOIN posts ON posts.id = bookmarks.post_id").Scopes(published).
+		Select("? AS type, bookmarks.id * 2 + 1 AS seq, bookmarks.post_id AS post_id, bookmarks.user_id AS actor_id, bookmarks.collection_id AS collection_id, bookmarks.created_at AS at", FeedItemBookmark).
+		Where("bookmarks.user_id IN (?)", followees)
+
+	db := f.db.Table("(? UNION ALL ?) AS feed", posts, bookmarks).Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取动态失败")
+	}
+
+	paged, err := paginate(db, q, "feed.at", "feed.seq", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []feedRow
+	if err := paged.Select("feed.*").Scan(&rows).Error; err != nil {
+		return nil, nil, errors.New("获取动态失败")
+	}
+
+	pagination := buildPagination(q, total, len(rows), func() (time.Time, uint) {
+		last := rows[q.Size-1]
+		return last.At, last.Seq
+	})
+	if len(rows) > q.Size {
+		rows = rows[:q.Size]
+	}
+
+	items, err := f.loadFeedItems(rows, userID)
+	if err != nil {
+		return nil, nil, errors.New("获取动态失败")
+	}
+	return items, pagination, nil
+}
+
+// loadFeedItems 批量加载动态引用的文章、用户和收藏夹
+func (f *FollowCRUD) loadFeedItems(rows []feedRow, viewerID uint) ([]FeedItem, error) {
+	postIDs := make([]uint, 0, len(rows))
+	actorIDs := make([]uint, 0, len(rows))
+	collectionIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		postIDs = append(postIDs, row.PostID)
+		actorIDs = append(actorIDs, row.ActorID)
+		if row.CollectionID != 0 {
+			collectionIDs = append(collectionIDs, row.CollectionID)
+		}
+	}
+
+	var posts []Post
+	if err := f.db.Preload("User").Preload("Category").Preload("Tags").Where("id IN ?", postIDs).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	if err := fillPostInteractions(f.db, posts, viewerID); err != nil {
+		return nil, err
+	}
+	postByID := make(map[uint]*Post, len(posts))
+	for i := range posts {
+		postByID[posts[i].ID] = &posts[i]
+	}
+
+	actorByID, err := loadUserSummaries(f.db, actorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	collectionNames := make(map[uint]string)
+	if len(collectionIDs) > 0 {
+		var collections []BookmarkCollection
+		if err := f.db.Select("id", "name").Where("id IN ?", collectionIDs).Find(&collections).Error; err != nil {
+			return nil, err
+		}
+		for _, collection := range collections {
+			collectionNames[collection.ID] = collection.Name
+		}
+	}
+
+	items := make([]FeedItem, 0, len(rows))
+	for _, row := range rows {
+		item := FeedItem{
+			Type:       row.Type,
+			At:         row.At,
+			Actor:      actorByID[row.ActorID],
+			Post:       postByID[row.PostID],
+			Collection: collectionNames[row.CollectionID],
+		}
+		// 合并查询和加载之间文章可能已被删除
+		if item.Post == nil || item.Actor == nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// loadUserSummaries 批量加载用户信息，返回以用户ID为键的映射
+func loadUserSummaries(db *gorm.DB, ids []uint) (map[uint]*UserSummary, error) {
+	summaries := make(map[uint]*UserSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	var users []User
+	if err := db.Select("id", "username", "nickname", "avatar").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		summary := user.Summary()
+		summaries[user.ID] = &summary
+	}
+	return summaries, nil
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Media 用户上传的媒体文件。Size包含缩略图大小，计入用户的存储配额
+type Media struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint      `gorm:"not null;index;comment:上传者ID" json:"user_id"`
+	Filename     string    `gorm:"size:255;comment:原始文件名" json:"filename"`
+	ContentType  string    `gorm:"not null;size:100;comment:文件类型" json:"content_type"`
+	Size         int64     `gorm:"not null;comment:占用空间(字节)" json:"size"`
+	Width        int       `gorm:"default:0;comment:图片宽度" json:"width,omitempty"`
+	Height       int       `gorm:"default:0;comment:图片高度" json:"height,omitempty"`
+	StorageKey   string    `gorm:"not null;uniqueIndex;size:255;comment:存储路径" json:"-"`
+	ThumbnailKey string    `gorm:"size:255;comment:缩略图存储路径" json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;comment:上传时间" json:"created_at"`
+
+	// 访问地址由存储后端生成，不保存到数据库
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnail_url,omitempty"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// StorageUsage 用户的存储空间使用情况，Quota为0表示不限制
+type StorageUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+// MediaCRUD 媒体文件CRUD操作
+type MediaCRUD struct {
+	db *gorm.DB
+}
+
+// NewMediaCRUD 创建媒体文件CRUD实例
+func NewMediaCRUD(db *gorm.DB) *MediaCRUD {
+	return &MediaCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (m *MediaCRUD) WithContext(ctx context.Context) *MediaCRUD {
+	copied := *m
+	copied.db = m.db.WithContext(ctx)
+	return &copied
+}
+
+// Create 保存媒体文件记录并占用上传者的存储空间，quota大于0时超出配额返回错误。
+// 配额检查和占用在同一条UPDATE语句中完成，并发上传也不会超出配额。
+func (m *MediaCRUD) Create(media *Media, quota int64) error {
+	return transaction(m.db, func(tx *gorm.DB) error {
+		db := tx.Model(&User{}).Where("id = ?", media.UserID)
+		if quota > 0 {
+			db = db.Where("storage_used + ? <= ?", media.Size, quota)
+		}
+		result := db.UpdateColumn("storage_used", gorm.Expr("storage_used + ?", media.Size))
+		if result.Error != nil {
+			return errors.New("文件保存失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("存储空间不足")
+		}
+
+		if err := tx.Omit("User").Create(media).Error; err != nil {
+			return errors.New("文件保存失败")
+		}
+		return nil
+	})
+}
+
+// GetByID 根据ID获取媒体文件
+func (m *MediaCRUD) GetByID(id uint) (*Media, error) {
+	var media Media
+	if err := m.db.First(&media, id).Error; err != nil {
+		return nil, errors.New("文件不存在")
+	}
+	return &media, nil
+}
+
+// List 分页获取用户上传的媒体文件，按上传时间倒序排列
+func (m *MediaCRUD) List(userID uint, q *PageQuery) ([]Media, *Pagination, error) {
+	q.normalize()
+	db := m.db.Model(&Media{}).Where("media.user_id = ?", userID).Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取文件列表失败")
+	}
+
+	paged, err := paginate(db, q, "media.created_at", "media.id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var items []Media
+	if err := paged.Find(&items).Error; err != nil {
+		return nil, nil, errors.New("获取文件列表失败")
+	}
+
+	pagination := buildPagination(q, total, len(items), func() (time.Time, uint) {
+		last := items[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(items) > q.Size {
+		items = items[:q.Size]
+	}
+	return items, pagination, nil
+}
+
+// Usage 获取用户已使用的存储空间
+func (m *MediaCRUD) Usage(userID uint) (int64, error) {
+	var user User
+	if err := m.db.Select("id", "storage_used").First(&user, userID).Error; err != nil {
+		return 0, errors.New("用户不存在")
+	}
+	return user.StorageUsed, nil
+}
+
+// Delete 删除媒体文件记录并释放存储空间，moderate为true时允许删除他人的文件。
+// 返回被删除的记录，由调用方删除存储中的文件。
+func (m *MediaCRUD) Delete(id uint, userID uint, moderate bool) (*Media, error) {
+	media, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查权限
+	if media.UserID != userID && !moderate {
+		return nil, errors.New("无权限删除此文件")
+	}
+
+	err = transaction(m.db, func(tx *gorm.DB) error {
+		if err := tx.Delete(media).Error; err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", media.UserID).
+			UpdateColumn("storage_used", gorm.Expr("CASE WHEN storage_used > ? THEN storage_used - ? ELSE 0 END", media.Size, media.Size)).Error
+	})
+	if err != nil {
+		return nil, errors.New("文件删除失败")
+	}
+	return media, nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ModerationItem 审核队列中的评论，附带来源和评分信息
+type ModerationItem struct {
+	Comment
+	IPAddress   string     `json:"ip_address"`
+	UserAgent   string     `json:"user_agent"`
+	SpamScore   float64    `json:"spam_score"`
+	SpamReason  string     `json:"spam_reason"`
+	ModeratedBy *uint      `json:"moderated_by,omitempty"`
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+}
+
+// moderationActions 审核动作与评论状态的对应关系
+var moderationActions = map[string]string{
+	"approve": CommentStatusApproved,
+	"reject":  CommentStatusRejected,
+	"spam":    CommentStatusSpam,
+}
+
+// ListForModeration 分页获取指定状态的评论，默认返回待审核评论
+func (c *CommentCRUD) ListForModeration(q *ModerationQuery) ([]ModerationItem, *Pagination, error) {
+	status := q.Status
+	if status == "" {
+		status = CommentStatusPending
+	}
+
+	db := c.db.Model(&Comment{}).Where("comments.status = ?", status)
+	comments, pagination, err := c.page(db, &q.PageQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]ModerationItem, len(comments))
+	for i, comment := range comments {
+		items[i] = ModerationItem{
+			Comment:     comment,
+			IPAddress:   comment.IPAddress,
+			UserAgent:   comment.UserAgent,
+			SpamScore:   comment.SpamScore,
+			SpamReason:  comment.SpamReason,
+			ModeratedBy: comment.ModeratedBy,
+			ModeratedAt: comment.ModeratedAt,
+		}
+	}
+	return items, pagination, nil
+}
+
+// Moderate 批量审核评论，返回审核的评论，PreviousStatus为审核前的状态
+func (c *CommentCRUD) Moderate(ids []uint, action string, moderatorID uint) ([]Comment, error) {
+	status, ok := moderationActions[action]
+	if !ok {
+		return nil, errors.New("无效的审核动作")
+	}
+
+	// 先按主键加载，使更新回调(如搜索索引)能拿到具体的评论ID，
+	// 返回的评论也用于发布业务事件
+	var comments []Comment
+	if err := c.db.Preload("User").Where("id IN ?", ids).Find(&comments).Error; err != nil {
+		return nil, errors.New("评论审核失败")
+	}
+	if len(comments) == 0 {
+		return nil, errors.New("评论不存在")
+	}
+
+	// Updates会把更新的字段写回comments，需要先记录审核前的状态
+	for i := range comments {
+		comments[i].PreviousStatus = comments[i].Status
+	}
+	now := time.Now()
+	// 评论预加载了用户，更新时忽略关联，避免重新写入用户记录
+	if err := c.db.Model(&comments).Omit(clause.Associations).Updates(map[string]interface{}{
+		"status":       status,
+		"moderated_by": moderatorID,
+		"moderated_at": now,
+	}).Error; err != nil {
+		return nil, errors.New("评论审核失败")
+	}
+
+	for i := range comments {
+		comments[i].Status = status
+		comments[i].ModeratedBy = &moderatorID
+		comments[i].ModeratedAt = &now
+	}
+	return comments, nil
+}
+
+// CountSameContent 统计指定时间之后同一用户或同一IP发布的相同内容评论数量
+func (c *CommentCRUD) CountSameContent(content string, userID uint, ip string, since time.Time) (int64, error) {
+	var count int64
+	err := c.db.Model(&Comment{}).
+		Where("created_at >= ? AND (user_id = ? OR (ip_address <> '' AND ip_address = ?))", since, userID, ip).
+		Where("TRIM(content) = ?", content).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByIP 统计指定时间之后同一IP发布的评论数量
+func (c *CommentCRUD) CountByIP(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := c.db.Model(&Comment{}).
+		Where("ip_address = ? AND created_at >= ?", ip, since).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,356 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"blog-system/markdown"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 通知类型
+const (
+	NotificationComment = "comment" // 文章收到评论
+	NotificationReply   = "reply"   // 评论收到回复
+	NotificationLike    = "like"    // 文章或评论被点赞
+	NotificationFollow  = "follow"  // 被关注
+)
+
+// NotificationTypes 全部通知类型，用于校验和返回通知偏好
+var NotificationTypes = []string{NotificationComment, NotificationReply, NotificationLike, NotificationFollow}
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notification_user,priority:1;comment:接收者ID" json:"-"`
+	ActorID   uint       `gorm:"not null;comment:触发者ID" json:"-"`
+	Type      string     `gorm:"not null;size:20;comment:通知类型" json:"type"`
+	PostID    uint       `gorm:"default:0;comment:文章ID" json:"post_id,omitempty"`
+	CommentID uint       `gorm:"default:0;comment:评论ID" json:"comment_id,omitempty"`
+	PostTitle string     `gorm:"size:200;comment:文章标题" json:"post_title,omitempty"`
+	Excerpt   string     `gorm:"size:500;comment:评论摘要" json:"excerpt,omitempty"`
+	ReadAt    *time.Time `gorm:"index;comment:已读时间" json:"read_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;index:idx_notification_user,priority:2;comment:创建时间" json:"created_at"`
+
+	Actor *UserSummary `gorm:"-" json:"actor"`
+	User  User         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// NotificationMute 用户关闭的通知类型
+type NotificationMute struct {
+	UserID uint   `gorm:"primaryKey;comment:用户ID"`
+	Type   string `gorm:"primaryKey;size:20;comment:通知类型"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// NotificationQuery 通知列表查询参数
+type NotificationQuery struct {
+	PageQuery
+	Unread bool `form:"unread"`
+}
+
+// NotificationCRUD 站内通知操作
+type NotificationCRUD struct {
+	db *gorm.DB
+}
+
+// NewNotificationCRUD 创建通知CRUD实例
+func NewNotificationCRUD(db *gorm.DB) *NotificationCRUD {
+	return &NotificationCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (n *NotificationCRUD) WithContext(ctx context.Context) *NotificationCRUD {
+	copied := *n
+	copied.db = n.db.WithContext(ctx)
+	return &copied
+}
+
+// NotifyComment 评论发布后通知文章作者，回复还会通知被回复评论的作者，返回生成的通知。待审核的评论不产生通知
+func (n *NotificationCRUD) NotifyComment(comment *Comment) ([]Notification, error) {
+	if comment.Status != CommentStatusApproved {
+		return nil, nil
+	}
+
+	var post Post
+	if err := n.db.Select("id", "user_id", "title").First(&post, comment.PostID).Error; err != nil {
+		return nil, err
+	}
+
+	base := Notification{
+		ActorID:   comment.UserID,
+		PostID:    post.ID,
+		CommentID: comment.ID,
+		PostTitle: post.Title,
+		Excerpt:   markdown.Summary(comment.ContentHTML),
+	}
+
+	notifications := make([]Notification, 0, 2)
+	if comment.ParentID != nil {
+		var parent Comment
+		if err := n.db.Select("id", "user_id").First(&parent, *comment.ParentID).Error; err == nil {
+			reply := base
+			reply.UserID, reply.Type = parent.UserID, NotificationReply
+			notifications = append(notifications, reply)
+		}
+	}
+
+	// 文章作者同时是被回复者时只收到回复通知
+	if len(notifications) == 0 || notifications[0].UserID != post.UserID {
+		notification := base
+		notification.UserID, notification.Type = post.UserID, NotificationComment
+		notifications = append(notifications, notification)
+	}
+	return n.create(notifications...)
+}
+
+// NotifyLike 点赞后通知文章或评论的作者，返回生成的通知
+func (n *NotificationCRUD) NotifyLike(targetType string, targetID, actorID uint) ([]Notification, error) {
+	notification := Notification{ActorID: actorID, Type: NotificationLike}
+
+	switch targetType {
+	case TargetPost:
+		var post Post
+		if err := n.db.Select("id", "user_id", "title").First(&post, targetID).Error; err != nil {
+			return nil, err
+		}
+		notification.UserID, notification.PostID, notification.PostTitle = post.UserID, post.ID, post.Title
+	case TargetComment:
+		var comment Comment
+		if err := n.db.Select("id", "user_id", "post_id", "content_html").First(&comment, targetID).Error; err != nil {
+			return nil, err
+		}
+		var post Post
+		if err := n.db.Select("id", "title").First(&post, comment.PostID).Error; err != nil {
+			return nil, err
+		}
+		notification.UserID, notification.PostID, notification.CommentID = comment.UserID, post.ID, comment.ID
+		notification.PostTitle, notification.Excerpt = post.Title, markdown.Summary(comment.ContentHTML)
+	default:
+		return nil, errors.New("无效的点赞对象")
+	}
+	return n.create(notification)
+}
+
+// NotifyFollow 关注后通知被关注的用户，返回生成的通知
+func (n *NotificationCRUD) NotifyFollow(followerID, followeeID uint) ([]Notification, error) {
+	return n.create(Notification{UserID: followeeID, ActorID: followerID, Type: NotificationFollow})
+}
+
+// create 保存通知并填充触发者信息，返回实际保存的通知
+func (n *NotificationCRUD) create(candidates ...Notification) ([]Notification, error) {
+	created := make([]Notification, 0, len(candidates))
+	for _, notification := range candidates {
+		ok, err := n.shouldNotify(&notification)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if err := n.db.Omit("User").Create(&notification).Error; err != nil {
+			return nil, err
+		}
+		created = append(created, notification)
+	}
+
+	if err := n.fillActors(created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// shouldNotify 判断是否需要保存通知。不通知用户自己的操作，跳过接收者关闭的通知类型；
+// 点赞和关注可以反复取消再操作，同一触发者对同一对象只通知一次
+func (n *NotificationCRUD) shouldNotify(notification *Notification) (bool, error) {
+	if notification.UserID == 0 || notification.UserID == notification.ActorID {
+		return false, nil
+	}
+
+	var muted int64
+	if err := n.db.Model(&NotificationMute{}).
+		Where("user_id = ? AND type = ?", notification.UserID, notification.Type).Count(&muted).Error; err != nil {
+		return false, err
+	}
+	if muted > 0 {
+		return false, nil
+	}
+
+	if notification.Type == NotificationLike || notification.Type == NotificationFollow {
+		var exists int64
+		if err := n.db.Model(&Notification{}).
+			Where("user_id = ? AND actor_id = ? AND type = ? AND post_id = ? AND comment_id = ?",
+				notification.UserID, notification.ActorID, notification.Type, notification.PostID, notification.CommentID).
+			Count(&exists).Error; err != nil {
+			return false, err
+		}
+		if exists > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// List 分页获取用户的通知，按时间倒序排列
+func (n *NotificationCRUD) List(userID uint, q *NotificationQuery) ([]Notification, *Pagination, error) {
+	q.normalize()
+
+	db := n.db.Model(&Notification{}).Where("user_id = ?", userID)
+	if q.Unread {
+		db = db.Where("read_at IS NULL")
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取通知失败")
+	}
+
+	paged, err := paginate(db, &q.PageQuery, "created_at", "id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var notifications []Notification
+	if err := paged.Find(&notifications).Error; err != nil {
+		return nil, nil, errors.New("获取通知失败")
+	}
+
+	pagination := buildPagination(&q.PageQuery, total, len(notifications), func() (time.Time, uint) {
+		last := notifications[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(notifications) > q.Size {
+		notifications = notifications[:q.Size]
+	}
+
+	if err := n.fillActors(notifications); err != nil {
+		return nil, nil, errors.New("获取通知失败")
+	}
+	return notifications, pagination, nil
+}
+
+// GetByID 获取用户的单条通知
+func (n *NotificationCRUD) GetByID(id, userID uint) (*Notification, error) {
+	var notification Notification
+	if err := n.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		return nil, errors.New("通知不存在")
+	}
+	notifications := []Notification{notification}
+	if err := n.fillActors(notifications); err != nil {
+		return nil, errors.New("获取通知失败")
+	}
+	return &notifications[0], nil
+}
+
+// fillActors 批量填充通知的触发者信息
+func (n *NotificationCRUD) fillActors(notifications []Notification) error {
+	ids := make([]uint, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.ActorID)
+	}
+	actors, err := loadUserSummaries(n.db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range notifications {
+		notifications[i].Actor = actors[notifications[i].ActorID]
+	}
+	return nil
+}
+
+// UnreadCount 获取用户的未读通知数量
+func (n *NotificationCRUD) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	if err := n.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, errors.New("获取未读通知数量失败")
+	}
+	return count, nil
+}
+
+// MarkRead 将通知标记为已读，已读的通知保持原来的已读时间
+func (n *NotificationCRUD) MarkRead(id, userID uint) (*Notification, error) {
+	if _, err := n.GetByID(id, userID); err != nil {
+		return nil, err
+	}
+	if err := n.db.Model(&Notification{}).Where("id = ? AND read_at IS NULL", id).
+		UpdateColumn("read_at", time.Now()).Error; err != nil {
+		return nil, errors.New("标记已读失败")
+	}
+	return n.GetByID(id, userID)
+}
+
+// MarkAllRead 将用户的全部未读通知标记为已读，返回标记的数量
+func (n *NotificationCRUD) MarkAllRead(userID uint) (int64, error) {
+	result := n.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		UpdateColumn("read_at", time.Now())
+	if result.Error != nil {
+		return 0, errors.New("标记已读失败")
+	}
+	return result.RowsAffected, nil
+}
+
+// Preferences 获取用户的通知偏好，键为通知类型，值为是否接收
+func (n *NotificationCRUD) Preferences(userID uint) (map[string]bool, error) {
+	var muted []string
+	if err := n.db.Model(&NotificationMute{}).Where("user_id = ?", userID).Pluck("type", &muted).Error; err != nil {
+		return nil, errors.New("获取通知偏好失败")
+	}
+
+	prefs := make(map[string]bool, len(NotificationTypes))
+	for _, t := range NotificationTypes {
+		prefs[t] = true
+	}
+	for _, t := range muted {
+		prefs[t] = false
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences 修改用户的通知偏好，未提供的类型保持不变
+func (n *NotificationCRUD) UpdatePreferences(userID uint, prefs map[string]bool) (map[string]bool, error) {
+	for t := range prefs {
+		if !isNotificationType(t) {
+			return nil, errors.New("无效的通知类型: " + t)
+		}
+	}
+
+	err := transaction(n.db, func(tx *gorm.DB) error {
+		for t, enabled := range prefs {
+			if enabled {
+				if err := tx.Where("user_id = ? AND type = ?", userID, t).Delete(&NotificationMute{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&NotificationMute{UserID: userID, Type: t}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("修改通知偏好失败")
+	}
+	return n.Preferences(userID)
+}
+
+// Purge 永久删除指定时间之前的已读通知
+func (n *NotificationCRUD) Purge(before time.Time) (int64, error) {
+	result := n.db.Where("read_at IS NOT NULL AND created_at < ?", before).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
+
+func isNotificationType(t string) bool {
+	for _, known := range NotificationTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// PageQuery 分页查询参数，cursor不为空时使用游标分页，否则使用页码分页
+type PageQuery struct {
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Size   int    `form:"size" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+// Pagination 分页元数据
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size"`
+	Total      int64  `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// normalize 填充分页参数的默认值
+func (q *PageQuery) normalize() {
+	if q.Size <= 0 {
+		q.Size = defaultPageSize
+	}
+	if q.Size > maxPageSize {
+		q.Size = maxPageSize
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+}
+
+// cursor 游标内容：排序字段的值和ID，保证排序稳定
+type cursor struct {
+	Value time.Time `json:"v"`
+	ID    uint      `json:"id"`
+}
+
+// encodeCursor 将游标编码为不透明字符串
+func encodeCursor(value time.Time, id uint) string {
+	b, _ := json.Marshal(cursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor 解析不透明游标字符串
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("无效的分页游标")
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, errors.New("无效的分页游标")
+	}
+	return &c, nil
+}
+
+// paginate 对查询应用分页和排序。column为带表名的时间排序字段，idColumn为对应的主键字段。
+// 返回的查询会多取一条记录，用于判断是否还有下一页。
+func paginate(db *gorm.DB, q *PageQuery, column, idColumn string, desc bool) (*gorm.DB, error) {
+	direction, op := " ASC", ">"
+	if desc {
+		direction, op = " DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("("+column+" "+op+" ? OR ("+column+" = ? AND "+idColumn+" "+op+" ?))", c.Value, c.Value, c.ID)
+	} else {
+		db = db.Offset((q.Page - 1) * q.Size)
+	}
+
+	return db.Order(column + direction).Order(idColumn + direction).Limit(q.Size + 1), nil
+}
+
+// buildPagination 根据查询结果生成分页元数据，last为当前页最后一条记录的排序值和ID
+func buildPagination(q *PageQuery, total int64, fetched int, last func() (time.Time, uint)) *Pagination {
+	p := &Pagination{
+		Size:    q.Size,
+		Total:   total,
+		HasMore: fetched > q.Size,
+	}
+	if q.Cursor == "" {
+		p.Page = q.Page
+	}
+	if p.HasMore {
+		value, id := last()
+		p.NextCursor = encodeCursor(value, id)
+	}
+	return p
+}
+
+// parseTimeParam 解析时间查询参数，支持RFC3339和日期格式。
+// endOfDay为true时，纯日期参数解析为次日零点，便于作为开区间的结束时间。
+func parseTimeParam(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("无效的时间参数: " + s)
+}
@@ -0,0 +1,357 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 点赞和表情回应的目标类型
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// 计数器名称
+const (
+	counterViews    = "views"
+	counterLikes    = "likes"
+	counterReaction = "reaction:"
+)
+
+// counterShards 每个计数的分片数，写入时随机选择分片，避免热门内容的计数集中更新同一行
+const counterShards = 16
+
+// ReactionTypes 支持的表情回应及对应的表情符号。数据库中保存名称而不是表情符号，
+// 因为MySQL的utf8mb4_general_ci排序规则会将不同的表情符号视为相等
+var ReactionTypes = map[string]string{
+	"thumbs_up": "👍",
+	"heart":     "❤️",
+	"laugh":     "😄",
+	"hooray":    "🎉",
+	"confused":  "😕",
+	"rocket":    "🚀",
+	"eyes":      "👀",
+}
+
+// NormalizeReaction 将表情回应名称或表情符号转换为名称
+func NormalizeReaction(s string) (string, bool) {
+	if _, ok := ReactionTypes[s]; ok {
+		return s, true
+	}
+	for name, emoji := range ReactionTypes {
+		if s == emoji {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Like 点赞记录，每个用户对同一对象只能点赞一次
+type Like struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_like_user_target;comment:用户ID" json:"user_id"`
+	TargetType string    `gorm:"not null;size:20;uniqueIndex:idx_like_user_target;comment:目标类型" json:"target_type"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_like_user_target;comment:目标ID" json:"target_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	// 多对一关系：多个点赞属于一个用户
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Reaction 表情回应记录，每个用户对同一对象的每种表情只能回应一次
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target;comment:用户ID" json:"user_id"`
+	TargetType string    `gorm:"not null;size:20;uniqueIndex:idx_reaction_user_target;comment:目标类型" json:"target_type"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target;comment:目标ID" json:"target_id"`
+	Type       string    `gorm:"not null;size:20;uniqueIndex:idx_reaction_user_target;comment:表情类型" json:"type"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	// 多对一关系：多个表情回应属于一个用户
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Counter 分片计数器，对象的计数为所有分片之和
+type Counter struct {
+	TargetType string `gorm:"primaryKey;size:20;comment:目标类型"`
+	TargetID   uint   `gorm:"primaryKey;autoIncrement:false;comment:目标ID"`
+	Name       string `gorm:"primaryKey;size:40;comment:计数名称"`
+	Shard      int    `gorm:"primaryKey;autoIncrement:false;comment:分片"`
+	Value      int64  `gorm:"not null;default:0;comment:计数值"`
+}
+
+// Interactions 点赞和表情回应统计，查询文章和评论时从计数器汇总；Liked和MyReactions是当前用户的状态
+type Interactions struct {
+	LikeCount   int64            `json:"like_count"`
+	Reactions   map[string]int64 `json:"reactions"`
+	Liked       bool             `json:"liked"`
+	MyReactions []string         `json:"my_reactions"`
+
+	views int64
+}
+
+// ReactionCRUD 点赞、表情回应和浏览量操作
+type ReactionCRUD struct {
+	db *gorm.DB
+}
+
+// NewReactionCRUD 创建点赞和表情回应操作实例
+func NewReactionCRUD(db *gorm.DB) *ReactionCRUD {
+	return &ReactionCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (r *ReactionCRUD) WithContext(ctx context.Context) *ReactionCRUD {
+	copied := *r
+	copied.db = r.db.WithContext(ctx)
+	return &copied
+}
+
+// Like 点赞，重复点赞不产生影响
+func (r *ReactionCRUD) Like(targetType string, targetID uint, userID uint) (*Interactions, error) {
+	if err := r.checkTarget(targetType, targetID, userID); err != nil {
+		return nil, err
+	}
+	err := transaction(r.db, func(tx *gorm.DB) error {
+		result := tx.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Like{UserID: userID, TargetType: targetType, TargetID: targetID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return incrementCounter(tx, targetType, targetID, counterLikes, 1)
+	})
+	if err != nil {
+		return nil, errors.New("点赞失败")
+	}
+	return r.Get(targetType, targetID, userID)
+}
+
+// Unlike 取消点赞，未点赞时不产生影响
+func (r *ReactionCRUD) Unlike(targetType string, targetID uint, userID uint) (*Interactions, error) {
+	if err := r.checkTarget(targetType, targetID, userID); err != nil {
+		return nil, err
+	}
+	err := transaction(r.db, func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).Delete(&Like{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return incrementCounter(tx, targetType, targetID, counterLikes, -1)
+	})
+	if err != nil {
+		return nil, errors.New("取消点赞失败")
+	}
+	return r.Get(targetType, targetID, userID)
+}
+
+// React 添加表情回应，重复回应同一表情不产生影响
+func (r *ReactionCRUD) React(targetType string, targetID uint, userID uint, reaction string) (*Interactions, error) {
+	if err := r.checkTarget(targetType, targetID, userID); err != nil {
+		return nil, err
+	}
+	err := transaction(r.db, func(tx *gorm.DB) error {
+		result := tx.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Reaction{UserID: userID, TargetType: targetType, TargetID: targetID, Type: reaction})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return incrementCounter(tx, targetType, targetID, counterReaction+reaction, 1)
+	})
+	if err != nil {
+		return nil, errors.New("表情回应失败")
+	}
+	return r.Get(targetType, targetID, userID)
+}
+
+// Unreact 取消表情回应，未回应时不产生影响
+func (r *ReactionCRUD) Unreact(targetType string, targetID uint, userID uint, reaction string) (*Interactions, error) {
+	if err := r.checkTarget(targetType, targetID, userID); err != nil {
+		return nil, err
+	}
+	err := transaction(r.db, func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND target_type = ? AND target_id = ? AND type = ?", userID, targetType, targetID, reaction).Delete(&Reaction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return incrementCounter(tx, targetType, targetID, counterReaction+reaction, -1)
+	})
+	if err != nil {
+		return nil, errors.New("取消表情回应失败")
+	}
+	return r.Get(targetType, targetID, userID)
+}
+
+// Get 获取对象的点赞和表情回应统计
+func (r *ReactionCRUD) Get(targetType string, targetID uint, viewerID uint) (*Interactions, error) {
+	stats, err := loadInteractions(r.db, targetType, []uint{targetID}, viewerID)
+	if err != nil {
+		return nil, errors.New("获取互动统计失败")
+	}
+	return stats[targetID], nil
+}
+
+// RecordView 文章浏览量加一，去重由调用方负责
+func (r *ReactionCRUD) RecordView(postID uint) error {
+	return incrementCounter(r.db, TargetPost, postID, counterViews, 1)
+}
+
+// checkTarget 检查对象存在且对用户可见：评论需通过审核(或为用户本人的评论)且所属文章可见
+func (r *ReactionCRUD) checkTarget(targetType string, targetID uint, userID uint) error {
+	var count int64
+	switch targetType {
+	case TargetPost:
+		r.db.Model(&Post{}).Scopes(visibleTo(userID)).Where("posts.id = ?", targetID).Count(&count)
+		if count == 0 {
+			return errors.New("文章不存在")
+		}
+	case TargetComment:
+		r.db.Model(&Comment{}).Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+			Scopes(visibleTo(userID)).
+			Where("comments.id = ? AND (comments.status = ? OR comments.user_id = ?)", targetID, CommentStatusApproved, userID).
+			Count(&count)
+		if count == 0 {
+			return errors.New("评论不存在")
+		}
+	default:
+		return errors.New("不支持的目标类型")
+	}
+	return nil
+}
+
+// incrementCounter 在随机分片上增加计数
+func incrementCounter(db *gorm.DB, targetType string, targetID uint, name string, delta int64) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "target_type"}, {Name: "target_id"}, {Name: "name"}, {Name: "shard"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr("value + ?", delta)}),
+	}).Create(&Counter{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Name:       name,
+		Shard:      rand.IntN(counterShards),
+		Value:      delta,
+	}).Error
+}
+
+// loadInteractions 批量汇总对象的计数，viewerID不为0时同时查询该用户的点赞和表情回应
+func loadInteractions(db *gorm.DB, targetType string, ids []uint, viewerID uint) (map[uint]*Interactions, error) {
+	stats := make(map[uint]*Interactions, len(ids))
+	for _, id := range ids {
+		stats[id] = &Interactions{Reactions: map[string]int64{}, MyReactions: []string{}}
+	}
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var sums []struct {
+		TargetID uint
+		Name     string
+		Total    int64
+	}
+	if err := db.Model(&Counter{}).Select("target_id, name, SUM(value) AS total").
+		Where("target_type = ? AND target_id IN ?", targetType, ids).
+		Group("target_id, name").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	for _, sum := range sums {
+		s := stats[sum.TargetID]
+		switch {
+		case sum.Name == counterViews:
+			s.views = sum.Total
+		case sum.Name == counterLikes:
+			s.LikeCount = sum.Total
+		case strings.HasPrefix(sum.Name, counterReaction):
+			if sum.Total > 0 {
+				s.Reactions[strings.TrimPrefix(sum.Name, counterReaction)] = sum.Total
+			}
+		}
+	}
+
+	if viewerID == 0 {
+		return stats, nil
+	}
+
+	var liked []uint
+	if err := db.Model(&Like{}).Where("user_id = ? AND target_type = ? AND target_id IN ?", viewerID, targetType, ids).
+		Pluck("target_id", &liked).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range liked {
+		stats[id].Liked = true
+	}
+
+	var reactions []Reaction
+	if err := db.Select("target_id", "type").Where("user_id = ? AND target_type = ? AND target_id IN ?", viewerID, targetType, ids).
+		Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		stats[reaction.TargetID].MyReactions = append(stats[reaction.TargetID].MyReactions, reaction.Type)
+	}
+	for _, s := range stats {
+		sort.Strings(s.MyReactions)
+	}
+	return stats, nil
+}
+
+// fillPostInteractions 为文章填充浏览量、点赞和表情回应统计
+func fillPostInteractions(db *gorm.DB, posts []Post, viewerID uint) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	stats, err := loadInteractions(db, TargetPost, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Interactions = *stats[posts[i].ID]
+		posts[i].ViewCount = stats[posts[i].ID].views
+	}
+	return nil
+}
+
+// fillCommentInteractions 为评论(包括嵌套的回复)填充点赞和表情回应统计
+func fillCommentInteractions(db *gorm.DB, comments []Comment, viewerID uint) error {
+	var all []*Comment
+	var collect func(list []Comment)
+	collect = func(list []Comment) {
+		for i := range list {
+			all = append(all, &list[i])
+			collect(list[i].Replies)
+		}
+	}
+	collect(comments)
+
+	ids := make([]uint, len(all))
+	for i, comment := range all {
+		ids[i] = comment.ID
+	}
+	stats, err := loadInteractions(db, TargetComment, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for _, comment := range all {
+		comment.Interactions = *stats[comment.ID]
+	}
+	return nil
+}
+
+// purgeInteractions 清理已永久删除的文章和评论的点赞、表情回应和计数
+func purgeInteractions(db *gorm.DB) error {
+	for targetType, table := range map[string]string{TargetPost: "posts", TargetComment: "comments"} {
+		orphan := "target_type = ? AND NOT EXISTS (SELECT 1 FROM " + table + " WHERE " + table + ".id = target_id)"
+		for _, model := range []interface{}{&Like{}, &Reaction{}, &Counter{}} {
+			if err := db.Where(orphan, targetType).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"blog-system/diff"
+
+	"gorm.io/gorm"
+)
+
+// PostRevision 文章修订记录，每次修改标题、摘要或正文时保存一份修改后的快照
+type PostRevision struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_post_revision;comment:文章ID" json:"post_id"`
+	Revision  int       `gorm:"not null;uniqueIndex:idx_post_revision;comment:修订版本号" json:"revision"`
+	Title     string    `gorm:"not null;size:200;comment:文章标题" json:"title"`
+	Content   string    `gorm:"not null;type:longtext;comment:文章内容" json:"content,omitempty"`
+	Summary   string    `gorm:"size:500;comment:文章摘要" json:"summary"`
+	EditorID  uint      `gorm:"not null;index;comment:修改者ID" json:"editor_id"`
+	Note      string    `gorm:"size:255;comment:修订说明" json:"note,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	Post   Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+	Editor User `gorm:"foreignKey:EditorID;constraint:OnDelete:CASCADE" json:"editor,omitempty"`
+}
+
+// FieldChange 单行字段的修改前后内容
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// RevisionDiff 两个修订版本之间的差异
+type RevisionDiff struct {
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Title   *FieldChange `json:"title,omitempty"`
+	Summary *FieldChange `json:"summary,omitempty"`
+	Content []diff.Line  `json:"content"`
+	Stats   diff.Stats   `json:"stats"`
+}
+
+// RevisionDiffQuery 修订差异查询参数，to为空时与最新版本比较
+type RevisionDiffQuery struct {
+	From int `form:"from" binding:"required,min=1"`
+	To   int `form:"to" binding:"omitempty,min=1"`
+}
+
+// RevisionCRUD 文章修订记录操作
+type RevisionCRUD struct {
+	db *gorm.DB
+}
+
+// NewRevisionCRUD 创建修订记录操作实例
+func NewRevisionCRUD(db *gorm.DB) *RevisionCRUD {
+	return &RevisionCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (r *RevisionCRUD) WithContext(ctx context.Context) *RevisionCRUD {
+	copied := *r
+	copied.db = r.db.WithContext(ctx)
+	return &copied
+}
+
+// List 获取文章的全部修订记录，按版本号倒序排列，不返回正文
+func (r *RevisionCRUD) List(postID uint) ([]PostRevision, error) {
+	var revisions []PostRevision
+	err := r.db.Omit("Content").Preload("Editor").
+		Where("post_id = ?", postID).
+		Order("revision DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, errors.New("获取修订记录失败")
+	}
+	return revisions, nil
+}
+
+// Get 获取指定版本号的修订记录
+func (r *RevisionCRUD) Get(postID uint, revision int) (*PostRevision, error) {
+	var rev PostRevision
+	if err := r.db.Preload("Editor").Where("post_id = ? AND revision = ?", postID, revision).First(&rev).Error; err != nil {
+		return nil, errors.New("修订版本不存在")
+	}
+	return &rev, nil
+}
+
+// Diff 比较两个修订版本，to为0时与最新版本比较
+func (r *RevisionCRUD) Diff(postID uint, from, to int) (*RevisionDiff, error) {
+	if to == 0 {
+		var latest PostRevision
+		if err := r.db.Select("revision").Where("post_id = ?", postID).Order("revision DESC").First(&latest).Error; err != nil {
+			return nil, errors.New("修订版本不存在")
+		}
+		to = latest.Revision
+	}
+
+	old, err := r.Get(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.Get(postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RevisionDiff{
+		From:    from,
+		To:      to,
+		Content: diff.Lines(old.Content, current.Content),
+	}
+	result.Stats = diff.Count(result.Content)
+	if old.Title != current.Title {
+		result.Title = &FieldChange{Old: old.Title, New: current.Title}
+	}
+	if old.Summary != current.Summary {
+		result.Summary = &FieldChange{Old: old.Summary, New: current.Summary}
+	}
+	return result, nil
+}
+
+// recordRevision 在事务中为文章当前内容写入一条新的修订记录
+func recordRevision(tx *gorm.DB, post *Post, editorID uint, note string) error {
+	var last PostRevision
+	next := 1
+	if err := tx.Select("revision").Where("post_id = ?", post.ID).Order("revision DESC").First(&last).Error; err == nil {
+		next = last.Revision + 1
+	}
+
+	rev := PostRevision{
+		PostID:   post.ID,
+		Revision: next,
+		Title:    post.Title,
+		Content:  post.Content,
+		Summary:  post.Summary,
+		EditorID: editorID,
+		Note:     note,
+	}
+	if err := tx.Omit("Post", "Editor").Create(&rev).Error; err != nil {
+		return errors.New("修订记录保存失败")
+	}
+	return nil
+}
+
+// ensureBaseRevision 为功能上线前创建、尚无修订记录的文章补写初始版本
+func ensureBaseRevision(tx *gorm.DB, post *Post) error {
+	var count int64
+	if err := tx.Model(&PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return errors.New("修订记录保存失败")
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordRevision(tx, post, post.UserID, "初始版本")
+}
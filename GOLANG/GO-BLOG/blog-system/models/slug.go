@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-system/slug"
+
+	"gorm.io/gorm"
+)
+
+// PostSlug 文章的历史别名，标题修改后旧别名继续指向该文章，用于重定向到当前别名
+type PostSlug struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	PostID    uint      `gorm:"not null;index;comment:文章ID" json:"post_id"`
+	Slug      string    `gorm:"not null;uniqueIndex;size:255;comment:历史URL别名" json:"slug"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	Post Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// assignSlug 设置文章别名：requested不为空时以其为基础，否则根据标题生成。
+// 别名发生变化时旧别名写入历史记录，需在事务中调用。
+func assignSlug(tx *gorm.DB, post *Post, requested string) error {
+	base := slug.Make(post.Title)
+	if requested != "" {
+		base = slug.Make(requested)
+	}
+
+	// 当前别名已由同一基础别名生成时保持不变，避免无意义的重定向
+	if post.Slug == base || isNumberedSlug(post.Slug, base) {
+		return nil
+	}
+
+	next, err := uniqueSlug(tx, base, post.ID)
+	if err != nil {
+		return err
+	}
+
+	old := post.Slug
+	post.Slug = next
+	if old == "" || post.ID == 0 {
+		return nil
+	}
+
+	if err := tx.Where("post_id = ? AND slug = ?", post.ID, next).Delete(&PostSlug{}).Error; err != nil {
+		return errors.New("文章别名保存失败")
+	}
+	if err := tx.Omit("Post").Create(&PostSlug{PostID: post.ID, Slug: old}).Error; err != nil {
+		return errors.New("文章别名保存失败")
+	}
+	return nil
+}
+
+// uniqueSlug 生成未被其他文章占用的别名，已被占用时依次追加-2、-3等后缀。
+// 回收站中的文章和其他文章的历史别名同样视为已占用。
+func uniqueSlug(tx *gorm.DB, base string, postID uint) (string, error) {
+	pattern := base + "-%"
+
+	var taken []string
+	if err := tx.Unscoped().Model(&Post{}).
+		Where("id <> ? AND (slug = ? OR slug LIKE ?)", postID, base, pattern).
+		Pluck("slug", &taken).Error; err != nil {
+		return "", errors.New("文章别名生成失败")
+	}
+	var history []string
+	if err := tx.Model(&PostSlug{}).
+		Where("post_id <> ? AND (slug = ? OR slug LIKE ?)", postID, base, pattern).
+		Pluck("slug", &history).Error; err != nil {
+		return "", errors.New("文章别名生成失败")
+	}
+
+	used := make(map[string]bool, len(taken)+len(history))
+	for _, s := range append(taken, history...) {
+		used[s] = true
+	}
+
+	candidate := base
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+	return candidate, nil
+}
+
+// isNumberedSlug 判断s是否为base追加数字后缀得到的别名
+func isNumberedSlug(s, base string) bool {
+	suffix := strings.TrimPrefix(s, base+"-")
+	if suffix == s || suffix == "" {
+		return false
+	}
+	return strings.Trim(suffix, "0123456789") == ""
+}
+
+// backfillSlugs 为尚无别名的文章生成别名
+func backfillSlugs(db *gorm.DB) error {
+	var posts []Post
+	if err := db.Unscoped().Select("id", "title", "slug").
+		Where("slug IS NULL OR slug = ''").Find(&posts).Error; err != nil {
+		return err
+	}
+
+	for i := range posts {
+		post := &posts[i]
+		err := transaction(db, func(tx *gorm.DB) error {
+			if err := assignSlug(tx, post, ""); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(post).UpdateColumn("slug", post.Slug).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBySlug 根据别名获取文章。别名为历史别名时返回文章的当前别名，调用方应重定向到当前别名。
+func (p *PostCRUD) GetBySlug(s string, viewerID uint) (*Post, string, error) {
+	var post Post
+	if err := p.db.Select("id").Where("slug = ?", s).First(&post).Error; err == nil {
+		found, err := p.GetByID(post.ID, viewerID)
+		return found, "", err
+	}
+
+	var history PostSlug
+	if err := p.db.Where("slug = ?", s).First(&history).Error; err != nil {
+		return nil, "", errors.New("文章不存在")
+	}
+
+	var current Post
+	if err := p.db.Scopes(visibleTo(viewerID)).Select("posts.id", "posts.slug").First(&current, history.PostID).Error; err != nil {
+		return nil, "", errors.New("文章不存在")
+	}
+	return nil, current.Slug, nil
+}
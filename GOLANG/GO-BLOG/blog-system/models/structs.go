@@ -6,20 +6,46 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户角色
+const (
+	RoleReader    = "reader"    // 读者：只能浏览和评论
+	RoleAuthor    = "author"    // 作者：可以发布文章
+	RoleModerator = "moderator" // 版主：可以管理任意评论
+	RoleAdmin     = "admin"     // 管理员：可以管理用户和所有内容
+)
+
+// 文章状态
+const (
+	PostStatusDraft     = "draft"     // 草稿：仅作者可见
+	PostStatusScheduled = "scheduled" // 定时发布：到达发布时间后由后台任务发布
+	PostStatusPublished = "published" // 已发布：所有人可见
+)
+
+// 评论审核状态
+const (
+	CommentStatusPending  = "pending"  // 待审核：仅评论者本人和版主可见
+	CommentStatusApproved = "approved" // 已通过：所有人可见
+	CommentStatusRejected = "rejected" // 已拒绝
+	CommentStatusSpam     = "spam"     // 垃圾评论
+)
+
 // User 用户模型
 type User struct {
-	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Username  string     `gorm:"unique;not null;size:50;comment:用户名" json:"username"`
-	Email     string     `gorm:"unique;not null;size:100;comment:邮箱" json:"email"`
-	Password  string     `gorm:"not null;size:255;comment:密码" json:"-"`
-	Nickname  string     `gorm:"size:50;comment:昵称" json:"nickname"`
-	Avatar    string     `gorm:"size:255;comment:头像URL" json:"avatar"`
-	Bio       string     `gorm:"type:text;comment:个人简介" json:"bio"`
-	IsActive  bool       `gorm:"default:true;comment:是否激活" json:"is_active"`
-	PostCount int        `gorm:"default:0;comment:文章数量统计" json:"post_count"`
-	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
-	DeletedAt *time.Time `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
+	ID              uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username        string         `gorm:"unique;not null;size:50;comment:用户名" json:"username"`
+	Email           string         `gorm:"unique;not null;size:100;comment:邮箱" json:"email"`
+	Password        string         `gorm:"not null;size:255;comment:密码" json:"-"`
+	Nickname        string         `gorm:"size:50;comment:昵称" json:"nickname"`
+	Avatar          string         `gorm:"size:255;comment:头像URL" json:"avatar"`
+	Bio             string         `gorm:"type:text;comment:个人简介" json:"bio"`
+	Role            string         `gorm:"default:author;size:20;index;comment:用户角色" json:"role"`
+	IsActive        bool           `gorm:"default:true;comment:是否激活" json:"is_active"`
+	EmailVerifiedAt *time.Time     `gorm:"comment:邮箱验证时间" json:"email_verified_at"`
+	PostCount       int            `gorm:"default:0;comment:文章数量统计" json:"post_count"`
+	StorageUsed     int64          `gorm:"default:0;comment:已用存储空间(字节)" json:"storage_used"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
 
 	// 一对多关系：一个用户可以发布多篇文章
 	Posts []Post `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"posts,omitempty"`
@@ -30,40 +56,90 @@ type User struct {
 
 // Post 文章模型
 type Post struct {
-	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Title     string     `gorm:"not null;size:200;comment:文章标题" json:"title"`
-	Content   string     `gorm:"not null;type:longtext;comment:文章内容" json:"content"`
-	Summary   string     `gorm:"size:500;comment:文章摘要" json:"summary"`
-	Status    string     `gorm:"default:published;size:20;comment:文章状态" json:"status"`
-	UserID    uint       `gorm:"not null;index;comment:作者ID" json:"user_id"`
-	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
-	DeletedAt *time.Time `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string         `gorm:"not null;size:200;comment:文章标题" json:"title"`
+	Slug        string         `gorm:"uniqueIndex;size:255;comment:URL别名" json:"slug"`
+	Content     string         `gorm:"not null;type:longtext;comment:文章内容" json:"content"`
+	ContentHTML string         `gorm:"type:longtext;comment:渲染后的HTML内容" json:"content_html"`
+	Summary     string         `gorm:"size:500;comment:文章摘要" json:"summary"`
+	Status      string         `gorm:"default:published;size:20;index;comment:文章状态" json:"status"`
+	UserID      uint           `gorm:"not null;index;comment:作者ID" json:"user_id"`
+	PublishedAt *time.Time     `gorm:"index;comment:发布时间" json:"published_at"`
+	ScheduledAt *time.Time     `gorm:"index;comment:定时发布时间" json:"scheduled_at,omitempty"`
+	CategoryID  *uint          `gorm:"index;comment:分类ID" json:"category_id"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
+	DeletedBy   *uint          `gorm:"comment:删除人ID" json:"deleted_by,omitempty"`
 
 	// 多对一关系：多篇文章属于一个用户
 	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
 
 	// 一对多关系：一篇文章可以有多个评论
 	Comments []Comment `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"comments,omitempty"`
+
+	// 多对一关系：多篇文章属于一个分类
+	Category *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL" json:"category,omitempty"`
+
+	// 多对多关系：文章与标签
+	Tags []Tag `gorm:"many2many:post_tags;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+
+	// 浏览量、点赞和表情回应统计，查询时从分片计数器汇总
+	ViewCount    int64 `gorm:"-" json:"view_count"`
+	Interactions `gorm:"-"`
+
+	// PreviousStatus 修改或变更状态前的文章状态，用于判断需要发布的业务事件
+	PreviousStatus string `gorm:"-" json:"-"`
+}
+
+// VisibleTo 判断文章对指定用户是否可见：已发布的文章所有人可见，未发布的文章仅作者可见
+func (p *Post) VisibleTo(viewerID uint) bool {
+	return p.Status == PostStatusPublished || p.UserID == viewerID
 }
 
 // Comment 评论模型
 type Comment struct {
-	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Content   string     `gorm:"not null;type:text;comment:评论内容" json:"content"`
-	UserID    uint       `gorm:"not null;index;comment:评论者ID" json:"user_id"`
-	PostID    uint       `gorm:"not null;index;comment:文章ID" json:"post_id"`
-	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
-	DeletedAt *time.Time `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Content     string         `gorm:"not null;type:text;comment:评论内容" json:"content"`
+	ContentHTML string         `gorm:"type:text;comment:渲染后的HTML内容" json:"content_html"`
+	UserID      uint           `gorm:"not null;index;comment:评论者ID" json:"user_id"`
+	PostID      uint           `gorm:"not null;index;comment:文章ID" json:"post_id"`
+	ParentID    *uint          `gorm:"index;comment:父评论ID" json:"parent_id"`
+	RootID      *uint          `gorm:"index;comment:根评论ID" json:"root_id,omitempty"`
+	Depth       int            `gorm:"default:0;comment:回复层级" json:"depth"`
+	Status      string         `gorm:"default:approved;size:20;index;comment:审核状态" json:"status"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index;comment:删除时间" json:"deleted_at,omitempty"`
+	DeletedBy   *uint          `gorm:"comment:删除人ID" json:"deleted_by,omitempty"`
+
+	// 来源和审核信息，不在公开接口中返回
+	IPAddress   string     `gorm:"size:45;index;comment:IP地址" json:"-"`
+	UserAgent   string     `gorm:"size:255;comment:用户代理" json:"-"`
+	SpamScore   float64    `gorm:"default:0;comment:垃圾评论评分" json:"-"`
+	SpamReason  string     `gorm:"size:255;comment:评分命中原因" json:"-"`
+	ModeratedBy *uint      `gorm:"comment:审核人ID" json:"-"`
+	ModeratedAt *time.Time `gorm:"comment:审核时间" json:"-"`
 
 	// 多对一关系：多个评论属于一个用户
 	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
 
 	// 多对一关系：多个评论属于一篇文章
 	Post Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"post,omitempty"`
+
+	// 自引用：评论的回复，仅在以树形结构返回时填充
+	Replies []Comment `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"replies,omitempty"`
+
+	// 点赞和表情回应统计，查询时从分片计数器汇总
+	Interactions `gorm:"-"`
+
+	// PreviousStatus 审核前的评论状态，用于判断需要发布的业务事件
+	PreviousStatus string `gorm:"-" json:"-"`
 }
 
+// MaxCommentDepth 评论回复的最大层级，顶级评论的层级为0
+const MaxCommentDepth = 5
+
 // 请求结构体
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -77,23 +153,172 @@ type LoginRequest struct {
 }
 
 type PostRequest struct {
-	Title   string `json:"title" binding:"required,max=200"`
-	Content string `json:"content" binding:"required"`
-	Summary string `json:"summary"`
+	Title      string     `json:"title" binding:"required,max=200"`
+	Slug       string     `json:"slug" binding:"omitempty,max=80"`
+	Content    string     `json:"content" binding:"required"`
+	Summary    string     `json:"summary"`
+	Status     string     `json:"status" binding:"omitempty,oneof=draft published scheduled"`
+	PublishAt  *time.Time `json:"publish_at"`
+	CategoryID *uint      `json:"category_id"`
+	Tags       []string   `json:"tags" binding:"omitempty,max=10"`
+}
+
+type PublishRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// PostListQuery 文章列表查询参数
+type PostListQuery struct {
+	PageQuery
+	AuthorID uint   `form:"author_id"`
+	Author   string `form:"author"`
+	Status   string `form:"status"`
+	Tag      string `form:"tag"`
+	Category uint   `form:"category"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=created_at updated_at"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
 }
 
 type CommentRequest struct {
-	Content string `json:"content" binding:"required,max=1000"`
+	Content  string `json:"content" binding:"required,max=1000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CommentMeta 创建或更新评论时附带的来源和审核信息
+type CommentMeta struct {
+	IPAddress  string
+	UserAgent  string
+	Status     string
+	SpamScore  float64
+	SpamReason string
+}
+
+// ModerationQuery 审核队列查询参数
+type ModerationQuery struct {
+	PageQuery
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected spam"`
+}
+
+// ModerateRequest 批量审核请求
+type ModerateRequest struct {
+	IDs    []uint `json:"ids" binding:"required,min=1,max=100"`
+	Action string `json:"action" binding:"required,oneof=approve reject spam"`
+}
+
+// CommentListQuery 评论列表查询参数
+type CommentListQuery struct {
+	PageQuery
+	Format string `form:"format" binding:"omitempty,oneof=flat tree"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=reader author moderator admin"`
+}
+
+// ProfileRequest 修改个人资料请求，email为空时保持不变
+type ProfileRequest struct {
+	Nickname string `json:"nickname" binding:"max=50"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
+	Avatar   string `json:"avatar" binding:"omitempty,max=255"`
+	Bio      string `json:"bio" binding:"max=500"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6,max=72"`
+}
+
+// VerifyEmailRequest 邮箱验证请求，令牌可以通过查询参数或请求体提供
+type VerifyEmailRequest struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+// ForgotPasswordRequest 申请重置密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6,max=72"`
+}
+
+// PublicProfile 用户公开资料，不包含邮箱、角色等信息
+type PublicProfile struct {
+	ID             uint      `json:"id"`
+	Username       string    `json:"username"`
+	Nickname       string    `json:"nickname"`
+	Avatar         string    `json:"avatar"`
+	Bio            string    `json:"bio"`
+	PostCount      int64     `json:"post_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PublicPost 公开资料中返回的文章，作者只包含UserSummary中的公开信息
+type PublicPost struct {
+	Post
+	User UserSummary `json:"user"`
+}
+
+// NewPublicPosts 将文章列表转换为公开返回的形式，隐藏作者的邮箱、角色等信息
+func NewPublicPosts(posts []Post) []PublicPost {
+	public := make([]PublicPost, len(posts))
+	for i := range posts {
+		public[i] = PublicPost{Post: posts[i], User: posts[i].User.Summary()}
+	}
+	return public
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // 响应结构体
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code       int         `json:"code"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
 }
 
 // AutoMigrate 自动迁移数据库表结构
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&User{}, &Post{}, &Comment{})
+	// 引入邮箱验证前注册的用户视为已验证，避免升级后无法发布内容
+	verifiedBefore := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerifiedAt")
+
+	if err := db.AutoMigrate(&User{}, &Category{}, &Tag{}, &Post{}, &PostSlug{}, &Comment{}, &PostRevision{}, &Media{}, &RefreshToken{}, &RevokedToken{}, &UserToken{}, &Like{}, &Reaction{}, &Counter{}, &Follow{}, &BookmarkCollection{}, &Bookmark{}, &Notification{}, &NotificationMute{}, &Webhook{}, &WebhookDelivery{}, &AuditLog{}, &AuditChain{}); err != nil {
+		return err
+	}
+
+	if verifiedBefore {
+		if err := db.Unscoped().Model(&User{}).Where("email_verified_at IS NULL").
+			UpdateColumn("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+	}
+
+	// 为迁移前已发布的文章补充发布时间
+	if err := db.Model(&Post{}).
+		Where("status = ? AND published_at IS NULL", PostStatusPublished).
+		UpdateColumn("published_at", gorm.Expr("created_at")).Error; err != nil {
+		return err
+	}
+
+	// 为迁移前创建的文章生成URL别名
+	if err := backfillSlugs(db); err != nil {
+		return err
+	}
+
+	// 为迁移前创建的文章和评论渲染HTML
+	return backfillContentHTML(db)
 }
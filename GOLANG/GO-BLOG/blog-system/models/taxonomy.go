@@ -0,0 +1,367 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag 标签模型，与文章是多对多关系
+type Tag struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"unique;not null;size:50;comment:标签名" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	// 统计字段，仅在查询时填充
+	PostCount int64 `gorm:"->;-:migration" json:"post_count,omitempty"`
+}
+
+// Category 分类模型，通过ParentID支持多级分类
+type Category struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"not null;size:50;uniqueIndex:idx_category_parent_name;comment:分类名" json:"name"`
+	Description string    `gorm:"size:255;comment:分类描述" json:"description"`
+	ParentID    *uint     `gorm:"index;uniqueIndex:idx_category_parent_name;comment:父分类ID" json:"parent_id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	// 自引用：父分类
+	Parent *Category `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT" json:"-"`
+
+	// 统计字段和子分类，仅在获取分类树时填充
+	PostCount int64      `gorm:"-" json:"post_count"`
+	Children  []Category `gorm:"-" json:"children,omitempty"`
+}
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=255"`
+	ParentID    *uint  `json:"parent_id"`
+}
+
+// TagCRUD 标签CRUD操作
+type TagCRUD struct {
+	db *gorm.DB
+}
+
+// NewTagCRUD 创建标签CRUD实例
+func NewTagCRUD(db *gorm.DB) *TagCRUD {
+	return &TagCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (t *TagCRUD) WithContext(ctx context.Context) *TagCRUD {
+	copied := *t
+	copied.db = t.db.WithContext(ctx)
+	return &copied
+}
+
+// ListWithCounts 获取所有标签及其已发布文章数量，按文章数量倒序排列
+func (t *TagCRUD) ListWithCounts() ([]Tag, error) {
+	var tags []Tag
+	err := t.db.Model(&Tag{}).
+		Select("tags.*, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ? AND posts.deleted_at IS NULL", PostStatusPublished).
+		Group("tags.id").
+		Order("post_count DESC, tags.name ASC").
+		Find(&tags).Error
+	if err != nil {
+		return nil, errors.New("获取标签列表失败")
+	}
+	return tags, nil
+}
+
+// Create 创建标签
+func (t *TagCRUD) Create(req *TagRequest) (*Tag, error) {
+	name := normalizeTagName(req.Name)
+	if name == "" {
+		return nil, errors.New("标签名不能为空")
+	}
+
+	var existing Tag
+	if err := t.db.Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, errors.New("标签已存在")
+	}
+
+	tag := Tag{Name: name}
+	if err := t.db.Create(&tag).Error; err != nil {
+		return nil, errors.New("标签创建失败")
+	}
+	return &tag, nil
+}
+
+// Update 重命名标签
+func (t *TagCRUD) Update(id uint, req *TagRequest) (*Tag, error) {
+	var tag Tag
+	if err := t.db.First(&tag, id).Error; err != nil {
+		return nil, errors.New("标签不存在")
+	}
+
+	name := normalizeTagName(req.Name)
+	if name == "" {
+		return nil, errors.New("标签名不能为空")
+	}
+
+	var existing Tag
+	if err := t.db.Where("name = ? AND id <> ?", name, id).First(&existing).Error; err == nil {
+		return nil, errors.New("标签已存在")
+	}
+
+	tag.Name = name
+	if err := t.db.Save(&tag).Error; err != nil {
+		return nil, errors.New("标签更新失败")
+	}
+	return &tag, nil
+}
+
+// Delete 删除标签及其与文章的关联
+func (t *TagCRUD) Delete(id uint) error {
+	var tag Tag
+	if err := t.db.First(&tag, id).Error; err != nil {
+		return errors.New("标签不存在")
+	}
+
+	return transaction(t.db, func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", id).Error; err != nil {
+			return errors.New("标签删除失败")
+		}
+		if err := tx.Delete(&tag).Error; err != nil {
+			return errors.New("标签删除失败")
+		}
+		return nil
+	})
+}
+
+// findOrCreateTags 根据名称查找标签，不存在时自动创建
+func findOrCreateTags(tx *gorm.DB, names []string) ([]Tag, error) {
+	seen := make(map[string]bool)
+	tags := make([]Tag, 0, len(names))
+	for _, raw := range names {
+		name := normalizeTagName(raw)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if len([]rune(name)) > 50 {
+			return nil, errors.New("标签名不能超过50个字符")
+		}
+
+		var tag Tag
+		if err := tx.Where(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, errors.New("标签保存失败")
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// normalizeTagName 规范化标签名：去除首尾空白和开头的#
+func normalizeTagName(name string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(name), "#"))
+}
+
+// CategoryCRUD 分类CRUD操作
+type CategoryCRUD struct {
+	db *gorm.DB
+}
+
+// NewCategoryCRUD 创建分类CRUD实例
+func NewCategoryCRUD(db *gorm.DB) *CategoryCRUD {
+	return &CategoryCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (c *CategoryCRUD) WithContext(ctx context.Context) *CategoryCRUD {
+	copied := *c
+	copied.db = c.db.WithContext(ctx)
+	return &copied
+}
+
+// Tree 获取分类树，每个分类的文章数量包含其所有子分类的已发布文章
+func (c *CategoryCRUD) Tree() ([]Category, error) {
+	var categories []Category
+	if err := c.db.Order("name ASC").Find(&categories).Error; err != nil {
+		return nil, errors.New("获取分类列表失败")
+	}
+
+	var counts []struct {
+		CategoryID uint
+		Count      int64
+	}
+	if err := c.db.Model(&Post{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("status = ? AND category_id IS NOT NULL", PostStatusPublished).
+		Group("category_id").
+		Scan(&counts).Error; err != nil {
+		return nil, errors.New("获取分类列表失败")
+	}
+
+	direct := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		direct[row.CategoryID] = row.Count
+	}
+
+	children := make(map[uint][]Category)
+	var roots []Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	// 递归组装子分类并汇总文章数量
+	var build func(node *Category)
+	build = func(node *Category) {
+		node.PostCount = direct[node.ID]
+		node.Children = children[node.ID]
+		for i := range node.Children {
+			build(&node.Children[i])
+			node.PostCount += node.Children[i].PostCount
+		}
+	}
+	for i := range roots {
+		build(&roots[i])
+	}
+
+	return roots, nil
+}
+
+// GetByID 根据ID获取分类
+func (c *CategoryCRUD) GetByID(id uint) (*Category, error) {
+	var category Category
+	if err := c.db.First(&category, id).Error; err != nil {
+		return nil, errors.New("分类不存在")
+	}
+	return &category, nil
+}
+
+// Create 创建分类
+func (c *CategoryCRUD) Create(req *CategoryRequest) (*Category, error) {
+	if req.ParentID != nil {
+		if _, err := c.GetByID(*req.ParentID); err != nil {
+			return nil, errors.New("父分类不存在")
+		}
+	}
+
+	if c.nameTaken(req.Name, req.ParentID, 0) {
+		return nil, errors.New("同级分类名称已存在")
+	}
+
+	category := Category{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		ParentID:    req.ParentID,
+	}
+	if err := c.db.Create(&category).Error; err != nil {
+		return nil, errors.New("分类创建失败")
+	}
+	return &category, nil
+}
+
+// Update 更新分类，移动分类时不允许形成循环
+func (c *CategoryCRUD) Update(id uint, req *CategoryRequest) (*Category, error) {
+	category, err := c.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == id {
+			return nil, errors.New("不能将分类移动到自身或其子分类下")
+		}
+		if _, err := c.GetByID(*req.ParentID); err != nil {
+			return nil, errors.New("父分类不存在")
+		}
+		descendants, err := descendantCategoryIDs(c.db, id)
+		if err != nil {
+			return nil, errors.New("分类更新失败")
+		}
+		for _, d := range descendants {
+			if d == *req.ParentID {
+				return nil, errors.New("不能将分类移动到自身或其子分类下")
+			}
+		}
+	}
+
+	if c.nameTaken(req.Name, req.ParentID, id) {
+		return nil, errors.New("同级分类名称已存在")
+	}
+
+	category.Name = strings.TrimSpace(req.Name)
+	category.Description = req.Description
+	category.ParentID = req.ParentID
+	if err := c.db.Save(category).Error; err != nil {
+		return nil, errors.New("分类更新失败")
+	}
+	return category, nil
+}
+
+// Delete 删除分类，存在子分类时不允许删除，该分类下的文章变为未分类
+func (c *CategoryCRUD) Delete(id uint) error {
+	category, err := c.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	c.db.Model(&Category{}).Where("parent_id = ?", id).Count(&count)
+	if count > 0 {
+		return errors.New("分类下存在子分类，无法删除")
+	}
+
+	return transaction(c.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&Post{}).Where("category_id = ?", id).Update("category_id", nil).Error; err != nil {
+			return errors.New("分类删除失败")
+		}
+		if err := tx.Delete(category).Error; err != nil {
+			return errors.New("分类删除失败")
+		}
+		return nil
+	})
+}
+
+// nameTaken 检查同一父分类下是否已有同名分类
+func (c *CategoryCRUD) nameTaken(name string, parentID *uint, excludeID uint) bool {
+	db := c.db.Model(&Category{}).Where("name = ? AND id <> ?", strings.TrimSpace(name), excludeID)
+	if parentID == nil {
+		db = db.Where("parent_id IS NULL")
+	} else {
+		db = db.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	db.Count(&count)
+	return count > 0
+}
+
+// descendantCategoryIDs 获取分类及其所有子孙分类的ID
+func descendantCategoryIDs(db *gorm.DB, id uint) ([]uint, error) {
+	var categories []Category
+	if err := db.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken 刷新令牌模型，数据库中只保存令牌的SHA-256摘要
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"not null;index;comment:用户ID" json:"user_id"`
+	TokenHash  string     `gorm:"unique;not null;size:64;comment:令牌摘要" json:"-"`
+	FamilyID   string     `gorm:"not null;index;size:64;comment:令牌族ID" json:"family_id"`
+	ExpiresAt  time.Time  `gorm:"not null;comment:过期时间" json:"expires_at"`
+	RevokedAt  *time.Time `gorm:"comment:吊销时间" json:"revoked_at,omitempty"`
+	ReplacedBy *uint      `gorm:"comment:轮换后的令牌ID" json:"replaced_by,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	// 多对一关系：多个刷新令牌属于一个用户
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// RevokedToken 已吊销的访问令牌(jti黑名单)
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	JTI       string    `gorm:"unique;not null;size:64;comment:令牌ID" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index;comment:令牌原过期时间" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:吊销时间" json:"created_at"`
+}
+
+// TokenCRUD 令牌CRUD操作
+type TokenCRUD struct {
+	db         *gorm.DB
+	refreshTTL time.Duration
+}
+
+// NewTokenCRUD 创建令牌CRUD实例
+func NewTokenCRUD(db *gorm.DB, refreshTTL time.Duration) *TokenCRUD {
+	return &TokenCRUD{db: db, refreshTTL: refreshTTL}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (t *TokenCRUD) WithContext(ctx context.Context) *TokenCRUD {
+	copied := *t
+	copied.db = t.db.WithContext(ctx)
+	return &copied
+}
+
+// IssueRefreshToken 为用户签发新的刷新令牌，familyID为空时开启新的令牌族
+func (t *TokenCRUD) IssueRefreshToken(userID uint, familyID string) (string, *RefreshToken, error) {
+	return t.issue(t.db, userID, familyID)
+}
+
+func (t *TokenCRUD) issue(tx *gorm.DB, userID uint, familyID string) (string, *RefreshToken, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", nil, errors.New("刷新令牌生成失败")
+	}
+
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return "", nil, errors.New("刷新令牌生成失败")
+		}
+	}
+
+	token := RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(raw),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(t.refreshTTL),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", nil, errors.New("刷新令牌保存失败")
+	}
+
+	return raw, &token, nil
+}
+
+// Rotate 轮换刷新令牌：旧令牌立即失效并在同一令牌族中签发新令牌。
+// 如果提交的是已被轮换或吊销的令牌，视为令牌泄露，吊销整个令牌族。
+func (t *TokenCRUD) Rotate(raw string) (string, *RefreshToken, error) {
+	var current RefreshToken
+	if err := t.db.Where("token_hash = ?", hashToken(raw)).First(&current).Error; err != nil {
+		return "", nil, errors.New("无效的刷新令牌")
+	}
+
+	if current.RevokedAt != nil {
+		if err := t.RevokeFamily(current.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, errors.New("刷新令牌已被重复使用")
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return "", nil, errors.New("刷新令牌已过期")
+	}
+
+	var newRaw string
+	var next *RefreshToken
+	err := transaction(t.db, func(tx *gorm.DB) error {
+		var err error
+		newRaw, next, err = t.issue(tx, current.UserID, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		// 条件更新防止并发请求同时轮换同一个令牌
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": next.ID})
+		if result.Error != nil {
+			return errors.New("刷新令牌轮换失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("刷新令牌已被重复使用")
+		}
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "刷新令牌已被重复使用" {
+			t.RevokeFamily(current.FamilyID)
+		}
+		return "", nil, err
+	}
+
+	return newRaw, next, nil
+}
+
+// RevokeFamily 吊销整个令牌族
+func (t *TokenCRUD) RevokeFamily(familyID string) error {
+	if err := t.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("令牌族吊销失败")
+	}
+	return nil
+}
+
+// RevokeRefreshToken 吊销指定用户的刷新令牌所在的令牌族
+func (t *TokenCRUD) RevokeRefreshToken(raw string, userID uint) error {
+	var token RefreshToken
+	if err := t.db.Where("token_hash = ? AND user_id = ?", hashToken(raw), userID).First(&token).Error; err != nil {
+		return errors.New("无效的刷新令牌")
+	}
+	return t.RevokeFamily(token.FamilyID)
+}
+
+// RevokeAllForUser 吊销用户的所有刷新令牌
+func (t *TokenCRUD) RevokeAllForUser(userID uint) error {
+	if err := t.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return errors.New("令牌吊销失败")
+	}
+	return nil
+}
+
+// RevokeAccessToken 将访问令牌的jti加入黑名单
+func (t *TokenCRUD) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	revoked := RevokedToken{JTI: jti, ExpiresAt: expiresAt}
+	if err := t.db.Where(RevokedToken{JTI: jti}).FirstOrCreate(&revoked).Error; err != nil {
+		return errors.New("访问令牌吊销失败")
+	}
+	return nil
+}
+
+// IsRevoked 检查访问令牌是否已被吊销
+func (t *TokenCRUD) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := t.db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// PurgeExpired 清理已过期的刷新令牌和黑名单记录
+func (t *TokenCRUD) PurgeExpired() error {
+	now := time.Now()
+	if err := t.db.Where("expires_at < ?", now).Delete(&RefreshToken{}).Error; err != nil {
+		return err
+	}
+	return t.db.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error
+}
+
+// randomToken 生成URL安全的随机令牌
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken 计算令牌的SHA-256摘要
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// afterCommitKey 事务上下文中保存提交后回调的键
+type afterCommitKey struct{}
+
+// afterCommitHooks 事务提交后需要执行的回调，db为开启事务前的连接
+type afterCommitHooks struct {
+	db  *gorm.DB
+	mu  sync.Mutex
+	fns []func(db *gorm.DB)
+}
+
+// transaction 在事务中执行fc，事务提交后依次执行其中通过AfterCommit注册的回调，回滚时丢弃。
+// 嵌套调用时回调在最外层事务提交后执行
+func transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	ctx := db.Statement.Context
+	if _, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks); ok {
+		return db.Transaction(fc)
+	}
+
+	// 事务已经提交，请求取消时也要执行回调
+	hooks := &afterCommitHooks{db: db.WithContext(context.WithoutCancel(ctx))}
+	if err := db.WithContext(context.WithValue(ctx, afterCommitKey{}, hooks)).Transaction(fc); err != nil {
+		return err
+	}
+	for _, fn := range hooks.fns {
+		fn(hooks.db)
+	}
+	return nil
+}
+
+// AfterCommit 注册在tx所在事务提交后执行的回调，用于同步缓存、索引等数据库之外的状态，
+// 避免事务回滚后留下未提交的数据。tx不在事务中时立即执行；
+// 事务不是通过transaction开启时无法得知提交结果，也立即执行，回调读取tx可以看到事务中的写入
+func AfterCommit(tx *gorm.DB, fn func(db *gorm.DB)) {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); ok {
+		if hooks, ok := tx.Statement.Context.Value(afterCommitKey{}).(*afterCommitHooks); ok {
+			hooks.mu.Lock()
+			hooks.fns = append(hooks.fns, fn)
+			hooks.mu.Unlock()
+			return
+		}
+	}
+	fn(tx.Session(&gorm.Session{NewDB: true}))
+}
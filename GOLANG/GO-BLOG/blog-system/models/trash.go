@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 回收站内容类型
+const (
+	TrashTypePost    = "post"
+	TrashTypeComment = "comment"
+)
+
+// TrashQuery 回收站查询参数
+type TrashQuery struct {
+	PageQuery
+	Type string `form:"type" binding:"omitempty,oneof=post comment"`
+}
+
+// TrashCRUD 回收站操作：查询用户已删除的内容，清理超过保留期的内容
+type TrashCRUD struct {
+	db *gorm.DB
+}
+
+// NewTrashCRUD 创建回收站操作实例
+func NewTrashCRUD(db *gorm.DB) *TrashCRUD {
+	return &TrashCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (t *TrashCRUD) WithContext(ctx context.Context) *TrashCRUD {
+	copied := *t
+	copied.db = t.db.WithContext(ctx)
+	return &copied
+}
+
+// ListPosts 分页获取用户已删除的文章，按删除时间倒序排列
+func (t *TrashCRUD) ListPosts(userID uint, q *PageQuery) ([]Post, *Pagination, error) {
+	q.normalize()
+	db := t.db.Unscoped().Model(&Post{}).
+		Where("posts.user_id = ? AND posts.deleted_at IS NOT NULL", userID).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取回收站失败")
+	}
+
+	paged, err := paginate(db, q, "posts.deleted_at", "posts.id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var posts []Post
+	if err := paged.Preload("Category").Preload("Tags").Find(&posts).Error; err != nil {
+		return nil, nil, errors.New("获取回收站失败")
+	}
+
+	pagination := buildPagination(q, total, len(posts), func() (time.Time, uint) {
+		last := posts[q.Size-1]
+		return last.DeletedAt.Time, last.ID
+	})
+	if len(posts) > q.Size {
+		posts = posts[:q.Size]
+	}
+	return posts, pagination, nil
+}
+
+// ListComments 分页获取用户已删除的评论，按删除时间倒序排列
+func (t *TrashCRUD) ListComments(userID uint, q *PageQuery) ([]Comment, *Pagination, error) {
+	q.normalize()
+	db := t.db.Unscoped().Model(&Comment{}).
+		Where("comments.user_id = ? AND comments.deleted_at IS NOT NULL", userID).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取回收站失败")
+	}
+
+	paged, err := paginate(db, q, "comments.deleted_at", "comments.id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var comments []Comment
+	if err := paged.Find(&comments).Error; err != nil {
+		return nil, nil, errors.New("获取回收站失败")
+	}
+
+	pagination := buildPagination(q, total, len(comments), func() (time.Time, uint) {
+		last := comments[q.Size-1]
+		return last.DeletedAt.Time, last.ID
+	})
+	if len(comments) > q.Size {
+		comments = comments[:q.Size]
+	}
+	return comments, pagination, nil
+}
+
+// Purge 永久删除在指定时间之前移入回收站的评论、文章和用户。
+// 永久删除文章或用户时，数据库外键会级联删除其评论、修订记录等关联数据。
+func (t *TrashCRUD) Purge(before time.Time) (int64, error) {
+	var purged int64
+	for _, model := range []interface{}{&Comment{}, &Post{}, &User{}} {
+		result := t.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+	}
+	if purged > 0 {
+		if err := purgeInteractions(t.db); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
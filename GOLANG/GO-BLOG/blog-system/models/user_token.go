@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 一次性令牌用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken 邮箱验证和密码重置使用的一次性令牌，数据库中只保存令牌的SHA-256摘要
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index;comment:用户ID" json:"user_id"`
+	Purpose   string     `gorm:"not null;size:20;comment:令牌用途" json:"purpose"`
+	Email     string     `gorm:"not null;size:100;comment:签发时的邮箱" json:"email"`
+	TokenHash string     `gorm:"unique;not null;size:64;comment:令牌摘要" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index;comment:过期时间" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"comment:使用时间" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+
+	// 多对一关系：多个令牌属于一个用户
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// UserTokenCRUD 一次性令牌操作。令牌由用途、用户ID、过期时间和随机数组成并使用HMAC签名，
+// 签名或过期校验失败的令牌无需查询数据库；数据库记录保证令牌只能使用一次
+type UserTokenCRUD struct {
+	db     *gorm.DB
+	secret []byte
+}
+
+// NewUserTokenCRUD 创建一次性令牌操作实例，secret为签名密钥
+func NewUserTokenCRUD(db *gorm.DB, secret []byte) *UserTokenCRUD {
+	return &UserTokenCRUD{db: db, secret: secret}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (t *UserTokenCRUD) WithContext(ctx context.Context) *UserTokenCRUD {
+	copied := *t
+	copied.db = t.db.WithContext(ctx)
+	return &copied
+}
+
+// Issue 为用户签发指定用途的令牌，同一用途尚未使用的旧令牌一并作废
+func (t *UserTokenCRUD) Issue(user *User, purpose string, ttl time.Duration) (string, error) {
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", errors.New("令牌生成失败")
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	payload := strings.Join([]string{purpose, strconv.FormatUint(uint64(user.ID), 10), strconv.FormatInt(expiresAt.Unix(), 10), nonce}, ".")
+	raw := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + t.sign(payload)
+
+	err = transaction(t.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Omit("User").Create(&UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			Email:     user.Email,
+			TokenHash: hashToken(raw),
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", errors.New("令牌保存失败")
+	}
+	return raw, nil
+}
+
+// Consume 校验并使用令牌，成功后令牌立即失效
+func (t *UserTokenCRUD) Consume(raw, purpose string) (*UserToken, error) {
+	encoded, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, errors.New("无效的令牌")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(t.sign(string(payload)))) {
+		return nil, errors.New("无效的令牌")
+	}
+
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 4 || fields[0] != purpose {
+		return nil, errors.New("无效的令牌")
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, errors.New("无效的令牌")
+	}
+	if time.Now().Unix() > expires {
+		return nil, errors.New("令牌已过期")
+	}
+
+	var token UserToken
+	if err := t.db.Where("token_hash = ? AND purpose = ?", hashToken(raw), purpose).First(&token).Error; err != nil {
+		return nil, errors.New("无效的令牌")
+	}
+	if token.UsedAt != nil {
+		return nil, errors.New("令牌已使用")
+	}
+
+	// 条件更新防止并发请求重复使用同一个令牌
+	now := time.Now()
+	result := t.db.Model(&UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, errors.New("令牌校验失败")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("令牌已使用")
+	}
+	token.UsedAt = &now
+	return &token, nil
+}
+
+// PurgeExpired 清理已过期的令牌
+func (t *UserTokenCRUD) PurgeExpired() error {
+	return t.db.Where("expires_at < ?", time.Now()).Delete(&UserToken{}).Error
+}
+
+// sign 计算载荷的HMAC-SHA256签名
+func (t *UserTokenCRUD) sign(payload string) string {
+	h := hmac.New(sha256.New, t.secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
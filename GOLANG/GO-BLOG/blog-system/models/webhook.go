@@ -0,0 +1,499 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"blog-system/events"
+
+	"gorm.io/gorm"
+)
+
+// 投递状态
+const (
+	DeliveryPending = "pending" // 等待投递或等待重试
+	DeliverySuccess = "success" // 投递成功
+	DeliveryFailed  = "failed"  // 重试次数用尽或Webhook已停用
+)
+
+// WebhookEvents 可以订阅的事件类型
+var WebhookEvents = []string{
+	events.PostPublished, events.PostUpdated, events.PostUnpublished, events.PostDeleted,
+	events.CommentCreated, events.CommentUpdated, events.CommentDeleted,
+}
+
+// Webhook 外部系统订阅的事件回调地址
+type Webhook struct {
+	ID                  uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL                 string     `gorm:"not null;size:500;comment:回调地址" json:"url"`
+	Secret              string     `gorm:"not null;size:100;comment:签名密钥" json:"-"`
+	Events              []string   `gorm:"serializer:json;type:text;comment:订阅的事件类型" json:"events"`
+	Description         string     `gorm:"size:200;comment:说明" json:"description"`
+	Active              bool       `gorm:"not null;default:true;comment:是否启用" json:"active"`
+	ConsecutiveFailures int        `gorm:"not null;default:0;comment:连续投递失败次数" json:"consecutive_failures"`
+	DisabledAt          *time.Time `gorm:"comment:自动停用时间" json:"disabled_at,omitempty"`
+	DisabledReason      string     `gorm:"size:255;comment:自动停用原因" json:"disabled_reason,omitempty"`
+	CreatedBy           uint       `gorm:"not null;comment:创建人ID" json:"created_by"`
+	CreatedAt           time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+// Subscribes 判断Webhook是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookWithSecret 创建Webhook或更换密钥时返回的信息，签名密钥只在此时返回
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery 一个事件向一个Webhook的投递记录，包括重试状态和最后一次请求的结果
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookID      uint       `gorm:"not null;index:idx_delivery_webhook,priority:1;comment:WebhookID" json:"webhook_id"`
+	Event          string     `gorm:"not null;size:50;comment:事件类型" json:"event"`
+	EventID        string     `gorm:"not null;size:64;index;comment:事件ID" json:"event_id"`
+	Payload        string     `gorm:"not null;type:mediumtext;comment:请求内容" json:"payload"`
+	Status         string     `gorm:"not null;size:20;index:idx_delivery_due,priority:1;comment:投递状态" json:"status"`
+	Attempts       int        `gorm:"not null;default:0;comment:已投递次数" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index:idx_delivery_due,priority:2;comment:下次投递时间" json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `gorm:"comment:最后投递时间" json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `gorm:"default:0;comment:响应状态码" json:"response_status,omitempty"`
+	ResponseBody   string     `gorm:"type:text;comment:响应内容" json:"response_body,omitempty"`
+	Error          string     `gorm:"size:500;comment:失败原因" json:"error,omitempty"`
+	DurationMs     int64      `gorm:"default:0;comment:请求耗时(毫秒)" json:"duration_ms"`
+	ReplayOf       *uint      `gorm:"comment:重新投递的原记录ID" json:"replay_of,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index:idx_delivery_webhook,priority:2;comment:创建时间" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime;comment:更新时间" json:"updated_at"`
+
+	Webhook *Webhook `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// WebhookRequest 创建或修改Webhook请求，修改时secret为空表示保留原密钥
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=100"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=200"`
+	Active      *bool    `json:"active"`
+}
+
+// DeliveryQuery 投递记录查询参数
+type DeliveryQuery struct {
+	PageQuery
+	Status string `form:"status" binding:"omitempty,oneof=pending success failed"`
+}
+
+// DeliveryAttempt 一次投递请求的结果，Error为空表示投递成功
+type DeliveryAttempt struct {
+	StatusCode int
+	Response   string
+	Error      string
+	Duration   time.Duration
+}
+
+// RetryPolicy 投递失败后的重试和自动停用策略
+type RetryPolicy struct {
+	MaxAttempts  int           // 每条投递最多请求的次数
+	BaseDelay    time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay     time.Duration // 重试等待时间的上限
+	DisableAfter int           // 连续多少条投递最终失败后停用Webhook，0表示不自动停用
+}
+
+// Backoff 返回第attempts次请求失败后到下一次重试的等待时间
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// WebhookCRUD Webhook和投递记录操作
+type WebhookCRUD struct {
+	db *gorm.DB
+}
+
+// NewWebhookCRUD 创建Webhook CRUD实例
+func NewWebhookCRUD(db *gorm.DB) *WebhookCRUD {
+	return &WebhookCRUD{db: db}
+}
+
+// WithContext 返回在ctx中执行SQL的副本，传入请求的context时SQL日志会附带请求ID
+func (w *WebhookCRUD) WithContext(ctx context.Context) *WebhookCRUD {
+	copied := *w
+	copied.db = w.db.WithContext(ctx)
+	return &copied
+}
+
+// List 获取全部Webhook
+func (w *WebhookCRUD) List() ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := w.db.Order("id").Find(&webhooks).Error; err != nil {
+		return nil, errors.New("获取Webhook失败")
+	}
+	return webhooks, nil
+}
+
+// GetByID 获取Webhook
+func (w *WebhookCRUD) GetByID(id uint) (*Webhook, error) {
+	var webhook Webhook
+	if err := w.db.First(&webhook, id).Error; err != nil {
+		return nil, errors.New("Webhook不存在")
+	}
+	return &webhook, nil
+}
+
+// Create 创建Webhook，未指定密钥时随机生成，返回的密钥需要由调用方告知创建者
+func (w *WebhookCRUD) Create(req *WebhookRequest, userID uint) (*WebhookWithSecret, error) {
+	webhook := Webhook{Description: req.Description, Active: true, CreatedBy: userID}
+	if err := applyWebhookRequest(&webhook, req); err != nil {
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		secret, err := randomToken(32)
+		if err != nil {
+			return nil, errors.New("生成签名密钥失败")
+		}
+		webhook.Secret = secret
+	}
+
+	if err := w.db.Create(&webhook).Error; err != nil {
+		return nil, errors.New("Webhook创建失败")
+	}
+	// 创建时显式指定停用会被字段默认值覆盖，需单独更新
+	if req.Active != nil && !*req.Active {
+		if err := w.db.Model(&webhook).Update("active", false).Error; err != nil {
+			return nil, errors.New("Webhook创建失败")
+		}
+		webhook.Active = false
+	}
+	return &WebhookWithSecret{Webhook: webhook, Secret: webhook.Secret}, nil
+}
+
+// Update 修改Webhook。重新启用时清零连续失败次数，停用时取消尚未完成的投递
+func (w *WebhookCRUD) Update(id uint, req *WebhookRequest) (*Webhook, error) {
+	webhook, err := w.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	wasActive := webhook.Active
+
+	webhook.Description = req.Description
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if webhook.Active && !wasActive {
+		webhook.ConsecutiveFailures = 0
+		webhook.DisabledAt = nil
+		webhook.DisabledReason = ""
+	}
+
+	err = transaction(w.db, func(tx *gorm.DB) error {
+		if err := tx.Save(webhook).Error; err != nil {
+			return errors.New("Webhook更新失败")
+		}
+		if wasActive && !webhook.Active {
+			return cancelPendingDeliveries(tx, webhook.ID, "Webhook已停用")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Delete 删除Webhook及其投递记录
+func (w *WebhookCRUD) Delete(id uint) error {
+	webhook, err := w.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return transaction(w.db, func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+			return errors.New("Webhook删除失败")
+		}
+		if err := tx.Delete(webhook).Error; err != nil {
+			return errors.New("Webhook删除失败")
+		}
+		return nil
+	})
+}
+
+// applyWebhookRequest 校验并应用请求中的地址、密钥和事件类型
+func applyWebhookRequest(webhook *Webhook, req *WebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook地址必须是http或https地址")
+	}
+
+	subscribed := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("无效的事件类型: %s", event)
+		}
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = subscribed
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	return nil
+}
+
+// Enqueue 为订阅了事件的所有启用中的Webhook创建待投递记录，返回创建的记录数
+func (w *WebhookCRUD) Enqueue(event, eventID string, payload []byte) (int, error) {
+	var active []Webhook
+	if err := w.db.Where("active = ?", true).Find(&active).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	for i := range active {
+		if active[i].Subscribes(event) {
+			deliveries = append(deliveries, WebhookDelivery{
+				WebhookID:     active[i].ID,
+				Event:         event,
+				EventID:       eventID,
+				Payload:       string(payload),
+				Status:        DeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	if err := w.db.Create(&deliveries).Error; err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// ClaimDue 领取最多limit条已到投递时间的记录，并将其下次投递时间推迟lease作为租约。
+// 逐条按条件更新，多实例同时运行时同一条记录只会被一个实例领取；
+// 进程在租约期内退出时，记录会在租约到期后被重新领取
+func (w *WebhookCRUD) ClaimDue(now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	var due []WebhookDelivery
+	if err := w.db.Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+		Order("next_attempt_at, id").Limit(limit).Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := make([]WebhookDelivery, 0, len(due))
+	leaseUntil := now.Add(lease)
+	for i := range due {
+		delivery := due[i]
+		result := w.db.Model(&WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, DeliveryPending, now).
+			Update("next_attempt_at", leaseUntil)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = &leaseUntil
+		var webhook Webhook
+		if err := w.db.First(&webhook, delivery.WebhookID).Error; err != nil {
+			continue
+		}
+		delivery.Webhook = &webhook
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+// RecordAttempt 记录一次投递的结果：成功后清零Webhook的连续失败次数；
+// 失败时按退避策略安排重试，重试次数用尽后计入连续失败次数，达到阈值时停用Webhook并取消其余待投递记录。
+// 返回Webhook是否因此被停用
+func (w *WebhookCRUD) RecordAttempt(delivery *WebhookDelivery, attempt DeliveryAttempt, policy RetryPolicy) (bool, error) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = attempt.StatusCode
+	delivery.ResponseBody = attempt.Response
+	delivery.Error = truncate(attempt.Error, 500)
+	delivery.DurationMs = attempt.Duration.Milliseconds()
+
+	final := false
+	switch {
+	case attempt.Error == "":
+		delivery.Status = DeliverySuccess
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts < policy.MaxAttempts:
+		next := now.Add(policy.Backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	default:
+		delivery.Status = DeliveryFailed
+		delivery.NextAttemptAt = nil
+		final = true
+	}
+
+	disabled := false
+	err := transaction(w.db, func(tx *gorm.DB) error {
+		if err := tx.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_attempt_at",
+			"response_status", "response_body", "error", "duration_ms").Updates(delivery).Error; err != nil {
+			return err
+		}
+
+		webhook := tx.Model(&Webhook{}).Where("id = ?", delivery.WebhookID)
+		if delivery.Status == DeliverySuccess {
+			return webhook.UpdateColumn("consecutive_failures", 0).Error
+		}
+		if !final {
+			return nil
+		}
+		if err := webhook.UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return err
+		}
+		if policy.DisableAfter <= 0 {
+			return nil
+		}
+
+		result := tx.Model(&Webhook{}).
+			Where("id = ? AND active = ? AND consecutive_failures >= ?", delivery.WebhookID, true, policy.DisableAfter).
+			Updates(map[string]interface{}{
+				"active":          false,
+				"disabled_at":     now,
+				"disabled_reason": truncate(fmt.Sprintf("连续%d次投递失败，最后一次: %s", policy.DisableAfter, delivery.Error), 255),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		disabled = true
+		return cancelPendingDeliveries(tx, delivery.WebhookID, "Webhook已自动停用")
+	})
+	return disabled, err
+}
+
+// Cancel 放弃投递，用于领取后发现Webhook已停用的记录
+func (w *WebhookCRUD) Cancel(delivery *WebhookDelivery, reason string) error {
+	return w.db.Model(delivery).Updates(map[string]interface{}{
+		"status":          DeliveryFailed,
+		"next_attempt_at": nil,
+		"error":           reason,
+	}).Error
+}
+
+// cancelPendingDeliveries 将Webhook尚未完成的投递标记为失败
+func cancelPendingDeliveries(tx *gorm.DB, webhookID uint, reason string) error {
+	return tx.Model(&WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhookID, DeliveryPending).
+		Updates(map[string]interface{}{
+			"status":          DeliveryFailed,
+			"next_attempt_at": nil,
+			"error":           reason,
+		}).Error
+}
+
+// ListDeliveries 分页获取Webhook的投递记录，按创建时间倒序排列
+func (w *WebhookCRUD) ListDeliveries(webhookID uint, q *DeliveryQuery) ([]WebhookDelivery, *Pagination, error) {
+	q.normalize()
+
+	if _, err := w.GetByID(webhookID); err != nil {
+		return nil, nil, err
+	}
+
+	db := w.db.Model(&WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, errors.New("获取投递记录失败")
+	}
+
+	paged, err := paginate(db, &q.PageQuery, "created_at", "id", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var deliveries []WebhookDelivery
+	if err := paged.Find(&deliveries).Error; err != nil {
+		return nil, nil, errors.New("获取投递记录失败")
+	}
+
+	pagination := buildPagination(&q.PageQuery, total, len(deliveries), func() (time.Time, uint) {
+		last := deliveries[q.Size-1]
+		return last.CreatedAt, last.ID
+	})
+	if len(deliveries) > q.Size {
+		deliveries = deliveries[:q.Size]
+	}
+	return deliveries, pagination, nil
+}
+
+// GetDelivery 获取Webhook的单条投递记录
+func (w *WebhookCRUD) GetDelivery(webhookID, id uint) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := w.db.Where("webhook_id = ?", webhookID).First(&delivery, id).Error; err != nil {
+		return nil, errors.New("投递记录不存在")
+	}
+	return &delivery, nil
+}
+
+// Replay 以相同的事件ID和内容重新投递，创建一条新的待投递记录。已停用的Webhook需先启用
+func (w *WebhookCRUD) Replay(webhookID, id uint) (*WebhookDelivery, error) {
+	webhook, err := w.GetByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, errors.New("Webhook已停用，请先启用")
+	}
+	original, err := w.GetDelivery(webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	replay := WebhookDelivery{
+		WebhookID:     webhook.ID,
+		Event:         original.Event,
+		EventID:       original.EventID,
+		Payload:       original.Payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		ReplayOf:      &original.ID,
+	}
+	if err := w.db.Create(&replay).Error; err != nil {
+		return nil, errors.New("重新投递失败")
+	}
+	return &replay, nil
+}
+
+// PurgeDeliveries 删除before之前创建且已完成的投递记录，返回删除数量
+func (w *WebhookCRUD) PurgeDeliveries(before time.Time) (int64, error) {
+	result := w.db.Where("status <> ? AND created_at < ?", DeliveryPending, before).Delete(&WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// truncate 将字符串截断为最多n个字符，避免超出字段长度
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"blog-system/events"
+	"blog-system/internal/testdb"
+)
+
+// newTestWebhook 创建订阅文章发布事件的Webhook并为其创建一条待投递记录
+func newTestWebhook(t *testing.T, crud *WebhookCRUD) (*WebhookWithSecret, *WebhookDelivery) {
+	t.Helper()
+	hook, err := crud.Create(&WebhookRequest{URL: "https://example.com/hook", Events: []string{events.PostPublished}}, 1)
+	if err != nil {
+		t.Fatalf("创建Webhook失败: %v", err)
+	}
+	if n, err := crud.Enqueue(events.PostPublished, "evt-1", []byte(`{"id":"evt-1"}`)); err != nil || n != 1 {
+		t.Fatalf("Enqueue() = %d, %v", n, err)
+	}
+	var delivery WebhookDelivery
+	if err := crud.db.Where("webhook_id = ?", hook.ID).Last(&delivery).Error; err != nil {
+		t.Fatalf("获取投递记录失败: %v", err)
+	}
+	return hook, &delivery
+}
+
+// newTestWebhookDelivery 为已有的Webhook再创建一条待投递记录
+func newTestWebhookDelivery(t *testing.T, crud *WebhookCRUD, webhookID uint) *WebhookDelivery {
+	t.Helper()
+	if _, err := crud.Enqueue(events.PostPublished, "evt-next", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	var delivery WebhookDelivery
+	if err := crud.db.Where("webhook_id = ?", webhookID).Last(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &delivery
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := policy.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestEnqueueOnlySubscribedActiveWebhooks(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	inactive := false
+	for _, req := range []*WebhookRequest{
+		{URL: "https://a.example.com", Events: []string{events.PostPublished}},
+		{URL: "https://b.example.com", Events: []string{events.CommentCreated}},
+		{URL: "https://c.example.com", Events: []string{events.PostPublished}, Active: &inactive},
+	} {
+		if _, err := crud.Create(req, 1); err != nil {
+			t.Fatalf("创建Webhook失败: %v", err)
+		}
+	}
+
+	n, err := crud.Enqueue(events.PostPublished, "evt", []byte("{}"))
+	if err != nil || n != 1 {
+		t.Fatalf("Enqueue() = %d, %v, want 1", n, err)
+	}
+}
+
+func TestRecordAttemptSchedulesRetries(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	hook, delivery := newTestWebhook(t, crud)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, DisableAfter: 5}
+	failure := DeliveryAttempt{StatusCode: 500, Response: "boom", Error: "接收方返回状态码 500", Duration: 20 * time.Millisecond}
+
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		before := time.Now()
+		disabled, err := crud.RecordAttempt(delivery, failure, policy)
+		if err != nil || disabled {
+			t.Fatalf("第%d次RecordAttempt() = %v, %v", attempt, disabled, err)
+		}
+
+		saved, err := crud.GetDelivery(hook.ID, delivery.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Status != DeliveryPending || saved.Attempts != attempt {
+			t.Fatalf("第%d次失败后 status=%s attempts=%d", attempt, saved.Status, saved.Attempts)
+		}
+		wait := saved.NextAttemptAt.Sub(before)
+		if backoff := policy.Backoff(attempt); wait < backoff || wait > backoff+time.Second {
+			t.Fatalf("第%d次失败后等待 %v, want %v", attempt, wait, backoff)
+		}
+		if saved.ResponseStatus != 500 || saved.ResponseBody != "boom" || saved.DurationMs != 20 {
+			t.Fatalf("未记录响应: %+v", saved)
+		}
+	}
+
+	if _, err := crud.RecordAttempt(delivery, failure, policy); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := crud.GetDelivery(hook.ID, delivery.ID)
+	if saved.Status != DeliveryFailed || saved.NextAttemptAt != nil || saved.Attempts != policy.MaxAttempts {
+		t.Fatalf("重试次数用尽后 status=%s next=%v attempts=%d", saved.Status, saved.NextAttemptAt, saved.Attempts)
+	}
+	webhook, _ := crud.GetByID(hook.ID)
+	if webhook.ConsecutiveFailures != 1 || !webhook.Active {
+		t.Fatalf("连续失败次数=%d active=%v, want 1 true", webhook.ConsecutiveFailures, webhook.Active)
+	}
+
+	// 投递成功后清零连续失败次数
+	next := newTestWebhookDelivery(t, crud, hook.ID)
+	if _, err := crud.RecordAttempt(next, DeliveryAttempt{StatusCode: 200}, policy); err != nil {
+		t.Fatal(err)
+	}
+	webhook, _ = crud.GetByID(hook.ID)
+	saved, _ = crud.GetDelivery(hook.ID, next.ID)
+	if webhook.ConsecutiveFailures != 0 || saved.Status != DeliverySuccess || saved.NextAttemptAt != nil {
+		t.Fatalf("成功后 连续失败次数=%d status=%s", webhook.ConsecutiveFailures, saved.Status)
+	}
+}
+
+func TestRecordAttemptDisablesWebhook(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	hook, first := newTestWebhook(t, crud)
+	policy := RetryPolicy{MaxAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, DisableAfter: 2}
+	failure := DeliveryAttempt{Error: "connection refused"}
+
+	disabled, err := crud.RecordAttempt(first, failure, policy)
+	if err != nil || disabled {
+		t.Fatalf("第一次最终失败 RecordAttempt() = %v, %v, want false", disabled, err)
+	}
+
+	second := newTestWebhookDelivery(t, crud, hook.ID)
+	pending := newTestWebhookDelivery(t, crud, hook.ID)
+	disabled, err = crud.RecordAttempt(second, failure, policy)
+	if err != nil || !disabled {
+		t.Fatalf("第二次最终失败 RecordAttempt() = %v, %v, want true", disabled, err)
+	}
+
+	webhook, _ := crud.GetByID(hook.ID)
+	if webhook.Active || webhook.DisabledAt == nil || webhook.DisabledReason == "" {
+		t.Fatalf("Webhook应已停用: %+v", webhook)
+	}
+	saved, _ := crud.GetDelivery(hook.ID, pending.ID)
+	if saved.Status != DeliveryFailed || saved.Error != "Webhook已自动停用" {
+		t.Fatalf("停用后其余待投递记录应被取消: %+v", saved)
+	}
+	if n, _ := crud.Enqueue(events.PostPublished, "evt-after", []byte("{}")); n != 0 {
+		t.Fatalf("停用后不应再创建投递记录, got %d", n)
+	}
+
+	// 重新启用后清零连续失败次数
+	active := true
+	webhook, err = crud.Update(hook.ID, &WebhookRequest{URL: webhook.URL, Events: webhook.Events, Active: &active})
+	if err != nil || !webhook.Active || webhook.ConsecutiveFailures != 0 || webhook.DisabledAt != nil {
+		t.Fatalf("重新启用 = %+v, %v", webhook, err)
+	}
+}
+
+func TestClaimDueLease(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	hook, delivery := newTestWebhook(t, crud)
+	lease := time.Minute
+	now := time.Now().Add(time.Second)
+
+	claimed, err := crud.ClaimDue(now, lease, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != delivery.ID {
+		t.Fatalf("ClaimDue() = %v, %v, want 1条", claimed, err)
+	}
+	if claimed[0].Webhook == nil || claimed[0].Webhook.ID != hook.ID || claimed[0].Webhook.Secret != hook.Secret {
+		t.Fatalf("领取的记录应带有Webhook: %+v", claimed[0].Webhook)
+	}
+	if !claimed[0].NextAttemptAt.Equal(now.Add(lease)) {
+		t.Fatalf("NextAttemptAt = %v, want %v", claimed[0].NextAttemptAt, now.Add(lease))
+	}
+
+	// 租约期内不能被再次领取
+	if again, err := crud.ClaimDue(now.Add(lease/2), lease, 10); err != nil || len(again) != 0 {
+		t.Fatalf("租约期内 ClaimDue() = %v, %v, want 0条", again, err)
+	}
+	// 租约到期后视为处理中断，可以重新领取
+	again, err := crud.ClaimDue(now.Add(lease+time.Second), lease, 10)
+	if err != nil || len(again) != 1 || again[0].ID != delivery.ID {
+		t.Fatalf("租约到期后 ClaimDue() = %v, %v, want 1条", again, err)
+	}
+
+	// 已完成的记录不会被领取
+	if _, err := crud.RecordAttempt(&again[0], DeliveryAttempt{StatusCode: 200}, RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := crud.ClaimDue(now.Add(time.Hour), lease, 10); err != nil || len(done) != 0 {
+		t.Fatalf("投递成功后 ClaimDue() = %v, %v, want 0条", done, err)
+	}
+}
+
+func TestClaimDueLimit(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	hook, _ := newTestWebhook(t, crud)
+	newTestWebhookDelivery(t, crud, hook.ID)
+	newTestWebhookDelivery(t, crud, hook.ID)
+
+	now := time.Now().Add(time.Second)
+	first, err := crud.ClaimDue(now, time.Minute, 2)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("ClaimDue(limit=2) = %d条, %v", len(first), err)
+	}
+	rest, err := crud.ClaimDue(now, time.Minute, 2)
+	if err != nil || len(rest) != 1 || rest[0].ID == first[0].ID || rest[0].ID == first[1].ID {
+		t.Fatalf("第二批 ClaimDue() = %v, %v, want 剩余的1条", rest, err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	crud := NewWebhookCRUD(testdb.New(t, AutoMigrate))
+	hook, delivery := newTestWebhook(t, crud)
+	if _, err := crud.RecordAttempt(delivery, DeliveryAttempt{Error: "timeout"}, RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := crud.Replay(hook.ID, delivery.ID)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if replay.ID == delivery.ID || replay.ReplayOf == nil || *replay.ReplayOf != delivery.ID {
+		t.Fatalf("应创建引用原记录的新记录: %+v", replay)
+	}
+	if replay.Status != DeliveryPending || replay.Attempts != 0 || replay.EventID != delivery.EventID || replay.Payload != delivery.Payload {
+		t.Fatalf("重新投递的记录不正确: %+v", replay)
+	}
+	claimed, err := crud.ClaimDue(time.Now().Add(time.Second), time.Minute, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != replay.ID {
+		t.Fatalf("重新投递的记录应可被领取: %v, %v", claimed, err)
+	}
+
+	if _, err := crud.Replay(hook.ID, 9999); err == nil || err.Error() != "投递记录不存在" {
+		t.Fatalf("Replay(不存在) error = %v", err)
+	}
+	inactive := false
+	if _, err := crud.Update(hook.ID, &WebhookRequest{URL: hook.URL, Events: hook.Events, Active: &inactive}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crud.Replay(hook.ID, delivery.ID); err == nil || err.Error() != "Webhook已停用，请先启用" {
+		t.Fatalf("Replay(已停用) error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"strings"
+	"time"
+)
+
+// lockoutMemory 最后一次失败后保留登录失败状态的时间，期间再次被锁定时锁定时长继续递增
+const lockoutMemory = 24 * time.Hour
+
+// LoginGuard 登录暴力破解防护：同一用户名在统计窗口内连续失败达到阈值后锁定，
+// 每次锁定的时长是上一次的两倍，直到达到上限；登录成功后清除失败记录
+type LoginGuard struct {
+	store     Store
+	threshold int
+	window    time.Duration
+	base      time.Duration
+	max       time.Duration
+}
+
+// NewLoginGuard 创建登录防护，threshold不大于0时返回nil，表示不限制
+func NewLoginGuard(store Store, threshold int, window, base, max time.Duration) *LoginGuard {
+	if threshold <= 0 {
+		return nil
+	}
+	return &LoginGuard{store: store, threshold: threshold, window: window, base: base, max: max}
+}
+
+// Check 返回用户名剩余的锁定时间，未锁定时返回0
+func (g *LoginGuard) Check(username string, now time.Time) time.Duration {
+	if g == nil {
+		return 0
+	}
+	state := g.store.Lockout(lockoutKey(username), now)
+	if now.Before(state.LockedUntil) {
+		return state.LockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// Fail 记录一次登录失败，达到阈值时锁定用户名并返回锁定时长
+func (g *LoginGuard) Fail(username string, now time.Time) time.Duration {
+	if g == nil {
+		return 0
+	}
+	state := g.store.UpdateLockout(lockoutKey(username), now, lockoutMemory, func(s *LockoutState) {
+		if now.Sub(s.WindowStart) > g.window {
+			s.Failures = 0
+			s.WindowStart = now
+		}
+		s.Failures++
+		if s.Failures < g.threshold {
+			return
+		}
+
+		// 锁定时长按锁定次数翻倍递增
+		duration := g.base
+		for i := 0; i < s.Lockouts && duration < g.max; i++ {
+			duration *= 2
+		}
+		if duration > g.max {
+			duration = g.max
+		}
+		s.Lockouts++
+		s.Failures = 0
+		s.LockedUntil = now.Add(duration)
+	})
+	if now.Before(state.LockedUntil) {
+		return state.LockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// Succeed 登录成功后清除失败记录
+func (g *LoginGuard) Succeed(username string) {
+	if g == nil {
+		return
+	}
+	g.store.ResetLockout(lockoutKey(username))
+}
+
+// lockoutKey 用户名不区分大小写，与数据库的默认排序规则一致
+func lockoutKey(username string) string {
+	return "login:" + strings.ToLower(username)
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理过期状态的间隔
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // 令牌桶补满的时间，之后该状态与不存在等价
+}
+
+type lockoutEntry struct {
+	state   LockoutState
+	expires time.Time
+}
+
+// Memory 进程内的限流状态存储
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lockouts  map[string]*lockoutEntry
+	lastSweep time.Time
+}
+
+// NewMemory 创建内存限流状态存储
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:  make(map[string]*bucket),
+		lockouts: make(map[string]*lockoutEntry),
+	}
+}
+
+// Take 从key对应的令牌桶中取出一个令牌
+func (m *Memory) Take(key string, limit Limit, now time.Time) Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	burst := float64(limit.Burst)
+	rate := limit.rate()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		m.buckets[key] = b
+	}
+
+	// 按经过的时间补充令牌
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+		b.last = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+	return result
+}
+
+// Lockout 获取key对应的登录失败状态
+func (m *Memory) Lockout(key string, now time.Time) LockoutState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.lockouts[key]; ok && now.Before(e.expires) {
+		return e.state
+	}
+	return LockoutState{}
+}
+
+// UpdateLockout 原子地修改key对应的登录失败状态
+func (m *Memory) UpdateLockout(key string, now time.Time, ttl time.Duration, update func(*LockoutState)) LockoutState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	e, ok := m.lockouts[key]
+	if !ok || !now.Before(e.expires) {
+		e = &lockoutEntry{}
+		m.lockouts[key] = e
+	}
+	update(&e.state)
+	e.expires = now.Add(ttl)
+	return e.state
+}
+
+// ResetLockout 清除key对应的登录失败状态
+func (m *Memory) ResetLockout(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.lockouts, key)
+}
+
+// sweep 定期删除已补满的令牌桶和过期的登录失败状态，调用方需持有锁
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, e := range m.lockouts {
+		if !now.Before(e.expires) {
+			delete(m.lockouts, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import "time"
+
+// Limit 令牌桶参数：桶容量为Burst，每隔Period/Burst补充一个令牌
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// rate 每秒补充的令牌数
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result 一次取令牌的结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 桶容量
+	Remaining  int           // 剩余令牌数
+	Reset      time.Duration // 令牌桶补满所需时间
+	RetryAfter time.Duration // 被拒绝时距离下一个可用令牌的时间
+}
+
+// LockoutState 登录失败状态
+type LockoutState struct {
+	Failures    int       // 当前统计窗口内的失败次数
+	WindowStart time.Time // 统计窗口的开始时间
+	Lockouts    int       // 连续被锁定的次数，用于递增锁定时长
+	LockedUntil time.Time // 锁定截止时间
+}
+
+// Store 限流状态存储。内存实现只在单个进程内生效，多实例部署时可替换为共享存储(如Redis)的实现
+type Store interface {
+	// Take 从key对应的令牌桶中取出一个令牌
+	Take(key string, limit Limit, now time.Time) Result
+	// Lockout 获取key对应的登录失败状态，不存在时返回零值
+	Lockout(key string, now time.Time) LockoutState
+	// UpdateLockout 原子地修改key对应的登录失败状态，状态在now+ttl之后过期
+	UpdateLockout(key string, now time.Time, ttl time.Duration, update func(*LockoutState)) LockoutState
+	// ResetLockout 清除key对应的登录失败状态
+	ResetLockout(key string)
+}
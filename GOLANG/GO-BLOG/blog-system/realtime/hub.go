@@ -0,0 +1,169 @@
+// Package realtime 实时推送的连接中心：按主题分发消息，保留最近的消息供断线重连时按Last-Event-ID补发
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理过期历史消息的间隔
+const sweepInterval = time.Minute
+
+// Message 推送给客户端的消息，ID在整个连接中心内单调递增
+type Message struct {
+	ID    uint64
+	Event string
+	Data  []byte // JSON编码的消息内容
+
+	at time.Time
+}
+
+// Subscription 一个客户端连接对主题的订阅
+type Subscription struct {
+	// C 待推送的消息。客户端处理过慢导致队列已满时连接中心会关闭C并取消订阅，
+	// 客户端应断开连接后携带最后收到的消息ID重连
+	C <-chan Message
+
+	ch    chan Message
+	topic string
+}
+
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	history     []Message
+}
+
+// Hub 实时推送的连接中心
+type Hub struct {
+	mu        sync.Mutex
+	topics    map[string]*topic
+	lastID    uint64
+	buffer    int
+	replay    int
+	replayTTL time.Duration
+	lastSweep time.Time
+}
+
+// NewHub 创建连接中心。buffer为每个订阅的待推送队列长度，
+// 每个主题最多保留replay条、replayTTL之内的消息用于补发
+func NewHub(buffer, replay int, replayTTL time.Duration) *Hub {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	return &Hub{
+		topics:    make(map[string]*topic),
+		buffer:    buffer,
+		replay:    replay,
+		replayTTL: replayTTL,
+	}
+}
+
+// Publish 向主题的所有订阅者推送消息，data按JSON编码。队列已满的订阅会被取消，不会阻塞发布方
+func (h *Hub) Publish(name, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.sweep(now)
+
+	h.lastID++
+	msg := Message{ID: h.lastID, Event: event, Data: payload, at: now}
+
+	t := h.topic(name)
+	if h.replay > 0 {
+		t.history = append(t.history, msg)
+		if len(t.history) > h.replay {
+			t.history = t.history[len(t.history)-h.replay:]
+		}
+	}
+
+	for s := range t.subscribers {
+		select {
+		case s.ch <- msg:
+		default:
+			h.remove(t, s)
+		}
+	}
+	if len(t.subscribers) == 0 && len(t.history) == 0 {
+		delete(h.topics, name)
+	}
+	return nil
+}
+
+// Subscribe 订阅主题。lastID大于0时返回该ID之后仍保留的历史消息，
+// 补发与订阅在同一把锁内完成，两者之间发布的消息不会丢失
+func (h *Hub) Subscribe(name string, lastID uint64) (*Subscription, []Message) {
+	ch := make(chan Message, h.buffer)
+	s := &Subscription{C: ch, ch: ch, topic: name}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep(time.Now())
+
+	t := h.topic(name)
+	t.subscribers[s] = struct{}{}
+
+	var missed []Message
+	if lastID > 0 {
+		for _, msg := range t.history {
+			if msg.ID > lastID {
+				missed = append(missed, msg)
+			}
+		}
+	}
+	return s, missed
+}
+
+// Unsubscribe 取消订阅，重复调用不产生影响
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.topics[s.topic]; ok {
+		if _, ok := t.subscribers[s]; ok {
+			h.remove(t, s)
+		}
+		if len(t.subscribers) == 0 && len(t.history) == 0 {
+			delete(h.topics, s.topic)
+		}
+	}
+}
+
+func (h *Hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{subscribers: make(map[*Subscription]struct{})}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// remove 移除订阅并关闭其队列，需持有锁
+func (h *Hub) remove(t *topic, s *Subscription) {
+	delete(t.subscribers, s)
+	close(s.ch)
+}
+
+// sweep 定期删除过期的历史消息和不再使用的主题，需持有锁
+func (h *Hub) sweep(now time.Time) {
+	if now.Sub(h.lastSweep) < sweepInterval {
+		return
+	}
+	h.lastSweep = now
+
+	cutoff := now.Add(-h.replayTTL)
+	for name, t := range h.topics {
+		i := 0
+		for i < len(t.history) && t.history[i].at.Before(cutoff) {
+			i++
+		}
+		t.history = t.history[i:]
+		if len(t.subscribers) == 0 && len(t.history) == 0 {
+			delete(h.topics, name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// job 周期性后台任务
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler 后台任务调度器，每个任务在独立的goroutine中按固定间隔执行
+type Scheduler struct {
+	jobs []job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New 创建调度器
+func New() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Every 注册按固定间隔执行的任务，需在Start之前调用
+func (s *Scheduler) Every(interval time.Duration, name string, run func() error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start 启动所有任务，任务启动时立即执行一次
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop 停止所有任务并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(j)
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// runOnce 执行一次任务，任务panic不会影响调度器
+func (s *Scheduler) runOnce(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("⏰ 后台任务异常", "job", j.name, "error", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+
+	if err := j.run(); err != nil {
+		slog.Error("⏰ 后台任务执行失败", "job", j.name, "error", err)
+	}
+}
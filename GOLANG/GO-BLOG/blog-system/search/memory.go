@@ -0,0 +1,337 @@
+package search
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"blog-system/models"
+
+	"gorm.io/gorm"
+)
+
+// 各字段的权重，标题命中比正文命中更重要
+const (
+	weightTitle   = 3.0
+	weightSummary = 2.0
+	weightContent = 1.0
+)
+
+// docKey 文档标识
+type docKey struct {
+	kind string
+	id   uint
+}
+
+// document 索引中的文档
+type document struct {
+	key       docKey
+	postID    uint
+	title     string
+	text      string
+	createdAt time.Time
+	terms     map[string]float64
+}
+
+// MemoryIndex 进程内倒排索引，适用于SQLite和测试环境。
+// 通过GORM回调在文章和评论写入后自动更新索引。
+type MemoryIndex struct {
+	db       *gorm.DB
+	mu       sync.RWMutex
+	docs     map[docKey]*document
+	postings map[string]map[docKey]float64
+}
+
+// NewMemoryIndex 创建进程内倒排索引，加载现有数据并注册写入回调
+func NewMemoryIndex(db *gorm.DB) (*MemoryIndex, error) {
+	idx := &MemoryIndex{
+		db:       db,
+		docs:     make(map[docKey]*document),
+		postings: make(map[string]map[docKey]float64),
+	}
+
+	if err := idx.Rebuild(); err != nil {
+		return nil, err
+	}
+
+	// 在默认事务提交或回滚之后执行，回滚的写入不会进入索引
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("search:index_create", idx.afterWrite); err != nil {
+		return nil, err
+	}
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("search:index_update", idx.afterWrite); err != nil {
+		return nil, err
+	}
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("search:index_delete", idx.afterWrite); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// Rebuild 从数据库重建全部索引
+func (m *MemoryIndex) Rebuild() error {
+	var posts []models.Post
+	if err := m.db.Find(&posts).Error; err != nil {
+		return err
+	}
+	var comments []models.Comment
+	if err := m.db.Find(&comments).Error; err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs = make(map[docKey]*document)
+	m.postings = make(map[string]map[docKey]float64)
+	for i := range posts {
+		m.putPost(&posts[i])
+	}
+	for i := range comments {
+		m.putComment(&comments[i])
+	}
+	return nil
+}
+
+// afterWrite GORM回调：根据主键重新加载文章或评论并刷新索引。
+// 写入发生在事务中时，等事务提交后再刷新，事务回滚时不刷新
+func (m *MemoryIndex) afterWrite(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.Schema == nil {
+		return
+	}
+
+	table := tx.Statement.Schema.Table
+	if table != "posts" && table != "comments" {
+		return
+	}
+
+	field := tx.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return
+	}
+
+	var ids []uint
+	collect := func(v reflect.Value) {
+		if value, zero := field.ValueOf(tx.Statement.Context, v); !zero {
+			if id, ok := value.(uint); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	rv := reflect.Indirect(tx.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			collect(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		collect(rv)
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+	models.AfterCommit(tx, func(db *gorm.DB) {
+		for _, id := range ids {
+			if table == "posts" {
+				m.refreshPost(db, id)
+			} else {
+				m.refreshComment(db, id)
+			}
+		}
+	})
+}
+
+func (m *MemoryIndex) refreshPost(db *gorm.DB, id uint) {
+	var post models.Post
+	err := db.First(&post, id).Error
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.remove(docKey{TypePost, id})
+		// 文章被删除(包括移入回收站)时，同步移除其评论的索引
+		for key, doc := range m.docs {
+			if key.kind == TypeComment && doc.postID == id {
+				m.remove(key)
+			}
+		}
+		return
+	}
+
+	// 文章恢复或重新发布后，其评论需要重新加入索引
+	_, indexed := m.docs[docKey{TypePost, id}]
+	m.putPost(&post)
+	if _, ok := m.docs[docKey{TypePost, id}]; ok && !indexed {
+		var comments []models.Comment
+		db.Where("post_id = ?", id).Find(&comments)
+		for i := range comments {
+			m.putComment(&comments[i])
+		}
+	}
+}
+
+func (m *MemoryIndex) refreshComment(db *gorm.DB, id uint) {
+	var comment models.Comment
+	err := db.First(&comment, id).Error
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.remove(docKey{TypeComment, id})
+		return
+	}
+	m.putComment(&comment)
+}
+
+// putPost 索引文章，只有已发布的文章可被搜索，调用方需持有写锁
+func (m *MemoryIndex) putPost(post *models.Post) {
+	if post.Status != models.PostStatusPublished {
+		m.remove(docKey{TypePost, post.ID})
+		return
+	}
+
+	terms := make(map[string]float64)
+	addTerms(terms, post.Title, weightTitle)
+	addTerms(terms, post.Summary, weightSummary)
+	addTerms(terms, post.Content, weightContent)
+
+	m.put(&document{
+		key:       docKey{TypePost, post.ID},
+		postID:    post.ID,
+		title:     post.Title,
+		text:      post.Title + "\n" + post.Summary + "\n" + post.Content,
+		createdAt: post.CreatedAt,
+		terms:     terms,
+	})
+}
+
+// putComment 索引评论，只有审核通过的评论可被搜索，调用方需持有写锁
+func (m *MemoryIndex) putComment(comment *models.Comment) {
+	if comment.Status != models.CommentStatusApproved {
+		m.remove(docKey{TypeComment, comment.ID})
+		return
+	}
+
+	terms := make(map[string]float64)
+	addTerms(terms, comment.Content, weightContent)
+
+	m.put(&document{
+		key:       docKey{TypeComment, comment.ID},
+		postID:    comment.PostID,
+		text:      comment.Content,
+		createdAt: comment.CreatedAt,
+		terms:     terms,
+	})
+}
+
+func (m *MemoryIndex) put(doc *document) {
+	m.remove(doc.key)
+	m.docs[doc.key] = doc
+	for term, weight := range doc.terms {
+		if m.postings[term] == nil {
+			m.postings[term] = make(map[docKey]float64)
+		}
+		m.postings[term][doc.key] = weight
+	}
+}
+
+func (m *MemoryIndex) remove(key docKey) {
+	doc, ok := m.docs[key]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(m.postings[term], key)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	delete(m.docs, key)
+}
+
+func addTerms(terms map[string]float64, text string, weight float64) {
+	for _, t := range Tokenize(text) {
+		terms[t] += weight
+	}
+}
+
+// Search 在倒排索引中检索，所有查询词都必须命中，按TF-IDF加权得分排序
+func (m *MemoryIndex) Search(ctx context.Context, q *Query) ([]Result, int64, error) {
+	q.Normalize()
+	terms := uniqueTokens(q.Text)
+	if len(terms) == 0 {
+		return []Result{}, 0, nil
+	}
+
+	m.mu.RLock()
+	scores := make(map[docKey]float64)
+	for i, term := range terms {
+		postings := m.postings[term]
+		idf := math.Log(1 + float64(len(m.docs))/float64(1+len(postings)))
+		next := make(map[docKey]float64)
+		for key, weight := range postings {
+			if key.kind == TypeComment && !q.IncludeComments {
+				continue
+			}
+			// 跳过所属文章不可搜索(未发布或已删除)的评论
+			if key.kind == TypeComment {
+				if _, ok := m.docs[docKey{TypePost, m.docs[key].postID}]; !ok {
+					continue
+				}
+			}
+			if prev, ok := scores[key]; ok || i == 0 {
+				next[key] = prev + weight*idf
+			}
+		}
+		scores = next
+	}
+
+	results := make([]Result, 0, len(scores))
+	texts := make(map[docKey]string, len(scores))
+	for key, score := range scores {
+		doc := m.docs[key]
+		texts[key] = doc.text
+		results = append(results, Result{
+			Type:      key.kind,
+			ID:        key.id,
+			PostID:    doc.postID,
+			Title:     doc.title,
+			Score:     score,
+			CreatedAt: doc.createdAt,
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	total := int64(len(results))
+	start := q.Offset()
+	if start > len(results) {
+		start = len(results)
+	}
+	end := start + q.Size
+	if end > len(results) {
+		end = len(results)
+	}
+
+	// 只为当前页生成高亮片段
+	page := results[start:end]
+	for i := range page {
+		page[i].Snippet = Snippet(texts[docKey{page[i].Type, page[i].ID}], terms)
+	}
+
+	return page, total, nil
+}
@@ -0,0 +1,287 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"blog-system/internal/testdb"
+	"blog-system/models"
+
+	"gorm.io/gorm"
+)
+
+// newTestIndex 创建测试数据库、作者和内存索引
+func newTestIndex(t *testing.T) (*gorm.DB, *models.User, *MemoryIndex) {
+	t.Helper()
+	db := testdb.New(t, models.AutoMigrate)
+	user, err := models.NewUserCRUD(db).Create(&models.RegisterRequest{Username: "author", Password: "password123", Email: "author@example.com"})
+	if err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	idx, err := NewMemoryIndex(db)
+	if err != nil {
+		t.Fatalf("创建索引失败: %v", err)
+	}
+	return db, user, idx
+}
+
+func createPost(t *testing.T, crud *models.PostCRUD, userID uint, title, summary, content string) *models.Post {
+	t.Helper()
+	post, err := crud.Create(&models.PostRequest{Title: title, Summary: summary, Content: content}, userID)
+	if err != nil {
+		t.Fatalf("创建文章失败: %v", err)
+	}
+	return post
+}
+
+// search 执行查询并检查total与返回的结果数一致
+func search(t *testing.T, idx *MemoryIndex, text string, includeComments bool) []Result {
+	t.Helper()
+	results, total, err := idx.Search(context.Background(), &Query{Text: text, IncludeComments: includeComments})
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", text, err)
+	}
+	if total != int64(len(results)) {
+		t.Fatalf("Search(%q) total = %d, 结果数 = %d", text, total, len(results))
+	}
+	return results
+}
+
+func hasResult(results []Result, kind string, id uint) bool {
+	for _, r := range results {
+		if r.Type == kind && r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMemoryIndexRanking(t *testing.T) {
+	db, user, idx := newTestIndex(t)
+	posts := models.NewPostCRUD(db)
+
+	inContent := createPost(t, posts, user.ID, "日常记录", "随笔", "今天学习了并发编程")
+	inTitle := createPost(t, posts, user.ID, "并发编程实践", "随笔", "一些笔记")
+	inSummary := createPost(t, posts, user.ID, "读书笔记", "关于并发编程", "一些想法")
+	createPost(t, posts, user.ID, "无关文章", "随笔", "与查询无关的内容")
+
+	results := search(t, idx, "并发编程", false)
+	if len(results) != 3 {
+		t.Fatalf("命中%d篇文章, want 3", len(results))
+	}
+	for i, want := range []uint{inTitle.ID, inSummary.ID, inContent.ID} {
+		if results[i].ID != want {
+			t.Fatalf("第%d个结果 = %d, want %d(标题 > 摘要 > 正文)", i+1, results[i].ID, want)
+		}
+	}
+	if results[0].Score <= results[1].Score || results[1].Score <= results[2].Score {
+		t.Fatalf("得分应严格递减: %v, %v, %v", results[0].Score, results[1].Score, results[2].Score)
+	}
+	if results[0].Title != "并发编程实践" || !strings.Contains(results[0].Snippet, "<mark>并发编程</mark>") {
+		t.Fatalf("结果 = %+v", results[0])
+	}
+
+	// 多个查询词需全部命中
+	results = search(t, idx, "并发 想法", false)
+	if len(results) != 1 || results[0].ID != inSummary.ID {
+		t.Fatalf("Search(并发 想法) = %+v, want 文章%d", results, inSummary.ID)
+	}
+	if results := search(t, idx, "不存在的词", false); len(results) != 0 {
+		t.Fatalf("未命中时应返回空结果: %+v", results)
+	}
+	if results := search(t, idx, "  ", false); len(results) != 0 {
+		t.Fatalf("空查询应返回空结果: %+v", results)
+	}
+
+	// 分页：total为全部命中数，只返回当前页
+	page, total, err := idx.Search(context.Background(), &Query{Text: "随笔", Page: 2, Size: 1})
+	if err != nil || total != 3 || len(page) != 1 {
+		t.Fatalf("分页结果 = %d条, total = %d, %v", len(page), total, err)
+	}
+}
+
+func TestMemoryIndexRebuildLoadsExistingData(t *testing.T) {
+	db := testdb.New(t, models.AutoMigrate)
+	user, err := models.NewUserCRUD(db).Create(&models.RegisterRequest{Username: "author", Password: "password123", Email: "author@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := createPost(t, models.NewPostCRUD(db), user.ID, "Go语言入门", "", "内容")
+	if _, err := models.NewPostCRUD(db).Create(&models.PostRequest{Title: "Go语言草稿", Content: "内容", Status: models.PostStatusDraft}, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := NewMemoryIndex(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := search(t, idx, "go语言", false)
+	if len(results) != 1 || results[0].ID != published.ID {
+		t.Fatalf("重建后应只包含已发布的文章: %+v", results)
+	}
+}
+
+func TestMemoryIndexFollowsPostWrites(t *testing.T) {
+	db, user, idx := newTestIndex(t)
+	posts := models.NewPostCRUD(db)
+
+	post := createPost(t, posts, user.ID, "Gin框架教程", "", "路由与中间件")
+	if !hasResult(search(t, idx, "gin", false), TypePost, post.ID) {
+		t.Fatal("创建的文章应可被搜索")
+	}
+	draft, err := posts.Create(&models.PostRequest{Title: "Gin草稿", Content: "内容", Status: models.PostStatusDraft}, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasResult(search(t, idx, "gin", false), TypePost, draft.ID) {
+		t.Fatal("草稿不应被搜索到")
+	}
+
+	// 更新后旧词移出索引，新词加入索引
+	if _, err := posts.Update(post.ID, &models.PostRequest{Title: "GORM使用指南", Content: "关联与事务"}, user.ID, false); err != nil {
+		t.Fatalf("更新文章失败: %v", err)
+	}
+	if hasResult(search(t, idx, "gin", false), TypePost, post.ID) {
+		t.Fatal("更新后不应再按旧标题命中")
+	}
+	if !hasResult(search(t, idx, "gorm 事务", false), TypePost, post.ID) {
+		t.Fatal("更新后应按新内容命中")
+	}
+
+	// 撤回为草稿后移出索引，重新发布后加入索引
+	if _, err := posts.Unpublish(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if hasResult(search(t, idx, "gorm", false), TypePost, post.ID) {
+		t.Fatal("撤回的文章不应被搜索到")
+	}
+	if _, err := posts.Publish(post.ID, user.ID, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !hasResult(search(t, idx, "gorm", false), TypePost, post.ID) {
+		t.Fatal("重新发布的文章应可被搜索")
+	}
+
+	// 移入回收站后移出索引，恢复后重新加入
+	if _, err := posts.Delete(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if hasResult(search(t, idx, "gorm", false), TypePost, post.ID) {
+		t.Fatal("已删除的文章不应被搜索到")
+	}
+	if _, err := posts.Restore(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !hasResult(search(t, idx, "gorm", false), TypePost, post.ID) {
+		t.Fatal("恢复的文章应可被搜索")
+	}
+}
+
+func TestMemoryIndexFollowsCommentWrites(t *testing.T) {
+	db, user, idx := newTestIndex(t)
+	posts := models.NewPostCRUD(db)
+	comments := models.NewCommentCRUD(db)
+
+	post := createPost(t, posts, user.ID, "部署笔记", "", "使用容器部署")
+	comment, err := comments.Create(&models.CommentRequest{Content: "感谢分享Kubernetes经验"}, user.ID, post.ID, &models.CommentMeta{})
+	if err != nil {
+		t.Fatalf("创建评论失败: %v", err)
+	}
+	if comment.Status != models.CommentStatusApproved {
+		t.Fatalf("评论状态 = %s", comment.Status)
+	}
+
+	if results := search(t, idx, "kubernetes", false); len(results) != 0 {
+		t.Fatalf("未开启评论搜索时不应返回评论: %+v", results)
+	}
+	results := search(t, idx, "kubernetes", true)
+	if len(results) != 1 || results[0].Type != TypeComment || results[0].ID != comment.ID || results[0].PostID != post.ID {
+		t.Fatalf("Search(kubernetes) = %+v", results)
+	}
+
+	pending, err := comments.Create(&models.CommentRequest{Content: "Kubernetes待审核"}, user.ID, post.ID, &models.CommentMeta{Status: models.CommentStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasResult(search(t, idx, "kubernetes", true), TypeComment, pending.ID) {
+		t.Fatal("未通过审核的评论不应被搜索到")
+	}
+
+	// 评论删除与恢复
+	if _, err := comments.Delete(comment.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if hasResult(search(t, idx, "kubernetes", true), TypeComment, comment.ID) {
+		t.Fatal("已删除的评论不应被搜索到")
+	}
+	if _, err := comments.Restore(comment.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !hasResult(search(t, idx, "kubernetes", true), TypeComment, comment.ID) {
+		t.Fatal("恢复的评论应可被搜索")
+	}
+
+	// 文章删除时其评论一并移出索引，文章恢复后评论重新加入
+	if _, err := posts.Delete(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if results := search(t, idx, "kubernetes", true); len(results) != 0 {
+		t.Fatalf("文章删除后不应返回其评论: %+v", results)
+	}
+	if _, err := posts.Restore(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !hasResult(search(t, idx, "kubernetes", true), TypeComment, comment.ID) {
+		t.Fatal("文章恢复后其评论应可被搜索")
+	}
+
+	// 文章撤回为草稿时不返回其评论
+	if _, err := posts.Unpublish(post.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if results := search(t, idx, "kubernetes", true); len(results) != 0 {
+		t.Fatalf("未发布文章的评论不应被搜索到: %+v", results)
+	}
+}
+
+func TestMemoryIndexIgnoresRolledBackWrites(t *testing.T) {
+	db, user, idx := newTestIndex(t)
+	posts := models.NewPostCRUD(db)
+	post := createPost(t, posts, user.ID, "并发编程", "随笔", "原来的内容")
+
+	// 保存修订记录失败使整个更新事务回滚，此时文章已在事务中写入
+	failRevisions := func(tx *gorm.DB) {
+		if tx.Statement.Table == "post_revisions" {
+			tx.AddError(errors.New("写入失败"))
+		}
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_revisions", failRevisions); err != nil {
+		t.Fatal(err)
+	}
+	req := &models.PostRequest{Title: "并发编程", Summary: "随笔", Content: "分布式系统"}
+	if _, err := posts.Update(post.ID, req, user.ID, false); err == nil {
+		t.Fatal("更新应失败")
+	}
+	if results := search(t, idx, "分布式系统", false); len(results) != 0 {
+		t.Fatalf("回滚的内容被索引: %+v", results)
+	}
+	if results := search(t, idx, "原来的内容", false); !hasResult(results, TypePost, post.ID) {
+		t.Fatal("回滚后原内容应仍可搜索")
+	}
+
+	// 事务提交后刷新索引
+	if err := db.Callback().Create().Remove("test:fail_revisions"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := posts.Update(post.ID, req, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if results := search(t, idx, "分布式系统", false); !hasResult(results, TypePost, post.ID) {
+		t.Fatal("提交后新内容应可搜索")
+	}
+	if results := search(t, idx, "原来的内容", false); len(results) != 0 {
+		t.Fatalf("提交后旧内容仍可搜索: %+v", results)
+	}
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 全文索引名称
+const (
+	postIndexName    = "ft_posts_search"
+	commentIndexName = "ft_comments_search"
+)
+
+// MySQLSearcher 基于MySQL FULLTEXT索引(ngram分词器，支持中文)的搜索实现
+type MySQLSearcher struct {
+	db *gorm.DB
+}
+
+// NewMySQLSearcher 创建MySQL搜索实现，并确保全文索引存在
+func NewMySQLSearcher(db *gorm.DB) (*MySQLSearcher, error) {
+	s := &MySQLSearcher{db: db}
+	if err := s.ensureIndex("posts", postIndexName, "title, summary, content"); err != nil {
+		return nil, err
+	}
+	if err := s.ensureIndex("comments", commentIndexName, "content"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ensureIndex 创建缺失的全文索引
+func (s *MySQLSearcher) ensureIndex(table, name, columns string) error {
+	var count int64
+	err := s.db.Raw(`SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`, table, name).Scan(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := s.db.Exec("ALTER TABLE " + table + " ADD FULLTEXT INDEX " + name + " (" + columns + ") WITH PARSER ngram").Error; err != nil {
+		return errors.New("创建全文索引失败: " + err.Error())
+	}
+	return nil
+}
+
+// row 搜索查询的结果行
+type row struct {
+	Type      string
+	ID        uint
+	PostID    uint
+	Title     string
+	Text      string
+	Score     float64
+	CreatedAt time.Time
+}
+
+// Search 使用MATCH ... AGAINST进行自然语言模式的全文检索，按相关度排序
+func (s *MySQLSearcher) Search(ctx context.Context, q *Query) ([]Result, int64, error) {
+	q.Normalize()
+	terms := uniqueTokens(q.Text)
+	if len(terms) == 0 {
+		return []Result{}, 0, nil
+	}
+
+	db := s.db.WithContext(ctx)
+
+	postMatch := "MATCH(p.title, p.summary, p.content) AGAINST(? IN NATURAL LANGUAGE MODE)"
+	postSQL := "SELECT 'post' AS type, p.id AS id, p.id AS post_id, p.title AS title, " +
+		"CONCAT_WS('\\n', p.title, p.summary, p.content) AS text, " + postMatch + " AS score, p.created_at AS created_at " +
+		"FROM posts p WHERE " + postMatch + " AND p.status = 'published' AND p.deleted_at IS NULL"
+	args := []interface{}{q.Text, q.Text}
+
+	unionSQL := postSQL
+	if q.IncludeComments {
+		commentMatch := "MATCH(c.content) AGAINST(? IN NATURAL LANGUAGE MODE)"
+		commentSQL := "SELECT 'comment' AS type, c.id AS id, c.post_id AS post_id, '' AS title, " +
+			"c.content AS text, " + commentMatch + " AS score, c.created_at AS created_at " +
+			"FROM comments c JOIN posts p ON p.id = c.post_id WHERE " + commentMatch + " AND c.status = 'approved' AND c.deleted_at IS NULL AND p.status = 'published' AND p.deleted_at IS NULL"
+		unionSQL += " UNION ALL " + commentSQL
+		args = append(args, q.Text, q.Text)
+	}
+
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) FROM ("+unionSQL+") AS hits", args...).Scan(&total).Error; err != nil {
+		return nil, 0, errors.New("搜索失败")
+	}
+
+	var rows []row
+	pageArgs := append(append([]interface{}{}, args...), q.Size, q.Offset())
+	if err := db.Raw("SELECT * FROM ("+unionSQL+") AS hits ORDER BY score DESC, created_at DESC LIMIT ? OFFSET ?", pageArgs...).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, errors.New("搜索失败")
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, r := range rows {
+		results = append(results, Result{
+			Type:      r.Type,
+			ID:        r.ID,
+			PostID:    r.PostID,
+			Title:     r.Title,
+			Snippet:   Snippet(r.Text, terms),
+			Score:     r.Score,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	return results, total, nil
+}
@@ -0,0 +1,202 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// 搜索结果类型
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+)
+
+const (
+	defaultLimit  = 10
+	maxLimit      = 50
+	snippetRadius = 60
+)
+
+// Query 搜索请求参数
+type Query struct {
+	Text            string `form:"q" binding:"required,max=100"`
+	IncludeComments bool   `form:"comments"`
+	Page            int    `form:"page" binding:"omitempty,min=1"`
+	Size            int    `form:"size" binding:"omitempty,min=1,max=50"`
+}
+
+// Normalize 填充默认分页参数
+func (q *Query) Normalize() {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Size <= 0 {
+		q.Size = defaultLimit
+	}
+	if q.Size > maxLimit {
+		q.Size = maxLimit
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+}
+
+// Offset 计算分页偏移量
+func (q *Query) Offset() int {
+	return (q.Page - 1) * q.Size
+}
+
+// Result 单条搜索结果
+type Result struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	Title     string    `json:"title,omitempty"`
+	Snippet   string    `json:"snippet"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Searcher 搜索接口，handlers只依赖该接口，不关心底层实现
+type Searcher interface {
+	// Search 执行搜索，返回当前页结果和命中总数
+	Search(ctx context.Context, q *Query) ([]Result, int64, error)
+}
+
+// New 根据驱动名称创建搜索实现：mysql使用FULLTEXT索引，memory使用进程内倒排索引
+func New(driver string, db *gorm.DB) (Searcher, error) {
+	switch driver {
+	case "", "mysql":
+		return NewMySQLSearcher(db)
+	case "memory":
+		return NewMemoryIndex(db)
+	default:
+		return nil, errors.New("不支持的搜索驱动: " + driver)
+	}
+}
+
+// isCJK 判断是否为中日韩字符，这类字符按二元组切分
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Tokenize 分词：拉丁字母和数字按单词切分并转小写，中日韩文本切分为二元组(单字时保留单字)
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// uniqueTokens 去重后的查询词
+func uniqueTokens(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range Tokenize(text) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// Snippet 截取包含查询词的片段并用<mark>高亮，其余文本做HTML转义
+func Snippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记所有命中的字符位置
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for k := i; k < i+len(t); k++ {
+					marked[k] = true
+				}
+				if first == -1 || i < first {
+					first = i
+				}
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first > snippetRadius {
+		start = first - snippetRadius
+	}
+	if start+2*snippetRadius < end {
+		end = start + 2*snippetRadius
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"Go 1.25 released", []string{"go", "1", "25", "released"}},
+		{"并发编程", []string{"并发", "发编", "编程"}},
+		{"学", []string{"学"}},
+		{"Go语言入门", []string{"go", "语言", "言入", "入门"}},
+		{"Gin框架，GORM库", []string{"gin", "框架", "gorm", "库"}},
+		{"こんにちは", []string{"こん", "んに", "にち", "ちは"}},
+		{"한국어 검색", []string{"한국", "국어", "검색"}},
+		{"ÄÖÜ café", []string{"äöü", "café"}},
+		{"  ...  ", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueTokens(t *testing.T) {
+	got := uniqueTokens("Go go GO 编程编程")
+	want := []string{"go", "编程", "程编"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("uniqueTokens() = %q, want %q", got, want)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"英文不区分大小写", "Learning Go is fun", []string{"go"}, "Learning <mark>Go</mark> is fun"},
+		{"中文二元组连续高亮", "学习并发编程很有趣", uniqueTokens("并发编程"), "学习<mark>并发编程</mark>很有趣"},
+		{"多个查询词", "Gin和GORM", []string{"gin", "gorm"}, "<mark>Gin</mark>和<mark>GORM</mark>"},
+		{"转义HTML", "<b>go</b> & more", []string{"go"}, "&lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; more"},
+		{"没有命中", "nothing here", []string{"go"}, "nothing here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms); got != tt.want {
+				t.Fatalf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetWindow(t *testing.T) {
+	text := strings.Repeat("前", 100) + "关键" + strings.Repeat("后", 100)
+	got := Snippet(text, []string{"关键"})
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Fatalf("远离开头的命中应截取片段并加省略号: %q", got)
+	}
+	if !strings.Contains(got, "<mark>关键</mark>") {
+		t.Fatalf("片段应包含高亮的查询词: %q", got)
+	}
+	plain := strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(got)
+	if n := len([]rune(plain)); n != 2*snippetRadius {
+		t.Fatalf("片段长度 = %d, want %d", n, 2*snippetRadius)
+	}
+	if before := strings.Index(plain, "关键"); len([]rune(plain[:before])) != snippetRadius {
+		t.Fatalf("命中位置之前应保留%d个字符: %q", snippetRadius, plain)
+	}
+
+	short := Snippet("开头就有关键词"+strings.Repeat("尾", 200), []string{"关键"})
+	if strings.HasPrefix(short, "…") || !strings.HasSuffix(short, "…") {
+		t.Fatalf("命中靠近开头时从头截取: %q", short)
+	}
+}
@@ -0,0 +1,96 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength 别名的最大长度，超出时在单词边界截断
+const MaxLength = 80
+
+// Fallback 标题中没有可用字符时使用的别名
+const Fallback = "post"
+
+// Transliterator 将非ASCII字符转写为ASCII单词，无法转写时返回空字符串
+type Transliterator interface {
+	Transliterate(r rune) string
+}
+
+// Pinyin 将汉字转写为不带声调的拼音，多音字取常用读音
+type Pinyin struct{}
+
+func (Pinyin) Transliterate(r rune) string {
+	if !unicode.Is(unicode.Han, r) {
+		return ""
+	}
+	syllables := pinyin.LazyPinyin(string(r), pinyin.NewArgs())
+	if len(syllables) == 0 {
+		return ""
+	}
+	return syllables[0]
+}
+
+// Default 默认的转写策略
+var Default Transliterator = Pinyin{}
+
+// Make 使用默认转写策略生成别名
+func Make(title string) string {
+	return MakeWith(title, Default)
+}
+
+// MakeWith 根据标题生成URL别名：带音调符号的拉丁字母去除符号，ASCII字母和数字转为小写保留，
+// 其他字符通过转写策略转为单词，单词之间以"-"连接。
+// 纯数字的别名会加上前缀，避免与文章ID混淆。
+func MakeWith(title string, t Transliterator) string {
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range norm.NFD.String(title) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// 跳过分解后的组合音调符号，如é分解为e和´
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			current.WriteRune(unicode.ToLower(r))
+		case r >= unicode.MaxASCII:
+			flush()
+			if word := t.Transliterate(r); word != "" {
+				words = append(words, strings.ToLower(word))
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	slug := ""
+	for _, word := range words {
+		next := word
+		if slug != "" {
+			next = slug + "-" + word
+		}
+		if len(next) > MaxLength {
+			if slug == "" {
+				slug = word[:MaxLength]
+			}
+			break
+		}
+		slug = next
+	}
+
+	if slug == "" {
+		return Fallback
+	}
+	if strings.Trim(slug, "0123456789") == "" {
+		slug = Fallback + "-" + slug
+	}
+	return slug
+}
@@ -0,0 +1,95 @@
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// History 历史评论查询接口，由评论存储实现
+type History interface {
+	// CountSameContent 统计指定时间之后同一用户或同一IP发布的相同内容评论数量
+	CountSameContent(content string, userID uint, ip string, since time.Time) (int64, error)
+	// CountByIP 统计指定时间之后同一IP发布的评论数量
+	CountByIP(ip string, since time.Time) (int64, error)
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// LinkRule 链接数量规则：链接数超过Max时计分，每多一个链接加分
+type LinkRule struct {
+	Max int
+}
+
+func (r *LinkRule) Name() string { return "links" }
+
+func (r *LinkRule) Score(in *Input) (float64, string, error) {
+	n := len(linkPattern.FindAllString(in.Content, -1))
+	if n <= r.Max {
+		return 0, "", nil
+	}
+	return 1 + 0.5*float64(n-r.Max-1), fmt.Sprintf("包含%d个链接", n), nil
+}
+
+// BlocklistRule 屏蔽词规则：每命中一个屏蔽词计1分，不区分大小写
+type BlocklistRule struct {
+	Words []string
+}
+
+func (r *BlocklistRule) Name() string { return "blocklist" }
+
+func (r *BlocklistRule) Score(in *Input) (float64, string, error) {
+	content := strings.ToLower(in.Content)
+	var hits []string
+	for _, word := range r.Words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(content, word) {
+			hits = append(hits, word)
+		}
+	}
+	if len(hits) == 0 {
+		return 0, "", nil
+	}
+	return float64(len(hits)), "命中屏蔽词: " + strings.Join(hits, ","), nil
+}
+
+// RepeatRule 重复内容规则：Window时间内同一用户或IP重复发布相同内容时计分
+type RepeatRule struct {
+	History History
+	Window  time.Duration
+}
+
+func (r *RepeatRule) Name() string { return "repeat" }
+
+func (r *RepeatRule) Score(in *Input) (float64, string, error) {
+	n, err := r.History.CountSameContent(strings.TrimSpace(in.Content), in.UserID, in.IP, time.Now().Add(-r.Window))
+	if err != nil || n == 0 {
+		return 0, "", err
+	}
+	score := 1.5 * float64(n)
+	if score > 3 {
+		score = 3
+	}
+	return score, fmt.Sprintf("重复发布相同内容%d次", n), nil
+}
+
+// VelocityRule 频率规则：Window时间内同一IP的评论数达到Limit时计分
+type VelocityRule struct {
+	History History
+	Window  time.Duration
+	Limit   int
+}
+
+func (r *VelocityRule) Name() string { return "velocity" }
+
+func (r *VelocityRule) Score(in *Input) (float64, string, error) {
+	if in.IP == "" || r.Limit <= 0 {
+		return 0, "", nil
+	}
+	n, err := r.History.CountByIP(in.IP, time.Now().Add(-r.Window))
+	if err != nil || n < int64(r.Limit) {
+		return 0, "", err
+	}
+	return 1 + 0.5*float64(n-int64(r.Limit)), fmt.Sprintf("同一IP在%v内发布%d条评论", r.Window, n), nil
+}
@@ -0,0 +1,87 @@
+package spam
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Action 评分后的处理动作
+type Action string
+
+const (
+	ActionAllow Action = "allow" // 直接通过
+	ActionHold  Action = "hold"  // 进入人工审核队列
+	ActionSpam  Action = "spam"  // 判定为垃圾评论
+)
+
+// Input 待评分的评论内容及上下文
+type Input struct {
+	Content   string
+	UserID    uint
+	PostID    uint
+	IP        string
+	UserAgent string
+}
+
+// Rule 评分规则，返回该规则的得分和命中原因，得分为0表示未命中
+type Rule interface {
+	Name() string
+	Score(in *Input) (float64, string, error)
+}
+
+// Verdict 评分结果
+type Verdict struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+	Action  Action   `json:"action"`
+}
+
+// Reason 将命中原因合并为一行文本
+func (v *Verdict) Reason() string {
+	return strings.Join(v.Reasons, "; ")
+}
+
+// Scorer 垃圾评论评分器，累加所有规则的得分并按阈值决定处理动作
+type Scorer struct {
+	rules         []Rule
+	holdThreshold float64
+	spamThreshold float64
+}
+
+// NewScorer 创建评分器，得分达到holdThreshold进入审核，达到spamThreshold判定为垃圾评论
+func NewScorer(holdThreshold, spamThreshold float64, rules ...Rule) *Scorer {
+	return &Scorer{
+		rules:         rules,
+		holdThreshold: holdThreshold,
+		spamThreshold: spamThreshold,
+	}
+}
+
+// Use 追加评分规则
+func (s *Scorer) Use(rules ...Rule) {
+	s.rules = append(s.rules, rules...)
+}
+
+// Evaluate 对评论评分。单个规则出错时跳过该规则，不影响评论发布。
+func (s *Scorer) Evaluate(in *Input) *Verdict {
+	verdict := &Verdict{Action: ActionAllow}
+	for _, rule := range s.rules {
+		score, reason, err := rule.Score(in)
+		if err != nil {
+			slog.Error("垃圾评论规则执行失败", "rule", rule.Name(), "error", err)
+			continue
+		}
+		if score > 0 {
+			verdict.Score += score
+			verdict.Reasons = append(verdict.Reasons, reason)
+		}
+	}
+
+	switch {
+	case verdict.Score >= s.spamThreshold:
+		verdict.Action = ActionSpam
+	case verdict.Score >= s.holdThreshold:
+		verdict.Action = ActionHold
+	}
+	return verdict
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local 本地文件系统存储，文件保存在root目录下，通过baseURL对外提供访问
+type Local struct {
+	root    string
+	baseURL string
+}
+
+// NewLocal 创建本地文件系统存储，root目录不存在时自动创建
+func NewLocal(root, baseURL string) (*Local, error) {
+	if root == "" {
+		return nil, errors.New("本地存储目录不能为空")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, errors.New("创建本地存储目录失败: " + err.Error())
+	}
+	return &Local{root: root, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// Root 返回本地存储的根目录，用于挂载静态文件服务
+func (l *Local) Root() string {
+	return l.root
+}
+
+func (l *Local) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put 先写入临时文件再重命名，避免读取到写了一半的文件
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	target, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + key
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash 空请求体的SHA256，用于GET和DELETE请求的签名
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Options S3兼容存储的连接参数
+type S3Options struct {
+	Endpoint  string // 服务地址，如https://s3.amazonaws.com或http://localhost:9000(MinIO)
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string // 文件的公开访问地址前缀，为空时使用Endpoint/Bucket
+}
+
+// S3 S3兼容的对象存储，使用路径风格的地址和AWS签名V4，
+// 适用于AWS S3以及MinIO等本地替代服务
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3 创建S3兼容存储
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("S3存储需要配置服务地址和存储桶")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, errors.New("无效的S3服务地址: " + opts.Endpoint)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.PublicURL == "" {
+		opts.PublicURL = endpoint.String() + "/" + opts.Bucket
+	}
+	opts.PublicURL = strings.TrimRight(opts.PublicURL, "/")
+
+	return &S3{
+		opts:     opts,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Put 上传对象，请求体不参与签名(UNSIGNED-PAYLOAD)，避免为计算摘要而缓存整个文件
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, "UNSIGNED-PAYLOAD", time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, emptyPayloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, emptyPayloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3删除不存在的对象同样返回204
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (s *S3) URL(key string) string {
+	return s.opts.PublicURL + "/" + escapePath(key)
+}
+
+// newRequest 创建指向对象的请求，地址形如{endpoint}/{bucket}/{key}
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.opts.Bucket + "/" + cleaned
+	u.RawPath = strings.TrimRight(s.endpoint.EscapedPath(), "/") + "/" + escapePath(s.opts.Bucket+"/"+cleaned)
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// sign 按AWS签名V4为请求添加认证头，参与签名的请求头为host、x-amz-content-sha256和x-amz-date
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath 按S3的规则编码路径：除非保留字符和"/"外全部百分号编码
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// responseError 将S3错误响应转换为错误，附带响应体中的错误信息
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3请求失败(%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+
+	"blog-system/config"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Storage 文件存储后端。key为以"/"分隔的相对路径，由调用方生成
+type Storage interface {
+	// Put 写入文件，size为内容长度，已存在的同名文件会被覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Open 读取文件，文件不存在时返回ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+
+	// URL 返回文件的公开访问地址
+	URL(key string) string
+}
+
+// New 根据配置创建存储后端
+func New(cfg config.MediaConfig) (Storage, error) {
+	switch cfg.Storage {
+	case "", "local":
+		return NewLocal(cfg.LocalDir, cfg.BaseURL)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+		})
+	default:
+		return nil, errors.New("不支持的存储后端: " + cfg.Storage)
+	}
+}
+
+// cleanKey 校验并规范化key，拒绝绝对路径和跳出根目录的路径
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key {
+		return "", errors.New("无效的文件路径: " + key)
+	}
+	return cleaned, nil
+}
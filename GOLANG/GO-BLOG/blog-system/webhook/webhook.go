@@ -0,0 +1,140 @@
+// Package webhook 向外部地址投递事件通知：请求体为JSON，使用HMAC-SHA256签名，接收方据此校验来源和防止重放
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 投递请求携带的请求头
+const (
+	HeaderEvent     = "X-Blog-Event"
+	HeaderDelivery  = "X-Blog-Delivery"
+	HeaderTimestamp = "X-Blog-Timestamp"
+	HeaderSignature = "X-Blog-Signature"
+)
+
+// signaturePrefix 签名请求头的前缀，标明使用的算法
+const signaturePrefix = "sha256="
+
+// maxResponseBody 记录的响应内容最大字节数
+const maxResponseBody = 1 << 10
+
+// Sign 计算签名：以密钥对"时间戳.请求体"做HMAC-SHA256，结果为带"sha256="前缀的十六进制字符串
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求头中的签名，时间戳与now相差超过tolerance时视为重放。供接收方和测试使用
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("无效的时间戳")
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return errors.New("时间戳已过期")
+	}
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(expected)) {
+		return errors.New("签名不匹配")
+	}
+	return nil
+}
+
+// Request 一次投递请求
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Result 投递结果，Err为空且状态码为2xx时视为成功
+type Result struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+	Err        error
+}
+
+// OK 判断投递是否成功
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Error 返回失败原因，成功时为空字符串
+func (r Result) Error() string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case !r.OK():
+		return fmt.Sprintf("接收方返回状态码 %d", r.StatusCode)
+	}
+	return ""
+}
+
+// Client 发送投递请求，不跟随重定向，避免签名过的请求被转发到其他地址
+type Client struct {
+	http      *http.Client
+	userAgent string
+}
+
+// NewClient 创建投递客户端，timeout为单次请求的超时时间
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		http: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		userAgent: "Blog-Webhook/1.0",
+	}
+}
+
+// Send 签名并发送请求，记录响应状态码和截断后的响应内容
+func (c *Client) Send(ctx context.Context, req Request) Result {
+	start := time.Now()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	timestamp := start.Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return Result{Err: err, Duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	// 读完剩余内容以便复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return Result{
+		StatusCode: resp.StatusCode,
+		Body:       strings.ToValidUTF8(string(body), ""),
+		Duration:   time.Since(start),
+	}
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignCoversTimestampAndBody(t *testing.T) {
+	body := []byte(`{"id":"1","event":"post.published"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", 1700000000, body); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+	if Sign("secret", 1700000001, body) == want {
+		t.Fatal("修改时间戳后签名不应相同")
+	}
+	if Sign("other", 1700000000, body) == want {
+		t.Fatal("修改密钥后签名不应相同")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	signed := func(secret string, at time.Time) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+		h.Set(HeaderSignature, Sign(secret, at.Unix(), body))
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr string
+	}{
+		{"有效签名", signed("secret", now), body, ""},
+		{"容差内的时间戳", signed("secret", now.Add(-4*time.Minute)), body, ""},
+		{"密钥不同", signed("other", now), body, "签名不匹配"},
+		{"请求体被修改", signed("secret", now), []byte(`{"id":"2"}`), "签名不匹配"},
+		{"时间戳过期", signed("secret", now.Add(-6*time.Minute)), body, "时间戳已过期"},
+		{"时间戳在未来", signed("secret", now.Add(6*time.Minute)), body, "时间戳已过期"},
+		{"缺少时间戳", http.Header{HeaderSignature: {Sign("secret", now.Unix(), body)}}, body, "无效的时间戳"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify("secret", tt.header, tt.body, 5*time.Minute, now)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Verify() error = %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("Verify() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientSendSignsRequest(t *testing.T) {
+	body := []byte(`{"id":"abc","event":"comment.created"}`)
+	var verifyErr error
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		header = r.Header.Clone()
+		verifyErr = Verify("secret", r.Header, received, time.Minute, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	result := NewClient(time.Second).Send(context.Background(), Request{
+		URL:        server.URL,
+		Secret:     "secret",
+		Event:      "comment.created",
+		DeliveryID: "42",
+		Body:       body,
+	})
+	if !result.OK() || result.StatusCode != http.StatusNoContent {
+		t.Fatalf("Send() = %+v, want 204", result)
+	}
+	if verifyErr != nil {
+		t.Fatalf("接收方校验签名失败: %v", verifyErr)
+	}
+	if header.Get(HeaderEvent) != "comment.created" || header.Get(HeaderDelivery) != "42" {
+		t.Fatalf("请求头不正确: %v", header)
+	}
+	if header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Content-Type = %s", header.Get("Content-Type"))
+	}
+}
+
+func TestClientSendFailures(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "upstream down")
+		case "/redirect":
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	result := client.Send(context.Background(), Request{URL: server.URL + "/error", Secret: "secret", Body: []byte("{}")})
+	if result.OK() || result.StatusCode != http.StatusBadGateway || result.Body != "upstream down" {
+		t.Fatalf("Send() = %+v, want 502", result)
+	}
+	if result.Error() != "接收方返回状态码 502" {
+		t.Fatalf("Error() = %s", result.Error())
+	}
+
+	result = client.Send(context.Background(), Request{URL: server.URL + "/redirect", Secret: "secret", Body: []byte("{}")})
+	if result.OK() || result.StatusCode != http.StatusFound || redirected {
+		t.Fatalf("重定向不应被跟随: %+v", result)
+	}
+
+	server.Close()
+	result = client.Send(context.Background(), Request{URL: server.URL, Secret: "secret", Body: []byte("{}")})
+	if result.OK() || result.Err == nil {
+		t.Fatalf("连接失败时应返回错误: %+v", result)
+	}
+}